@@ -0,0 +1,39 @@
+// Package api holds contract of OTP App api that is shared by server and clients: paths, headers, error codes
+// and proof-of-work puzzle. it only imports standard library, so clients do not depend on packages of server.
+package api
+
+// paths of api endpoints.
+const (
+	// BasePath is the path prefix of api endpoints.
+	BasePath string = "/api/v1"
+
+	// UserPath is base path of user endpoints.
+	UserPath string = BasePath + "/user"
+
+	// PowChallengePath is path of endpoint that issues proof-of-work challenges.
+	PowChallengePath string = BasePath + "/pow/challenge"
+)
+
+// headers of api requests and responses.
+const (
+	// TenantHeader is the request header that specifies tenant of request.
+	TenantHeader string = "X-Tenant"
+
+	// RequestIDHeader carries id of request in requests and responses.
+	RequestIDHeader string = "X-Request-ID"
+
+	// DeviceIDHeader is the request header that carries stable identifier of client device.
+	DeviceIDHeader string = "X-Device-ID"
+
+	// IdempotencyKeyHeader is the request header that carries idempotency key of client.
+	IdempotencyKeyHeader string = "Idempotency-Key"
+
+	// IdempotentReplayedHeader is set on responses that are replayed from idempotency store.
+	IdempotentReplayedHeader string = "Idempotent-Replayed"
+
+	// PowChallengeHeader carries token of solved proof-of-work challenge.
+	PowChallengeHeader string = "X-PoW-Challenge"
+
+	// PowNonceHeader carries nonce that solves proof-of-work challenge.
+	PowNonceHeader string = "X-PoW-Nonce"
+)
@@ -0,0 +1,46 @@
+package api
+
+// codes of error catalog that are reported in error_details of responses, codes must never change once released.
+const (
+	CodeNotImplemented       string = "NOT_IMPLEMENTED"
+	CodeInternal             string = "INTERNAL"
+	CodeUnhandled            string = "UNHANDLED"
+	CodeNotFound             string = "NOT_FOUND"
+	CodeAlreadyExists        string = "ALREADY_EXISTS"
+	CodeUnauthorized         string = "UNAUTHORIZED"
+	CodePermissionDenied     string = "PERMISSION_DENIED"
+	CodeInvalidBody          string = "INVALID_BODY"
+	CodeInvalidParam         string = "INVALID_PARAM"
+	CodeValidationFailed     string = "VALIDATION_FAILED"
+	CodePreconditionFailed   string = "PRECONDITION_FAILED"
+	CodePreconditionRequired string = "PRECONDITION_REQUIRED"
+	CodeRateLimited          string = "RATE_LIMITED"
+	CodeServiceUnavailable   string = "SERVICE_UNAVAILABLE"
+
+	CodeUnknownTenant string = "UNKNOWN_TENANT"
+	CodeOtpSendFailed string = "OTP_SEND_FAILED"
+
+	CodeAccountExists    string = "ACCOUNT_EXISTS"
+	CodeSignInFailed     string = "SIGN_IN_FAILED"
+	CodeRoleNotAvailable string = "ROLE_NOT_AVAILABLE"
+	CodeAccountNotFound  string = "ACCOUNT_NOT_FOUND"
+	CodeOtpInvalid       string = "OTP_INVALID"
+	CodeOtpExpired       string = "OTP_EXPIRED"
+	CodeOtpNotExpired    string = "OTP_NOT_EXPIRED"
+	CodeRefreshInvalid   string = "REFRESH_INVALID"
+	CodeAccountSuspended string = "ACCOUNT_SUSPENDED"
+	CodeDeviceNotFound   string = "DEVICE_NOT_FOUND"
+	CodeDeviceNotTrusted string = "DEVICE_NOT_TRUSTED"
+
+	CodeInvalidIdempotencyKey string = "INVALID_IDEMPOTENCY_KEY"
+	CodeIdempotencyKeyReused  string = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyInFlight   string = "IDEMPOTENCY_IN_FLIGHT"
+
+	CodeRiskBlocked         string = "RISK_BLOCKED"
+	CodeExtraFactorRequired string = "EXTRA_FACTOR_REQUIRED"
+
+	CodePowRequired string = "POW_REQUIRED"
+	CodePowInvalid  string = "POW_INVALID"
+
+	CodeReloadFailed string = "RELOAD_FAILED"
+)
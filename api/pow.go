@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/bits"
+	"strconv"
+)
+
+// PowAlgorithm is hash function of proof-of-work challenges.
+const PowAlgorithm string = "sha256"
+
+// Solve finds nonce that solves proof-of-work challenge of token and difficulty for subject, it stops when ctx is done.
+// subject is mobile of request that solution is sent with.
+func Solve(ctx context.Context, token string, difficulty int, subject string) (string, error) {
+	for n := uint64(0); ; n++ {
+		// checking ctx for every hash slows solving down
+		if n%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+		}
+
+		nonce := strconv.FormatUint(n, 10)
+		if LeadingZeros(Hash(token, subject, nonce)) >= difficulty {
+			return nonce, nil
+		}
+	}
+}
+
+// Hash returns sha256 hash of solution, solution is valid if hash starts with difficulty zero bits.
+func Hash(token, subject, nonce string) []byte {
+	sum := sha256.Sum256([]byte(token + ":" + subject + ":" + nonce))
+	return sum[:]
+}
+
+// LeadingZeros returns number of leading zero bits of b.
+func LeadingZeros(b []byte) int {
+	n := 0
+	for _, v := range b {
+		if v != 0 {
+			return n + bits.LeadingZeros8(v)
+		}
+
+		n += 8
+	}
+
+	return n
+}
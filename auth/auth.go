@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -12,6 +14,27 @@ import (
 // token Invalid Error
 var ErrInvalidToken = errors.New("provided token is not valid")
 
+// token kinds
+const (
+	// AccessToken is kind of tokens that authenticate api requests.
+	AccessToken string = "access"
+
+	// RefreshToken is kind of tokens that only can be exchanged for a new token bundle.
+	RefreshToken string = "refresh"
+
+	// RoleUser is role of regular accounts.
+	RoleUser string = "USER"
+
+	// RoleAdmin is role of accounts that administrate their tenant.
+	RoleAdmin string = "ADMIN"
+
+	// RolePlatformAdmin is role of accounts that administrate deployment, e.g. reload configuration of every tenant.
+	RolePlatformAdmin string = "PLATFORM_ADMIN"
+)
+
+// Roles are known roles of accounts.
+var Roles = []string{RoleUser, RoleAdmin, RolePlatformAdmin}
+
 type (
 	// Claims specify JWT payload claims
 	Claims struct {
@@ -20,6 +43,9 @@ type (
 		Issuer    string    `json:"iss"`
 		Audience  string    `json:"aud"`
 		Roles     []string  `json:"roles"`
+		Tenant    string    `json:"tenant"`
+		Locale    string    `json:"locale,omitempty"`
+		Kind      string    `json:"kind,omitempty"`
 		IssuedAt  time.Time `json:"issued_at"`
 		ExpiredAt time.Time `json:"exp"`
 	}
@@ -37,6 +63,46 @@ type (
 	}
 )
 
+// NewClaims returns claims of specified kind for subject that expires after ttl.
+func NewClaims(subject uint, kind, issuer, audience string, ttl time.Duration) *Claims {
+	now := time.Now().UTC()
+
+	return &Claims{
+		Subject:   subject,
+		ID:        newTokenID(),
+		Issuer:    issuer,
+		Audience:  audience,
+		Kind:      kind,
+		IssuedAt:  now,
+		ExpiredAt: now.Add(ttl),
+	}
+}
+
+// HasRole reports whether claims carry any of roles.
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, have := range c.Roles {
+		for _, role := range roles {
+			if have == role {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// IsRefresh reports whether claims belong to a refresh token.
+func (c *Claims) IsRefresh() bool {
+	return c.Kind == RefreshToken
+}
+
+func newTokenID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
 func NewPasetoMaker(symmetricKey string) (TokenMaker, error) {
 	if len(symmetricKey) != chacha20poly1305.KeySize {
 		return nil, fmt.Errorf("invalid key size: symmetric key must be exactly %d characters", chacha20poly1305.KeySize)
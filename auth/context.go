@@ -0,0 +1,21 @@
+package auth
+
+import "context"
+
+// claimsKey is the context key type for the authenticated principal.
+type claimsKey struct{}
+
+// NewContext returns a copy of parent that carries the given claims as the authenticated principal.
+func NewContext(parent context.Context, claims *Claims) context.Context {
+	return context.WithValue(parent, claimsKey{}, claims)
+}
+
+// FromContext returns the authenticated principal claims stored in ctx, if any.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	if !ok || claims == nil {
+		return nil, false
+	}
+
+	return claims, true
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// revocationCheckTimeout is maximum duration of looking up revocation store while verifying tokens.
+const revocationCheckTimeout = 2 * time.Second
+
+type (
+	// RevocationStore keeps the time that tokens of a subject have been revoked at.
+	// any token of subject that issued before that time is not valid anymore.
+	RevocationStore interface {
+		// Revoke revokes all tokens of subject that issued until now, ttl should be at least
+		// the lifetime of the longest living token.
+		Revoke(ctx context.Context, subject uint, ttl time.Duration) error
+
+		// RevokedAt returns the time that tokens of subject have been revoked at,
+		// zero time is returned if tokens never been revoked.
+		RevokedAt(ctx context.Context, subject uint) (time.Time, error)
+	}
+
+	redisRevocation struct {
+		client *redis.Client
+	}
+
+	// revocableMaker is a TokenMaker that rejects revoked tokens.
+	revocableMaker struct {
+		next  TokenMaker
+		store RevocationStore
+	}
+)
+
+// NewRedisRevocationStore returns RevocationStore that persisted on redis.
+func NewRedisRevocationStore(client *redis.Client) RevocationStore {
+	return &redisRevocation{
+		client: client,
+	}
+}
+
+// Revoke implements RevocationStore.
+func (r *redisRevocation) Revoke(ctx context.Context, subject uint, ttl time.Duration) error {
+	return r.client.Set(ctx, revocationKey(subject), time.Now().UTC().UnixNano(), ttl).Err()
+}
+
+// RevokedAt implements RevocationStore.
+func (r *redisRevocation) RevokedAt(ctx context.Context, subject uint) (time.Time, error) {
+	val, err := r.client.Get(ctx, revocationKey(subject)).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ns, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, ns).UTC(), nil
+}
+
+func revocationKey(subject uint) string {
+	return fmt.Sprintf("auth_revoked:%d", subject)
+}
+
+// NewRevocableMaker returns TokenMaker that verifies tokens by maker and rejects tokens
+// that revoked in store.
+func NewRevocableMaker(maker TokenMaker, store RevocationStore) TokenMaker {
+	return &revocableMaker{
+		next:  maker,
+		store: store,
+	}
+}
+
+// CreateToken implements TokenMaker.
+func (m *revocableMaker) CreateToken(claims *Claims) (string, error) {
+	return m.next.CreateToken(claims)
+}
+
+// VerifyToken implements TokenMaker.
+func (m *revocableMaker) VerifyToken(token string) (*Claims, error) {
+	claims, err := m.next.VerifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), revocationCheckTimeout)
+	defer cancel()
+
+	revoked, err := m.store.RevokedAt(ctx, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if !revoked.IsZero() && !claims.IssuedAt.After(revoked) {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,340 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ppeymann/top-app.git/api"
+)
+
+const (
+	// DefaultMaxRetries is number of retries for rate limited requests.
+	DefaultMaxRetries int = 3
+
+	// DefaultTimeout is timeout of the default http client.
+	DefaultTimeout time.Duration = 30 * time.Second
+
+	// maxBackoff caps the wait time between retries of rate limited requests.
+	maxBackoff time.Duration = 30 * time.Second
+)
+
+type (
+	// Client is the Go client for OTP App api service.
+	// It is safe for concurrent use by multiple goroutines.
+	Client struct {
+		baseURL    *url.URL
+		http       *http.Client
+		maxRetries int
+		tenant     string
+		language   string
+		device     string
+
+		mu      sync.RWMutex
+		token   string
+		refresh string
+		expire  time.Time
+	}
+
+	// Option configures a Client.
+	Option func(c *Client)
+)
+
+// WithHTTPClient sets http client that used for sending requests.
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) {
+		c.http = h
+	}
+}
+
+// WithMaxRetries sets number of retries for rate limited requests, zero disables retrying.
+func WithMaxRetries(n int) Option {
+	return func(c *Client) {
+		c.maxRetries = n
+	}
+}
+
+// WithTenant sets tenant that requests are sent for by X-Tenant header.
+func WithTenant(id string) Option {
+	return func(c *Client) {
+		c.tenant = id
+	}
+}
+
+// WithLanguage sets language of error messages and one time password texts by Accept-Language header, e.g. "fa".
+func WithLanguage(lang string) Option {
+	return func(c *Client) {
+		c.language = lang
+	}
+}
+
+// WithDeviceID sets stable identifier of device that requests are sent from by X-Device-ID header,
+// sign in attempts from known devices are less risky.
+func WithDeviceID(id string) Option {
+	return func(c *Client) {
+		c.device = id
+	}
+}
+
+// WithTokens sets previously issued access and refresh tokens.
+func WithTokens(token, refresh string) Option {
+	return func(c *Client) {
+		c.token = token
+		c.refresh = refresh
+	}
+}
+
+// New returns a Client for api server located at baseURL (e.g. "https://otpapp.com").
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base url: %s", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		http:       &http.Client{Timeout: DefaultTimeout},
+		maxRetries: DefaultMaxRetries,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Tokens returns current access token, refresh token and access token expiration time.
+func (c *Client) Tokens() (token, refresh string, expire time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.token, c.refresh, c.expire
+}
+
+// SetTokens replaces current access and refresh tokens.
+func (c *Client) SetTokens(token, refresh string, expire time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token, c.refresh, c.expire = token, refresh, expire
+}
+
+// call sends request to api and decodes BaseResult.Result into out.
+// authenticated requests are retried once with refreshed tokens on authentication failure (401),
+// requests that are forbidden for caller (403) are not retried.
+func (c *Client) call(ctx context.Context, method, path string, in, out interface{}, authenticated bool) error {
+	err := c.do(ctx, method, path, in, out, authenticated, nil)
+	if !authenticated || !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+
+	_, refresh, _ := c.Tokens()
+	if refresh == "" {
+		return err
+	}
+
+	if _, rErr := c.Refresh(ctx); rErr != nil {
+		return err
+	}
+
+	return c.do(ctx, method, path, in, out, authenticated, nil)
+}
+
+// do sends request and retries it when api responds with 429 status code or when a previous attempt
+// of POST request is still in flight. POST requests carry an Idempotency-Key that is the same for all
+// attempts, so retried requests are not processed twice. header is added to every attempt.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}, authenticated bool, header http.Header) error {
+	var key string
+	if method == http.MethodPost {
+		key = idempotencyKey()
+	}
+
+	var body []byte
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = b
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := c.newRequest(ctx, method, path, body, authenticated)
+		if err != nil {
+			return err
+		}
+
+		if key != "" {
+			req.Header.Set(api.IdempotencyKeyHeader, key)
+		}
+
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		res, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+
+		if retryable(res) && attempt < c.maxRetries {
+			wait := retryAfter(res.Header, attempt)
+			drain(res)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+
+			continue
+		}
+
+		return decode(res, out)
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body []byte, authenticated bool) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if c.tenant != "" {
+		req.Header.Set(api.TenantHeader, c.tenant)
+	}
+
+	if c.language != "" {
+		req.Header.Set("Accept-Language", c.language)
+	}
+
+	if c.device != "" {
+		req.Header.Set(api.DeviceIDHeader, c.device)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if authenticated {
+		token, _, _ := c.Tokens()
+		if token == "" {
+			return nil, ErrUnauthorized
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req, nil
+}
+
+// decode reads api response and maps its errors to *Error.
+func decode(res *http.Response, out interface{}) error {
+	defer drain(res)
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	r := &result{}
+	if len(raw) > 0 && json.Unmarshal(raw, r) != nil {
+		// some middlewares respond with a bare json string instead of BaseResult.
+		var msg string
+		if json.Unmarshal(raw, &msg) == nil {
+			r.Errors = []string{msg}
+		}
+	}
+
+	if res.StatusCode >= http.StatusBadRequest || len(r.Errors) > 0 {
+		return newError(res.StatusCode, r.Errors, r.ErrorDetails)
+	}
+
+	if out == nil || len(r.Result) == 0 || string(r.Result) == "null" {
+		return nil
+	}
+
+	return json.Unmarshal(r.Result, out)
+}
+
+// retryable reports whether request can be retried, it is rate limited or its previous attempt is in flight.
+func retryable(res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+
+	case http.StatusConflict:
+		return res.Header.Get("Retry-After") != ""
+	}
+
+	return false
+}
+
+// idempotencyKey returns a random Idempotency-Key.
+func idempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// retryAfter returns wait duration before retrying a rate limited request.
+// it honors Retry-After and RateLimit-Reset headers and falls back to exponential backoff.
+func retryAfter(h http.Header, attempt int) time.Duration {
+	for _, name := range []string{"Retry-After", "RateLimit-Reset"} {
+		v := h.Get(name)
+		if v == "" {
+			continue
+		}
+
+		if sec, err := strconv.Atoi(v); err == nil {
+			return capBackoff(time.Duration(sec) * time.Second)
+		}
+
+		if t, err := http.ParseTime(v); err == nil {
+			return capBackoff(time.Until(t))
+		}
+	}
+
+	return capBackoff((500 * time.Millisecond) << attempt)
+}
+
+func capBackoff(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+
+	if d > maxBackoff {
+		return maxBackoff
+	}
+
+	return d
+}
+
+func drain(res *http.Response) {
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+}
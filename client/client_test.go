@@ -0,0 +1,303 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/ppeymann/top-app.git/auth"
+	"github.com/ppeymann/top-app.git/client"
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/repository"
+	"github.com/ppeymann/top-app.git/server"
+	"github.com/ppeymann/top-app.git/services/user"
+	"github.com/ppeymann/top-app.git/tenant"
+	validations "github.com/ppeymann/top-app.git/validation"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	apiOnce sync.Once
+	apiURL  string
+)
+
+// newAPI returns url of a test server that runs real handlers of user service, server is shared by
+// tests because metrics of server are registered once per process. redis is unreachable, so rate
+// limits are counted in process. sign up is limited to a request per second for every mobile.
+func newAPI(t *testing.T) string {
+	t.Helper()
+
+	apiOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "otpapp-client")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conf := config.Default()
+		conf.Http.Mode = "test"
+		conf.Database.Driver = repository.SQLite
+		conf.Database.DSN = filepath.Join(dir, "otpapp.db")
+		conf.Paseto.SymmetricKey = "0123456789abcdef0123456789abcdef"
+		conf.Jwt = config.Jwt{TokenExpire: 60, RefreshExpire: 120, Issuer: "otpapp.com", Audience: "otpapp.com"}
+		conf.Otp = config.OtpConfig{Driver: "log", ExposeCode: true}
+		conf.RateLimit = config.RateLimitConfig{
+			Enabled:                     true,
+			RateLimitRequestPerDuration: 1000,
+			RateLimitDurationSeconds:    10,
+			Policies: map[string]config.RateLimitPolicy{
+				"signup": {Requests: 1, DurationSeconds: 1, Key: server.RateLimitKeyMobile},
+			},
+		}
+		conf.ProofOfWork = config.ProofOfWorkConfig{
+			Enabled:          true,
+			Secret:           "fedcba9876543210fedcba9876543210",
+			BaseDifficulty:   4,
+			MaxDifficulty:    8,
+			FactorDifficulty: 8,
+		}
+
+		path := filepath.Join(dir, "config.json")
+		b, err := json.Marshal(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		store, err := config.NewStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		logger := kitlog.NewNopLogger()
+
+		maker, err := auth.NewPasetoMaker(conf.Paseto.SymmetricKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tenants, err := tenant.NewRegistry(store.Config(), logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+		svr := server.NewServer(logger, store, rdb, maker, tenants)
+
+		repo, err := repository.NewSQLiteUserRepo(conf.Database.DSN)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		devices := repository.NewRiskRepo(repo.Model())
+		if err := devices.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+
+		schemas, err := validations.NewSchemas(filepath.Join("..", "schemas", "user"), filepath.Join("..", "schemas", "shared"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		srv, err := user.NewValidationService(schemas, user.NewService(repo, devices, store, maker, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		user.NewHandler(user.NewAuthService(srv), svr)
+
+		apiURL = httptest.NewServer(svr.Router).URL
+	})
+
+	if apiURL == "" {
+		t.Fatal("test server is not started")
+	}
+
+	return apiURL
+}
+
+func newClient(t *testing.T, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	c, err := client.New(newAPI(t), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// signUp creates account of mobile and returns tokens of its first sign in.
+func signUp(t *testing.T, mobile string) *client.TokenBundle {
+	t.Helper()
+
+	ctx := context.Background()
+	c := newClient(t)
+
+	otp, err := c.SignUp(ctx, mobile)
+	if err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+
+	bundle, err := c.VerifyOtp(ctx, mobile, otp.Code)
+	if err != nil {
+		t.Fatalf("VerifyOtp() error = %v", err)
+	}
+
+	return bundle
+}
+
+func TestRetryAfter(t *testing.T) {
+	ctx := context.Background()
+	mobile := "09120000001"
+
+	if _, err := newClient(t).SignUp(ctx, mobile); err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+
+	_, err := newClient(t, client.WithMaxRetries(0)).SignUp(ctx, mobile)
+	if !errors.Is(err, client.ErrRateLimited) {
+		t.Fatalf("SignUp() without retries error = %v, want ErrRateLimited", err)
+	}
+
+	// request is retried after Retry-After of limit and reaches service
+	start := time.Now()
+
+	_, err = newClient(t).SignUp(ctx, mobile)
+	if !errors.Is(err, client.ErrAccountExists) {
+		t.Fatalf("SignUp() error = %v, want ErrAccountExists", err)
+	}
+
+	if time.Since(start) < 500*time.Millisecond {
+		t.Errorf("SignUp() returned after %s, want it to wait for Retry-After", time.Since(start))
+	}
+}
+
+func TestRefreshOnUnauthorized(t *testing.T) {
+	ctx := context.Background()
+	bundle := signUp(t, "09120000002")
+
+	c := newClient(t, client.WithTokens("expired", bundle.Refresh))
+
+	me, err := c.Me(ctx)
+	if err != nil {
+		t.Fatalf("Me() error = %v", err)
+	}
+
+	if me.Mobile != "09120000002" {
+		t.Errorf("Me().Mobile = %q, want %q", me.Mobile, "09120000002")
+	}
+
+	if token, _, _ := c.Tokens(); token == "expired" {
+		t.Error("Me() did not replace access token")
+	}
+
+	// refresh is tried once, failure of refresh returns authorization failure of request
+	c = newClient(t, client.WithTokens("expired", "expired"))
+	if _, err := c.Me(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("Me() with invalid refresh error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestForbiddenIsNotRefreshed(t *testing.T) {
+	ctx := context.Background()
+	bundle := signUp(t, "09120000005")
+
+	c := newClient(t, client.WithTokens(bundle.Token, bundle.Refresh))
+
+	// listing accounts requires admin role
+	_, err := c.ListUsers(ctx, 1, 10)
+	if !errors.Is(err, client.ErrForbidden) || errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("ListUsers() error = %v, want ErrForbidden", err)
+	}
+
+	if token, refresh, _ := c.Tokens(); token != bundle.Token || refresh != bundle.Refresh {
+		t.Error("ListUsers() refreshed tokens of forbidden request")
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	ctx := context.Background()
+	mobile := "09120000003"
+
+	if _, err := newClient(t).SignUp(ctx, mobile); err != nil {
+		t.Fatalf("SignUp() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func(c *client.Client) error
+		want error
+		code string
+	}{
+		{
+			name: "otp invalid",
+			call: func(c *client.Client) error {
+				_, err := c.VerifyOtp(ctx, mobile, "000000")
+				return err
+			},
+			want: client.ErrOtpInvalid,
+			code: "OTP_INVALID",
+		},
+		{
+			name: "otp not expired",
+			call: func(c *client.Client) error {
+				_, err := c.SignIn(ctx, mobile)
+				return err
+			},
+			want: client.ErrOtpNotExpired,
+			code: "OTP_NOT_EXPIRED",
+		},
+		{
+			name: "account not found",
+			call: func(c *client.Client) error {
+				_, err := c.SignIn(ctx, "09120000004")
+				return err
+			},
+			want: client.ErrNotFound,
+			code: "ACCOUNT_NOT_FOUND",
+		},
+		{
+			name: "refresh invalid",
+			call: func(c *client.Client) error {
+				c.SetTokens("", "invalid", time.Time{})
+				_, err := c.Refresh(ctx)
+				return err
+			},
+			want: client.ErrInvalidToken,
+			code: "REFRESH_INVALID",
+		},
+		{
+			name: "validation failed",
+			call: func(c *client.Client) error {
+				_, err := c.VerifyOtp(ctx, mobile, "code")
+				return err
+			},
+			want: client.ErrBadRequest,
+			code: "VALIDATION_FAILED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(newClient(t))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+
+			var e *client.Error
+			if !errors.As(err, &e) || len(e.Codes) == 0 || e.Codes[0] != tt.code {
+				t.Errorf("error codes = %v, want %s", e, tt.code)
+			}
+		})
+	}
+}
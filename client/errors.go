@@ -0,0 +1,158 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ppeymann/top-app.git/api"
+)
+
+// Errors that api responses are mapped to, use errors.Is for checking them.
+var (
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrForbidden     = errors.New("forbidden")
+	ErrRateLimited   = errors.New("rate limit exceeded")
+	ErrBadRequest    = errors.New("bad request")
+	ErrNotFound      = errors.New("not found")
+	ErrAccountExists = errors.New("account already exists")
+	ErrOtpInvalid    = errors.New("otp is not correct")
+	ErrOtpExpired    = errors.New("otp expired")
+	ErrOtpNotExpired = errors.New("previous otp not expired")
+	ErrInvalidToken  = errors.New("refresh token is not valid")
+	ErrSuspended     = errors.New("account is suspended")
+	ErrServer        = errors.New("server error")
+	ErrInProgress    = errors.New("request is in progress")
+	ErrPrecondition  = errors.New("precondition failed")
+	ErrRiskBlocked   = errors.New("sign in is blocked by risk policy")
+	ErrFactorNeeded  = errors.New("extra verification factor is required")
+	ErrUntrusted     = errors.New("device is not trusted")
+	ErrProofRequired = errors.New("proof of work is required")
+	ErrProofInvalid  = errors.New("proof of work is not valid")
+)
+
+// codes maps error codes of BaseResult.ErrorDetails to client errors.
+var codes = map[string]error{
+	api.CodeUnauthorized:          ErrUnauthorized,
+	api.CodePermissionDenied:      ErrForbidden,
+	api.CodeNotFound:              ErrNotFound,
+	api.CodeInternal:              ErrServer,
+	api.CodeUnhandled:             ErrServer,
+	api.CodeServiceUnavailable:    ErrServer,
+	api.CodeInvalidBody:           ErrBadRequest,
+	api.CodeInvalidParam:          ErrBadRequest,
+	api.CodeValidationFailed:      ErrBadRequest,
+	api.CodeRateLimited:           ErrRateLimited,
+	api.CodePreconditionFailed:    ErrPrecondition,
+	api.CodePreconditionRequired:  ErrPrecondition,
+	api.CodeInvalidIdempotencyKey: ErrBadRequest,
+	api.CodeIdempotencyKeyReused:  ErrBadRequest,
+	api.CodeIdempotencyInFlight:   ErrInProgress,
+	api.CodeRiskBlocked:           ErrRiskBlocked,
+	api.CodeExtraFactorRequired:   ErrFactorNeeded,
+	api.CodePowRequired:           ErrProofRequired,
+	api.CodePowInvalid:            ErrProofInvalid,
+	api.CodeAccountExists:         ErrAccountExists,
+	api.CodeAccountNotFound:       ErrNotFound,
+	api.CodeOtpInvalid:            ErrOtpInvalid,
+	api.CodeOtpExpired:            ErrOtpExpired,
+	api.CodeOtpNotExpired:         ErrOtpNotExpired,
+	api.CodeRefreshInvalid:        ErrInvalidToken,
+	api.CodeAccountSuspended:      ErrSuspended,
+	api.CodeDeviceNotFound:        ErrNotFound,
+	api.CodeDeviceNotTrusted:      ErrUntrusted,
+}
+
+// messages maps error messages of BaseResult.Errors to client errors, it is used for
+// servers that respond without error codes.
+var messages = map[string]error{
+	"UnAuthorization Error":                               ErrUnauthorized,
+	"not found":                                           ErrNotFound,
+	"internal server error":                               ErrServer,
+	"please provide required JSON body":                   ErrBadRequest,
+	"please provide required params":                      ErrBadRequest,
+	"account with specified params already exists":        ErrAccountExists,
+	"specified account does not exist":                    ErrNotFound,
+	"OTP is not correct":                                  ErrOtpInvalid,
+	"OTP Expired":                                         ErrOtpExpired,
+	"previous otp not expired, please wait a few minutes": ErrOtpNotExpired,
+	"refresh token is not valid or expired":               ErrInvalidToken,
+	"account is suspended":                                ErrSuspended,
+	"record not found":                                    ErrNotFound,
+	"rate limit exceeded":                                 ErrRateLimited,
+}
+
+// Error is returned for api calls that respond with error status or BaseResult.Errors.
+type Error struct {
+	// StatusCode is the http status code of response
+	StatusCode int
+
+	// Messages is BaseResult.Errors of response
+	Messages []string
+
+	// Codes are codes of BaseResult.ErrorDetails of response
+	Codes []string
+
+	kinds []error
+}
+
+func newError(status int, msgs []string, details []errorDetail) *Error {
+	e := &Error{
+		StatusCode: status,
+		Messages:   msgs,
+	}
+
+	switch {
+	case status == http.StatusUnauthorized:
+		e.kinds = append(e.kinds, ErrUnauthorized)
+	case status == http.StatusForbidden:
+		e.kinds = append(e.kinds, ErrForbidden)
+	case status == http.StatusTooManyRequests:
+		e.kinds = append(e.kinds, ErrRateLimited)
+	case status == http.StatusNotFound:
+		e.kinds = append(e.kinds, ErrNotFound)
+	case status >= http.StatusInternalServerError:
+		e.kinds = append(e.kinds, ErrServer)
+	case status >= http.StatusBadRequest:
+		e.kinds = append(e.kinds, ErrBadRequest)
+	}
+
+	for _, detail := range details {
+		e.Codes = append(e.Codes, detail.Code)
+		if kind, ok := codes[detail.Code]; ok {
+			e.kinds = append(e.kinds, kind)
+		}
+	}
+
+	if len(details) > 0 {
+		return e
+	}
+
+	for _, msg := range msgs {
+		if kind, ok := messages[msg]; ok {
+			e.kinds = append(e.kinds, kind)
+		}
+	}
+
+	return e
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	if len(e.Messages) == 0 {
+		return "otpapp: " + http.StatusText(e.StatusCode)
+	}
+
+	return "otpapp: " + strings.Join(e.Messages, "; ")
+}
+
+// Is reports whether e is mapped to target client error.
+func (e *Error) Is(target error) bool {
+	for _, kind := range e.kinds {
+		if kind == target {
+			return true
+		}
+	}
+
+	return false
+}
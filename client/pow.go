@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ppeymann/top-app.git/api"
+)
+
+// Challenge requests a proof-of-work challenge for routes that send one time password.
+func (c *Client) Challenge(ctx context.Context) (*Challenge, error) {
+	return c.challenge(ctx, false)
+}
+
+func (c *Client) challenge(ctx context.Context, factor bool) (*Challenge, error) {
+	out := &Challenge{}
+
+	path := api.PowChallengePath
+	if factor {
+		path += "?purpose=factor"
+	}
+
+	err := c.call(ctx, http.MethodGet, path, nil, out, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// callProven sends POST request of a route that may require proof of work. if api rejects it for missing
+// or invalid proof or risk engine asks for an extra factor, a challenge is requested and solved for mobile
+// and request is sent again with its solution.
+func (c *Client) callProven(ctx context.Context, path, mobile string, in, out interface{}) error {
+	var header http.Header
+
+	for attempt := 0; ; attempt++ {
+		err := c.do(ctx, http.MethodPost, path, in, out, false, header)
+
+		factor := errors.Is(err, ErrFactorNeeded)
+		if attempt == 2 || (!factor && !errors.Is(err, ErrProofRequired) && !errors.Is(err, ErrProofInvalid)) {
+			return err
+		}
+
+		header, err = c.prove(ctx, mobile, factor)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// prove requests and solves a challenge for mobile and returns headers of its solution.
+func (c *Client) prove(ctx context.Context, mobile string, factor bool) (http.Header, error) {
+	challenge, err := c.challenge(ctx, factor)
+	if err != nil {
+		return nil, err
+	}
+
+	// api binds solution to mobile of request body as it is trimmed
+	nonce, err := api.Solve(ctx, challenge.Token, challenge.Difficulty, strings.TrimSpace(mobile))
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set(api.PowChallengeHeader, challenge.Token)
+	header.Set(api.PowNonceHeader, nonce)
+
+	return header, nil
+}
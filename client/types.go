@@ -0,0 +1,100 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type (
+	// OtpOutput is result of requests that issue a one time password.
+	OtpOutput struct {
+		// Mobile is the mobile number that one time password issued for
+		Mobile string `json:"mobile"`
+
+		// Code is the issued one time password, it is only returned by servers in development mode
+		Code string `json:"code,omitempty"`
+
+		// Expire is expiration time of one time password
+		Expire time.Time `json:"expire"`
+	}
+
+	// TokenBundle is result of requests that sign in user.
+	TokenBundle struct {
+		Token   string    `json:"token"`
+		Refresh string    `json:"refresh"`
+		Expire  time.Time `json:"expire"`
+
+		// DeviceToken signs in device without one time password until DeviceExpire, it is only
+		// returned when device trust is requested
+		DeviceToken  string     `json:"device_token,omitempty"`
+		DeviceExpire *time.Time `json:"device_expire,omitempty"`
+	}
+
+	// User is account of a user.
+	User struct {
+		ID        uint      `json:"ID"`
+		CreatedAt time.Time `json:"CreatedAt"`
+		UpdatedAt time.Time `json:"UpdatedAt"`
+		TenantID  string    `json:"tenant_id"`
+		Mobile    string    `json:"mobile"`
+		Roles     []string  `json:"roles"`
+		Suspended bool      `json:"suspended"`
+		Locale    string    `json:"locale"`
+	}
+
+	// Device is a device that user signed in from.
+	Device struct {
+		ID           uint       `json:"ID"`
+		CreatedAt    time.Time  `json:"CreatedAt"`
+		UpdatedAt    time.Time  `json:"UpdatedAt"`
+		TenantID     string     `json:"tenant_id"`
+		UserID       uint       `json:"user_id"`
+		Fingerprint  string     `json:"fingerprint"`
+		Name         string     `json:"name"`
+		LastIP       string     `json:"last_ip"`
+		LastASN      uint32     `json:"last_asn"`
+		LastSeenAt   time.Time  `json:"last_seen_at"`
+		TrustedUntil *time.Time `json:"trusted_until,omitempty"`
+	}
+
+	// Challenge is a proof-of-work challenge that is solved by api.Solve.
+	Challenge struct {
+		Token      string    `json:"token"`
+		Difficulty int       `json:"difficulty"`
+		Algorithm  string    `json:"algorithm"`
+		Expire     time.Time `json:"expire"`
+	}
+
+	// errorDetail is machine-readable code of an error of response.
+	errorDetail struct {
+		Code        string `json:"code"`
+		Description string `json:"description"`
+		Field       string `json:"field,omitempty"`
+	}
+
+	// result is envelope of api responses.
+	result struct {
+		Errors       []string        `json:"errors"`
+		ErrorDetails []errorDetail   `json:"error_details,omitempty"`
+		Result       json.RawMessage `json:"result"`
+	}
+
+	mobileInput struct {
+		Mobile string `json:"mobile"`
+	}
+
+	otpInput struct {
+		Mobile       string `json:"mobile"`
+		Verification string `json:"verification"`
+		TrustDevice  bool   `json:"trust_device"`
+	}
+
+	deviceSignInInput struct {
+		Mobile      string `json:"mobile"`
+		DeviceToken string `json:"device_token"`
+	}
+
+	refreshInput struct {
+		Refresh string `json:"refresh"`
+	}
+)
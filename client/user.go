@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ppeymann/top-app.git/api"
+)
+
+// SignUp creates a new account for mobile and issues a one time password, proof-of-work challenge
+// is solved if api requires it.
+func (c *Client) SignUp(ctx context.Context, mobile string) (*OtpOutput, error) {
+	out := &OtpOutput{}
+
+	err := c.callProven(ctx, api.UserPath+"/signup", mobile, &mobileInput{Mobile: mobile}, out)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// SignIn issues a one time password for existing account of mobile, proof-of-work challenge
+// is solved if api requires it.
+func (c *Client) SignIn(ctx context.Context, mobile string) (*OtpOutput, error) {
+	out := &OtpOutput{}
+
+	err := c.callProven(ctx, api.UserPath+"/signin", mobile, &mobileInput{Mobile: mobile}, out)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// VerifyOtp verifies one time password of mobile and keeps issued tokens for authenticated calls.
+func (c *Client) VerifyOtp(ctx context.Context, mobile, code string) (*TokenBundle, error) {
+	return c.verifyOtp(ctx, &otpInput{
+		Mobile:       mobile,
+		Verification: code,
+	})
+}
+
+// VerifyOtpAndTrust verifies one time password like VerifyOtp and trusts device of client, DeviceToken of
+// result signs in device by DeviceSignIn without one time password. device is identified by WithDeviceID.
+func (c *Client) VerifyOtpAndTrust(ctx context.Context, mobile, code string) (*TokenBundle, error) {
+	return c.verifyOtp(ctx, &otpInput{
+		Mobile:       mobile,
+		Verification: code,
+		TrustDevice:  true,
+	})
+}
+
+// DeviceSignIn signs in trusted device of client by its device token and keeps issued tokens for authenticated calls.
+func (c *Client) DeviceSignIn(ctx context.Context, mobile, deviceToken string) (*TokenBundle, error) {
+	out := &TokenBundle{}
+
+	in := &deviceSignInInput{
+		Mobile:      mobile,
+		DeviceToken: deviceToken,
+	}
+
+	err := c.callProven(ctx, api.UserPath+"/devices/signin", mobile, in, out)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetTokens(out.Token, out.Refresh, out.Expire)
+
+	return out, nil
+}
+
+// TrustedDevices returns trusted devices of authenticated user.
+func (c *Client) TrustedDevices(ctx context.Context) ([]Device, error) {
+	var out []Device
+
+	err := c.call(ctx, http.MethodGet, api.UserPath+"/devices", nil, &out, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// RevokeDevice revokes trust of device of authenticated user.
+func (c *Client) RevokeDevice(ctx context.Context, id uint) error {
+	return c.call(ctx, http.MethodDelete, fmt.Sprintf("%s/devices/%d", api.UserPath, id), nil, nil, true)
+}
+
+func (c *Client) verifyOtp(ctx context.Context, in *otpInput) (*TokenBundle, error) {
+	out := &TokenBundle{}
+
+	err := c.callProven(ctx, api.UserPath+"/otp", in.Mobile, in, out)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetTokens(out.Token, out.Refresh, out.Expire)
+
+	return out, nil
+}
+
+// Refresh exchanges current refresh token for a new token bundle.
+func (c *Client) Refresh(ctx context.Context) (*TokenBundle, error) {
+	_, refresh, _ := c.Tokens()
+	if refresh == "" {
+		return nil, ErrInvalidToken
+	}
+
+	out := &TokenBundle{}
+
+	err := c.do(ctx, http.MethodPost, api.UserPath+"/refresh", &refreshInput{Refresh: refresh}, out, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetTokens(out.Token, out.Refresh, out.Expire)
+
+	return out, nil
+}
+
+// Me returns account of authenticated user.
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	out := &User{}
+
+	err := c.call(ctx, http.MethodGet, api.UserPath+"/", nil, out, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// ListUsers returns specified page of user accounts.
+func (c *Client) ListUsers(ctx context.Context, page, limit int32) ([]User, error) {
+	var out []User
+
+	err := c.call(ctx, http.MethodGet, fmt.Sprintf("%s/%d/%d", api.UserPath, limit, page), nil, &out, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
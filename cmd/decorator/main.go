@@ -0,0 +1,351 @@
+// decorator generates decorators of a service interface, methods of "logging" decorator log calls by
+// logging.Call, methods of "tracing" decorator trace calls by tracing.Call and methods of "validation"
+// decorator validate their pointer inputs by json schemas. methods must accept context.Context as first
+// parameter and return *otpapp.BaseResult.
+//
+// Usage:
+//
+//	//go:generate go run ../../cmd/decorator -kind logging -source ../../models/user.go -interface UserService -import github.com/ppeymann/top-app.git/models
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const header = `// Code generated by cmd/decorator; DO NOT EDIT.
+
+package %s
+
+`
+
+type method struct {
+	name   string
+	params []param
+}
+
+type param struct {
+	name string
+	typ  string
+}
+
+// kind is template of a decorator.
+type kind struct {
+	// fields of decorator struct, they are parameters of constructor too.
+	fields []param
+
+	// imports of generated file in addition to imports of parameter types.
+	imports []string
+
+	// call returns body of method, its arguments are method name, arguments and inputs of the call.
+	call func(name, args string, inputs []param) string
+
+	// require returns statements of constructor that check inputs of every method, constructor
+	// returns error too if it is set.
+	require func(inputs []param) string
+
+	// doc is doc comment of constructor.
+	doc string
+}
+
+var kinds = map[string]kind{
+	"logging": {
+		fields:  []param{{"logger", "kitlog.Logger"}},
+		imports: []string{`kitlog "github.com/go-kit/log"`, `"github.com/ppeymann/top-app.git/logging"`},
+		call: func(name, args string, inputs []param) string {
+			in := ""
+			for _, p := range inputs {
+				in += ", " + p.name
+			}
+
+			return fmt.Sprintf("return logging.Call(ctx, d.logger, %q, func() *otpapp.BaseResult {\nreturn d.next.%s(%s)\n}%s)",
+				name, name, args, in)
+		},
+		doc: "logs every call of srv by logging.Call",
+	},
+	"tracing": {
+		fields:  []param{{"layer", "string"}},
+		imports: []string{`"github.com/ppeymann/top-app.git/tracing"`},
+		call: func(name, args string, _ []param) string {
+			return fmt.Sprintf("return tracing.Call(ctx, d.layer, %q, func(ctx context.Context) *otpapp.BaseResult {\nreturn d.next.%s(%s)\n})",
+				name, name, args)
+		},
+		doc: "traces every call of srv in a span named by layer and method",
+	},
+	"validation": {
+		fields:  []param{{"schemas", "*validations.Schemas"}},
+		imports: []string{`validations "github.com/ppeymann/top-app.git/validation"`},
+		call: func(name, args string, inputs []param) string {
+			body := ""
+			for _, p := range validated(inputs) {
+				body += fmt.Sprintf("if result := d.schemas.Validate(ctx, %s); result != nil {\nreturn result\n}\n\n", p.name)
+			}
+
+			return body + fmt.Sprintf("return d.next.%s(%s)", name, args)
+		},
+		require: func(inputs []param) string {
+			seen := map[string]bool{}
+			var types []string
+			for _, p := range validated(inputs) {
+				if !seen[p.typ] {
+					seen[p.typ] = true
+					types = append(types, fmt.Sprintf("(%s)(nil)", p.typ))
+				}
+			}
+
+			return fmt.Sprintf("if err := schemas.Require(%s); err != nil {\nreturn nil, err\n}\n\n", strings.Join(types, ", "))
+		},
+		doc: "validates every input of srv with json schema of its type, it reports error if schema of any input is not loaded",
+	},
+}
+
+// validated returns inputs that are validated by json schemas, they are pointers to structs.
+func validated(inputs []param) []param {
+	var out []param
+	for _, p := range inputs {
+		if strings.HasPrefix(p.typ, "*") {
+			out = append(out, p)
+		}
+	}
+
+	return out
+}
+
+func main() {
+	source := flag.String("source", "", "go file that declares the service interface")
+	iface := flag.String("interface", "", "name of the service interface")
+	importPath := flag.String("import", "", "import path of the package of source file")
+	kindName := flag.String("kind", "logging", "decorator kind: logging, tracing or validation")
+	output := flag.String("output", "", "generated file, default: <kind>.go")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package of generated file, defaults to package of go:generate directive")
+	flag.Parse()
+
+	if *source == "" || *iface == "" || *importPath == "" || *pkg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	k, ok := kinds[*kindName]
+	if !ok {
+		log.Fatalf("unknown decorator kind %q", *kindName)
+	}
+
+	if *output == "" {
+		*output = *kindName + ".go"
+	}
+
+	b, err := generate(k, *kindName, *source, *iface, *importPath, *pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*output, b, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func generate(k kind, kindName, source, iface, importPath, pkg string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, source, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// imports of source file by name, they are used by parameter types
+	imports := map[string]string{}
+	for _, spec := range file.Imports {
+		p, _ := strconv.Unquote(spec.Path.Value)
+
+		name := path.Base(strings.TrimSuffix(p, ".git"))
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+
+		imports[name] = p
+	}
+
+	srcPkg := file.Name.Name
+	imports[srcPkg] = importPath
+
+	var it *ast.InterfaceType
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok && spec.Name.Name == iface {
+			it, _ = spec.Type.(*ast.InterfaceType)
+		}
+
+		return it == nil
+	})
+
+	if it == nil {
+		return nil, fmt.Errorf("interface %s is not declared in %s", iface, source)
+	}
+
+	used := map[string]bool{srcPkg: true, "context": true, "otpapp": true}
+	methods := []method{}
+
+	for _, field := range it.Methods.List {
+		ft, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			// embedded interfaces are not supported
+			return nil, fmt.Errorf("%s: embedded interfaces are not supported", iface)
+		}
+
+		m := method{name: field.Names[0].Name}
+		for i, p := range ft.Params.List {
+			typ := qualify(p.Type, srcPkg, used)
+
+			names := p.Names
+			if len(names) == 0 {
+				names = []*ast.Ident{{Name: fmt.Sprintf("p%d", i)}}
+			}
+
+			for _, n := range names {
+				m.params = append(m.params, param{name: n.Name, typ: typ})
+			}
+		}
+
+		if len(m.params) == 0 || m.params[0].typ != "context.Context" {
+			return nil, fmt.Errorf("%s.%s: first parameter must be context.Context", iface, m.name)
+		}
+
+		m.params[0].name = "ctx"
+		methods = append(methods, m)
+	}
+
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].name < methods[j].name
+	})
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, header, pkg)
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	buf.WriteString("import (\n")
+	for _, name := range names {
+		p, ok := imports[name]
+		if !ok {
+			return nil, fmt.Errorf("import of package %s is not found in %s", name, source)
+		}
+
+		if name == path.Base(strings.TrimSuffix(p, ".git")) {
+			fmt.Fprintf(buf, "%q\n", p)
+			continue
+		}
+
+		fmt.Fprintf(buf, "%s %q\n", name, p)
+	}
+
+	for _, imp := range k.imports {
+		buf.WriteString(imp + "\n")
+	}
+
+	buf.WriteString(")\n\n")
+
+	qualified := srcPkg + "." + iface
+	typ := kindName + "Service"
+
+	fmt.Fprintf(buf, "type %s struct {\nnext %s\n", typ, qualified)
+	for _, f := range k.fields {
+		fmt.Fprintf(buf, "%s %s\n", f.name, f.typ)
+	}
+
+	buf.WriteString("}\n\n")
+
+	var all []param
+	for _, m := range methods {
+		var params, args []string
+		var inputs []param
+		for _, p := range m.params {
+			params = append(params, p.name+" "+p.typ)
+			args = append(args, p.name)
+
+			if p.name != "ctx" {
+				inputs = append(inputs, p)
+			}
+		}
+
+		all = append(all, inputs...)
+
+		fmt.Fprintf(buf, "// %s implements %s.\n", m.name, qualified)
+		fmt.Fprintf(buf, "func (d *%s) %s(%s) *otpapp.BaseResult {\n", typ, m.name, strings.Join(params, ", "))
+		fmt.Fprintf(buf, "%s\n}\n\n", k.call(m.name, strings.Join(args, ", "), inputs))
+	}
+
+	ctor := "New" + strings.ToUpper(kindName[:1]) + kindName[1:] + "Service"
+	var ctorParams []string
+	for _, f := range k.fields {
+		ctorParams = append(ctorParams, f.name+" "+f.typ)
+	}
+
+	fmt.Fprintf(buf, "// %s returns %s that %s.\n", ctor, iface, k.doc)
+	if k.require != nil {
+		fmt.Fprintf(buf, "func %s(%s, srv %s) (%s, error) {\n", ctor, strings.Join(ctorParams, ", "), qualified, qualified)
+		buf.WriteString(k.require(all))
+	} else {
+		fmt.Fprintf(buf, "func %s(%s, srv %s) %s {\n", ctor, strings.Join(ctorParams, ", "), qualified, qualified)
+	}
+
+	fmt.Fprintf(buf, "return &%s{\nnext: srv,\n", typ)
+	for _, f := range k.fields {
+		fmt.Fprintf(buf, "%s: %s,\n", f.name, f.name)
+	}
+
+	if k.require != nil {
+		buf.WriteString("}, nil\n}\n")
+	} else {
+		buf.WriteString("}\n}\n")
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// qualify returns type expression of source package in generated package, types of
+// source package are qualified by its name and packages of selectors are collected in used.
+func qualify(expr ast.Expr, srcPkg string, used map[string]bool) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if ast.IsExported(e.Name) {
+			return srcPkg + "." + e.Name
+		}
+
+		return e.Name
+
+	case *ast.SelectorExpr:
+		if x, ok := e.X.(*ast.Ident); ok {
+			used[x.Name] = true
+		}
+
+		return fmt.Sprintf("%s.%s", e.X.(*ast.Ident).Name, e.Sel.Name)
+
+	case *ast.StarExpr:
+		return "*" + qualify(e.X, srcPkg, used)
+
+	case *ast.ArrayType:
+		return "[]" + qualify(e.Elt, srcPkg, used)
+
+	case *ast.MapType:
+		return fmt.Sprintf("map[%s]%s", qualify(e.Key, srcPkg, used), qualify(e.Value, srcPkg, used))
+
+	case *ast.InterfaceType:
+		return "interface{}"
+	}
+
+	panic(fmt.Sprintf("unsupported parameter type %T", expr))
+}
@@ -1,19 +1,26 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"time"
 
 	kitLog "github.com/go-kit/log"
+	"github.com/ppeymann/top-app.git/auth"
 	"github.com/ppeymann/top-app.git/cmd/otpapp/pkg"
 	"github.com/ppeymann/top-app.git/config"
-	"github.com/ppeymann/top-app.git/env"
+	"github.com/ppeymann/top-app.git/migrations"
+	"github.com/ppeymann/top-app.git/repository"
 	"github.com/ppeymann/top-app.git/server"
+	"github.com/ppeymann/top-app.git/tenant"
+	"github.com/ppeymann/top-app.git/tracing"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
-	pg "gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
 )
 
 func main() {
@@ -25,25 +32,66 @@ func main() {
 
 	fmt.Println("date:", base, "start:", start, "end:", end)
 
-	config, err := config.NewConfiguration()
+	configPath := flag.String("config", "", "path of json, yaml or toml configuration file (env OTPAPP_CONFIG)")
+	flag.Parse()
+
+	store, err := config.NewStore(*configPath)
+	if err != nil {
+		log.Fatal(err)
+
+		return
+	}
+
+	config := store.Config()
+
+	// tracer provider and trace context propagation
+	shutdownTracing, err := tracing.Init(config.Tracing)
 	if err != nil {
 		log.Fatal(err)
 
 		return
 	}
 
-	db, err := gorm.Open(pg.Open(env.GetEnv("DSN", "")), &gorm.Config{SkipDefaultTransaction: true})
+	db, err := repository.Open(config.Database.Driver, config.Database.DSN)
 	if err != nil {
 		log.Fatal(err)
 
 		return
 	}
 
+	// spans of database queries
+	if err := db.Use(gormtracing.NewPlugin(gormtracing.WithoutMetrics())); err != nil {
+		log.Fatal(err)
+
+		return
+	}
+
+	if config.Database.Driver == repository.SQLite {
+		// local development database, schema is created by AutoMigrate
+		if err := repository.NewUserRepo(db, "").Migrate(); err != nil {
+			log.Fatal(err)
+
+			return
+		}
+
+		if err := repository.NewRiskRepo(db).Migrate(); err != nil {
+			log.Fatal(err)
+
+			return
+		}
+	} else {
+		migrate(db, config.Database.AutoMigrate)
+	}
+
 	// configuration logger
 	var logger kitLog.Logger
 	logger = kitLog.NewJSONLogger(kitLog.NewSyncWriter(os.Stderr))
 	logger = kitLog.With(logger, "ts", kitLog.DefaultTimestampUTC)
 
+	for _, warning := range config.Deprecated {
+		_ = logger.Log("component", "config", "warning", warning)
+	}
+
 	// Service Logger
 	sl := kitLog.With(logger, "component", "http")
 
@@ -55,13 +103,93 @@ func main() {
 
 	redisClient := redis.NewClient(redisClientOpt)
 
+	// spans of redis commands
+	if err := redisotel.InstrumentTracing(redisClient); err != nil {
+		log.Fatal(err)
+
+		return
+	}
+
+	// token maker that rejects tokens revoked by otpctl
+	maker, err := auth.NewPasetoMaker(config.Paseto.SymmetricKey)
+	if err != nil {
+		log.Fatal(err)
+
+		return
+	}
+
+	paseto := auth.NewRevocableMaker(maker, auth.NewRedisRevocationStore(redisClient))
+
+	// tenant registry
+	tenants, err := tenant.NewRegistry(config, logger)
+	if err != nil {
+		log.Fatal(err)
+
+		return
+	}
+
 	// Server instance
-	svr := server.NewServer(sl, config, redisClient)
+	svr := server.NewServer(sl, store, redisClient, paseto, tenants)
+
+	// database check is named by its driver, e.g. "postgres" or "sqlite"
+	svr.Health.Register(config.Database.Driver, 0, func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+
+		return sqlDB.PingContext(ctx)
+	})
+
+	// configuration and schemas are reloaded on file change, SIGHUP and admin endpoint
+	reloader := pkg.InitReload(kitLog.With(logger, "component", "reload"), store, svr)
 
 	// --------   SERVICES   --------
-	pkg.InitUserService(db, sl, config, svr)
+	pkg.InitUserService(db, redisClient, sl, store, svr, paseto, reloader)
+
+	go func() {
+		if err := reloader.Watch(context.Background()); err != nil {
+			_ = logger.Log("component", "reload", "err", err)
+		}
+	}()
 
 	// listen and serve...
 	svr.Listen()
 
+	// export remaining spans
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := shutdownTracing(ctx); err != nil {
+		_ = logger.Log("component", "tracing", "err", err)
+	}
+}
+
+// migrate applies versioned migrations on startup or runs "otpapp migrate" subcommand and exits.
+func migrate(db *gorm.DB, auto bool) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrator, err := migrations.New(sqlDB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// otpapp [-config <path>] migrate up|down|status
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err := migrations.Run(context.Background(), migrator, args[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+
+		os.Exit(0)
+	}
+
+	// replicas starting together are serialized by migrations advisory lock
+	if auto {
+		if _, err := migrator.Up(context.Background(), false); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
@@ -0,0 +1,45 @@
+package pkg
+
+import (
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/reload"
+	"github.com/ppeymann/top-app.git/server"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	kitLog "github.com/go-kit/log"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// InitReload returns reload manager of configuration and registers its administration endpoints,
+// services register their reloadable components to returned manager.
+func InitReload(logger kitLog.Logger, store *config.Store, server *server.Server) *reload.Manager {
+	manager := reload.NewManager(
+		logger,
+		prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "api",
+			Subsystem: "config",
+			Name:      "reload_count",
+			Help:      "number of configuration reloads by component and result.",
+		}, []string{"component", "result"}),
+		prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "api",
+			Subsystem: "config",
+			Name:      "version_info",
+			Help:      "active version of components, value of active version is 1.",
+		}, []string{"component", "version"}),
+	)
+
+	// tenants are rebuilt from reloaded configuration before it is swapped
+	store.OnReload(server.Tenants.Prepare)
+	manager.Register("config", store)
+
+	// certificates are reloaded without dropping established connections
+	if server.Certificates != nil {
+		manager.Register("tls", server.Certificates)
+	}
+
+	reload.NewHandler(manager, server)
+
+	return manager
+}
@@ -0,0 +1,67 @@
+package pkg
+
+import (
+	"time"
+
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/repository"
+	"github.com/ppeymann/top-app.git/risk"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// InitRiskEngine returns risk engine of sign in attempts, its policy and ASN database are replaced when configuration
+// is reloaded. engine is created even if risk evaluation is disabled, so it can be enabled by reloading configuration.
+func InitRiskEngine(db *gorm.DB, redis *redis.Client, store *config.Store) (*risk.Engine, error) {
+	asn, policy, err := riskPolicy(store.Config().Risk)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := risk.NewEngine(repository.NewRiskRepo(db), risk.NewRedisVelocity(redis), asn, policy)
+
+	// ASN database of reloaded configuration is loaded before it is swapped, so a broken database keeps active policy
+	store.OnReload(func(conf *config.Configuration) (func(), error) {
+		asn, policy, err := riskPolicy(conf.Risk)
+		if err != nil {
+			return nil, err
+		}
+
+		return func() {
+			engine.Update(asn, policy)
+		}, nil
+	})
+
+	return engine, nil
+}
+
+// riskPolicy returns ASN resolver and policy of risk configuration.
+func riskPolicy(conf config.RiskConfig) (risk.ASNResolver, risk.Policy, error) {
+	policy := risk.Policy{
+		ChallengeScore: conf.ChallengeScore,
+		BlockScore:     conf.BlockScore,
+		VelocityWindow: time.Duration(conf.VelocityWindowSeconds) * time.Second,
+	}
+
+	for _, rule := range conf.Rules {
+		policy.Rules = append(policy.Rules, risk.Rule{
+			Name:      rule.Name,
+			Signal:    rule.Signal,
+			Threshold: rule.Threshold,
+			Score:     rule.Score,
+			Action:    risk.Action(rule.Action),
+		})
+	}
+
+	var asn risk.ASNResolver
+	if conf.Enabled && conf.ASNDatabase != "" {
+		resolver, err := risk.LoadASNDatabase(conf.ASNDatabase)
+		if err != nil {
+			return nil, policy, err
+		}
+
+		asn = resolver
+	}
+
+	return asn, policy, nil
+}
@@ -1,39 +1,74 @@
 package pkg
 
 import (
+	"context"
+	"errors"
 	"log"
 
+	"github.com/ppeymann/top-app.git/auth"
 	"github.com/ppeymann/top-app.git/config"
-	"github.com/ppeymann/top-app.git/env"
 	"github.com/ppeymann/top-app.git/models"
+	"github.com/ppeymann/top-app.git/reload"
 	"github.com/ppeymann/top-app.git/repository"
 	"github.com/ppeymann/top-app.git/server"
 	"github.com/ppeymann/top-app.git/services/user"
+	validations "github.com/ppeymann/top-app.git/validation"
 
 	"github.com/go-kit/kit/metrics/prometheus"
 	kitLog "github.com/go-kit/log"
+	"github.com/redis/go-redis/v9"
 
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
 	"gorm.io/gorm"
 )
 
-func InitUserService(db *gorm.DB, logger kitLog.Logger, conf *config.Configuration, server *server.Server) models.UserService {
-	repo := repository.NewUserRepo(db, env.GetEnv("POSTGRES_DB", ""))
-	err := repo.Migrate()
+func InitUserService(db *gorm.DB, redis *redis.Client, logger kitLog.Logger, store *config.Store, server *server.Server,
+	paseto auth.TokenMaker, reloader *reload.Manager) models.UserService {
+	repo := repository.NewUserRepo(db, store.Config().Database.Name)
+
+	// users are read through redis cache if it is enabled, e.g. by every authenticated request and otp flow
+	repo = repository.NewCachedUserRepo(repo, redis, store,
+		prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "api",
+			Subsystem: "user",
+			Name:      "cache_request_count",
+			Help:      "num of user cache lookups by repository method and result (hit, miss or error).",
+		}, []string{"method", "result"}),
+	)
+
+	// login and otp verification attempts are evaluated by risk engine if it is enabled
+	engine, err := InitRiskEngine(db, redis, store)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// userService create service, every decorator of chain is traced in its own span
+	userService := user.NewService(repo, repository.NewRiskRepo(db), store, paseto, engine)
+	userService = user.NewTracingService("user.service", userService)
+
+	// schemas are reloaded when schema files change, they may refer to shared definitions
+	schemas, err := validations.NewSchemas(getSchemaPath("user"), getSchemaPath("shared"))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// userService create service
-	userService := user.NewService(repo, conf)
+	reloader.Register("schemas/user", schemas)
+	server.Health.Register("schemas/user", 0, func(context.Context) error {
+		if len(schemas.Map()) == 0 {
+			return errors.New("no schema is loaded")
+		}
 
-	// getting path
-	path := getSchemaPath("user")
-	userService, err = user.NewValidationService(userService, path)
+		return nil
+	})
+
+	// startup fails if any input of service has no schema
+	userService, err = user.NewValidationService(schemas, userService)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	userService = user.NewTracingService("user.validation", userService)
+
 	// @Injection Instrumenting service to chain
 	userService = user.NewInstrumentingService(
 		prometheus.NewCounterFrom(stdprometheus.CounterOpts{
@@ -50,9 +85,15 @@ func InitUserService(db *gorm.DB, logger kitLog.Logger, conf *config.Configurati
 		}, fieldKeys),
 		userService,
 	)
+	userService = user.NewTracingService("user.instrumenting", userService)
 
 	// @Injection Authorization service to chain
 	userService = user.NewAuthService(userService)
+	userService = user.NewTracingService("user.auth", userService)
+
+	// @Injection Logging service to chain, it wraps auth service so rejected calls are logged too
+	userService = user.NewLoggingService(logger, userService)
+	userService = user.NewTracingService("user.logging", userService)
 
 	_ = user.NewHandler(userService, server)
 
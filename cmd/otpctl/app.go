@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/ppeymann/top-app.git/auth"
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/migrations"
+	"github.com/ppeymann/top-app.git/models"
+	"github.com/ppeymann/top-app.git/repository"
+	"github.com/ppeymann/top-app.git/tenant"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// app holds otpctl dependencies, database and redis connections are opened on first use.
+type app struct {
+	out   *printer
+	store *config.Store
+	conf  *config.Configuration
+	db    *gorm.DB
+	redis *redis.Client
+	repo  models.UserRepository
+}
+
+func newApp(out *printer, configPath string) (*app, error) {
+	store, err := config.NewStore(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, warning := range store.Config().Deprecated {
+		fmt.Fprintln(os.Stderr, "otpctl: warning:", warning)
+	}
+
+	return &app{
+		out:   out,
+		store: store,
+		conf:  store.Config(),
+	}, nil
+}
+
+func (a *app) database() (*gorm.DB, error) {
+	if a.db != nil {
+		return a.db, nil
+	}
+
+	db, err := repository.Open(a.conf.Database.Driver, a.conf.Database.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	a.db = db
+
+	return a.db, nil
+}
+
+func (a *app) users() (models.UserRepository, error) {
+	if a.repo != nil {
+		return a.repo, nil
+	}
+
+	db, err := a.database()
+	if err != nil {
+		return nil, err
+	}
+
+	a.repo = repository.NewUserRepo(db, a.conf.Database.Name)
+
+	// updates must invalidate users that api caches, e.g. suspended accounts
+	if a.conf.UserCache.Enabled {
+		a.repo = repository.NewCachedUserRepo(a.repo, a.redisClient(), a.store, nil)
+	}
+
+	return a.repo, nil
+}
+
+func (a *app) redisClient() *redis.Client {
+	if a.redis == nil {
+		a.redis = redis.NewClient(&redis.Options{
+			Addr:     a.conf.Redis.Addr,
+			Password: a.conf.Redis.Password,
+			DB:       a.conf.Redis.DB,
+		})
+	}
+
+	return a.redis
+}
+
+func (a *app) revocations() auth.RevocationStore {
+	return auth.NewRedisRevocationStore(a.redisClient())
+}
+
+// refreshLifetime is the lifetime of longest living token that api issues.
+func (a *app) refreshLifetime() time.Duration {
+	return time.Duration(a.conf.Jwt.RefreshExpire) * time.Minute
+}
+
+func (a *app) user(args []string) error {
+	if len(args) == 0 {
+		return errors.New("user: subcommand is required")
+	}
+
+	repo, err := a.users()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("user "+args[0], flag.ExitOnError)
+	id := fs.Uint("id", 0, "user account id")
+	mobile := fs.String("mobile", "", "user account mobile number")
+	tenantID := fs.String("tenant", tenant.DefaultID, "tenant of user accounts")
+	roles := fs.String("roles", "", "comma separated roles")
+	set := fs.String("set", "", "comma separated roles to assign")
+	page := fs.Int("page", 1, "page number")
+	limit := fs.Int("limit", 20, "page size")
+	undo := fs.Bool("undo", false, "unsuspend user account")
+	_ = fs.Parse(args[1:])
+
+	switch args[0] {
+	case "create":
+		if *mobile == "" {
+			return errors.New("user create: -mobile is required")
+		}
+
+		user, err := repo.Create(context.Background(), *tenantID, *mobile)
+		if err != nil {
+			return err
+		}
+
+		if *roles != "" {
+			user.Roles, err = splitRoles(*roles)
+			if err != nil {
+				return err
+			}
+
+			if err := repo.Update(context.Background(), user); err != nil {
+				return err
+			}
+		}
+
+		return a.out.users(*user)
+
+	case "find":
+		user, err := a.findUser(repo, *id, *tenantID, *mobile)
+		if err != nil {
+			return err
+		}
+
+		return a.out.users(*user)
+
+	case "list":
+		users, err := repo.FindAllUser(context.Background(), *tenantID, int32(*page), int32(*limit))
+		if err != nil {
+			return err
+		}
+
+		return a.out.users(users...)
+
+	case "roles":
+		user, err := a.findUser(repo, *id, "", "")
+		if err != nil {
+			return err
+		}
+
+		user.Roles, err = splitRoles(*set)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.Update(context.Background(), user); err != nil {
+			return err
+		}
+
+		// roles are carried by tokens, issued tokens must not keep roles that are removed
+		if err := a.revocations().Revoke(context.Background(), user.ID, a.refreshLifetime()); err != nil {
+			return err
+		}
+
+		return a.out.users(*user)
+
+	case "suspend":
+		user, err := a.findUser(repo, *id, "", "")
+		if err != nil {
+			return err
+		}
+
+		user.Suspended = !*undo
+		if err := repo.Update(context.Background(), user); err != nil {
+			return err
+		}
+
+		if user.Suspended {
+			if err := a.revocations().Revoke(context.Background(), user.ID, a.refreshLifetime()); err != nil {
+				return err
+			}
+		}
+
+		return a.out.users(*user)
+	}
+
+	return fmt.Errorf("user: unknown subcommand %q", args[0])
+}
+
+func (a *app) findUser(repo models.UserRepository, id uint, tenantID, mobile string) (*models.UserEntity, error) {
+	switch {
+	case id != 0:
+		return repo.FindByID(context.Background(), id)
+	case mobile != "":
+		return repo.Find(context.Background(), tenantID, mobile)
+	}
+
+	return nil, errors.New("-id or -mobile is required")
+}
+
+func (a *app) token(args []string) error {
+	if len(args) == 0 {
+		return errors.New("token: subcommand is required")
+	}
+
+	fs := flag.NewFlagSet("token "+args[0], flag.ExitOnError)
+	id := fs.Uint("id", 0, "user account id")
+	ttl := fs.Duration("ttl", 15*time.Minute, "token lifetime")
+	_ = fs.Parse(args[1:])
+
+	if *id == 0 {
+		return fmt.Errorf("token %s: -id is required", args[0])
+	}
+
+	switch args[0] {
+	case "issue":
+		repo, err := a.users()
+		if err != nil {
+			return err
+		}
+
+		user, err := repo.FindByID(context.Background(), *id)
+		if err != nil {
+			return err
+		}
+
+		maker, err := auth.NewPasetoMaker(a.conf.Paseto.SymmetricKey)
+		if err != nil {
+			return err
+		}
+
+		tenants, err := tenant.NewRegistry(a.conf, kitlog.NewNopLogger())
+		if err != nil {
+			return err
+		}
+
+		t, ok := tenants.Get(user.TenantID)
+		if !ok {
+			return fmt.Errorf("tenant %s of user is not configured", user.TenantID)
+		}
+
+		claims := auth.NewClaims(user.ID, auth.AccessToken, t.Jwt.Issuer, t.Jwt.Audience, *ttl)
+		claims.Roles = user.Roles
+		claims.Tenant = t.ID
+
+		token, err := maker.CreateToken(claims)
+		if err != nil {
+			return err
+		}
+
+		return a.out.token(&models.TokenBundlerOutput{
+			Token:  token,
+			Expire: claims.ExpiredAt,
+		})
+
+	case "revoke":
+		err := a.revocations().Revoke(context.Background(), *id, a.refreshLifetime())
+		if err != nil {
+			return err
+		}
+
+		return a.out.message(fmt.Sprintf("tokens of user %d revoked", *id))
+	}
+
+	return fmt.Errorf("token: unknown subcommand %q", args[0])
+}
+
+func (a *app) risk(args []string) error {
+	if len(args) == 0 {
+		return errors.New("risk: subcommand is required")
+	}
+
+	fs := flag.NewFlagSet("risk "+args[0], flag.ExitOnError)
+	id := fs.Uint("id", 0, "user account id")
+	tenantID := fs.String("tenant", tenant.DefaultID, "tenant of decisions")
+	page := fs.Int("page", 1, "page number")
+	limit := fs.Int("limit", 20, "page size")
+	_ = fs.Parse(args[1:])
+
+	switch args[0] {
+	case "decisions":
+		db, err := a.database()
+		if err != nil {
+			return err
+		}
+
+		decisions, err := repository.NewRiskRepo(db).FindDecisions(context.Background(), *tenantID, *id, int32(*page), int32(*limit))
+		if err != nil {
+			return err
+		}
+
+		return a.out.decisions(decisions...)
+	}
+
+	return fmt.Errorf("risk: unknown subcommand %q", args[0])
+}
+
+func (a *app) migrate(args []string) error {
+	db, err := a.database()
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	migrator, err := migrations.New(sqlDB)
+	if err != nil {
+		return err
+	}
+
+	return migrations.Run(context.Background(), migrator, args, a.out.w)
+}
+
+func (a *app) config(_ []string) error {
+	b, err := json.MarshalIndent(a.conf.Redacted(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return a.out.raw(b)
+}
+
+// splitRoles returns roles of comma separated val, roles must be known roles of auth.Roles.
+func splitRoles(val string) (models.Roles, error) {
+	roles := models.Roles{}
+	for _, role := range strings.Split(val, ",") {
+		role = strings.ToUpper(strings.TrimSpace(role))
+		if role == "" {
+			continue
+		}
+
+		if !slices.Contains(auth.Roles, role) {
+			return nil, fmt.Errorf("unknown role %q, roles are %s", role, strings.Join(auth.Roles, ","))
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `otpctl is the administration tool of OTP App.
+
+Usage:
+  otpctl [-o json|table] [-config <path>] <command> [arguments]
+
+Commands:
+  user create -mobile <mobile> [-roles <role,...>]  create a user account
+  user find (-id <id> | -mobile <mobile>)           find a user account
+  user list [-page <page>] [-limit <limit>]         list user accounts
+                                                    (create, find and list accept -tenant <id>)
+  user roles -id <id> -set <role,...>               assign roles to a user account
+  user suspend -id <id> [-undo]                     suspend (or unsuspend) a user account and revoke its tokens
+  token issue -id <id> [-ttl <duration>]            issue a short-lived access token for debugging
+  token revoke -id <id>                             revoke all issued tokens of a user account
+  risk decisions [-id <id>] [-page <page>]          list risk decisions of sign in attempts, newest first
+                 [-limit <limit>] [-tenant <id>]
+  migrate up [-dry-run]                             apply pending database migrations
+  migrate down [-steps <n>] [-dry-run]              revert applied database migrations
+  migrate status                                    print database migrations state
+  config                                            print effective configuration with masked secrets
+`
+
+func main() {
+	fs := flag.NewFlagSet("otpctl", flag.ExitOnError)
+	format := fs.String("o", "table", "output format: json or table")
+	configPath := fs.String("config", "", "path of json, yaml or toml configuration file (env OTPAPP_CONFIG)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+	}
+
+	_ = fs.Parse(os.Args[1:])
+
+	out, err := newPrinter(os.Stdout, *format)
+	if err != nil {
+		fatal(err)
+	}
+
+	args := fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	app, err := newApp(out, *configPath)
+	if err != nil {
+		fatal(err)
+	}
+
+	switch args[0] {
+	case "user":
+		err = app.user(args[1:])
+	case "token":
+		err = app.token(args[1:])
+	case "risk":
+		err = app.risk(args[1:])
+	case "migrate":
+		err = app.migrate(args[1:])
+	case "config":
+		err = app.config(args[1:])
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "otpctl:", err)
+	os.Exit(1)
+}
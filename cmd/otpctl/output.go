@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ppeymann/top-app.git/models"
+)
+
+// printer writes command results as json or aligned table.
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "json":
+		return &printer{w: w, json: true}, nil
+	case "table":
+		return &printer{w: w}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported output format: %s", format)
+}
+
+func (p *printer) users(users ...models.UserEntity) error {
+	if p.json {
+		return p.encode(users)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTENANT\tMOBILE\tROLES\tSUSPENDED\tCREATED")
+	for _, u := range users {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%t\t%s\n", u.ID, u.TenantID, u.Mobile, strings.Join(u.Roles, ","), u.Suspended,
+			u.CreatedAt.UTC().Format(time.RFC3339))
+	}
+
+	return tw.Flush()
+}
+
+func (p *printer) decisions(decisions ...models.RiskDecisionEntity) error {
+	if p.json {
+		return p.encode(decisions)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUSER\tEVENT\tACTION\tSCORE\tREASONS\tDEVICE\tIP\tASN\tCREATED")
+	for _, d := range decisions {
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%d\t%s\t%s\t%s\t%d\t%s\n", d.ID, d.UserID, d.Event, d.Action, d.Score,
+			strings.Join(d.Reasons, ","), d.Device, d.IP, d.ASN, d.CreatedAt.UTC().Format(time.RFC3339))
+	}
+
+	return tw.Flush()
+}
+
+func (p *printer) token(out *models.TokenBundlerOutput) error {
+	if p.json {
+		return p.encode(out)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "TOKEN\t%s\n", out.Token)
+	fmt.Fprintf(tw, "EXPIRE\t%s\n", out.Expire.UTC().Format(time.RFC3339))
+
+	return tw.Flush()
+}
+
+func (p *printer) message(msg string) error {
+	if p.json {
+		return p.encode(map[string]string{"message": msg})
+	}
+
+	_, err := fmt.Fprintln(p.w, msg)
+	return err
+}
+
+// raw writes already encoded json document.
+func (p *printer) raw(b []byte) error {
+	_, err := fmt.Fprintln(p.w, string(b))
+	return err
+}
+
+func (p *printer) encode(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
@@ -0,0 +1,657 @@
+// schema generates draft-07 json schemas of service inputs from their Go types, inputs are pointer parameters
+// of methods of a service interface. properties are named by json tags and constrained by schema tags:
+//
+//	Mobile string `json:"mobile" schema:"required,ref=definitions#/definitions/mobile"`
+//	Limit  int32  `json:"limit" schema:"min=0,max=100"`
+//	Kind   string `json:"kind" schema:"enum=sms call"`
+//	Code   string `json:"code" schema:"required,pattern=^[0-9]{6}$"`
+//
+// min and max limit length of strings and arrays and value of numbers, pattern must be the last option.
+// generated schemas are checked instead of written with -check or SCHEMA_CHECK environment variable,
+// it fails if committed schemas are stale.
+//
+// Usage:
+//
+//	//go:generate go run ../cmd/schema -source user.go -interface UserService -output ../schemas/user
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	draft   string = "http://json-schema.org/draft-07/schema"
+	comment string = "Code generated by cmd/schema; DO NOT EDIT."
+)
+
+type (
+	// object is json object that keeps order of its members.
+	object []member
+
+	member struct {
+		key   string
+		value interface{}
+	}
+
+	// generator derives schemas of types of a package.
+	generator struct {
+		types map[string]*ast.TypeSpec
+	}
+)
+
+// set sets value of key, key is appended if it is not set.
+func (o *object) set(key string, value interface{}) {
+	for i, m := range *o {
+		if m.key == key {
+			(*o)[i].value = value
+			return
+		}
+	}
+
+	*o = append(*o, member{key: key, value: value})
+}
+
+func (o object) get(key string) (interface{}, bool) {
+	for _, m := range o {
+		if m.key == key {
+			return m.value, true
+		}
+	}
+
+	return nil, false
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o object) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := encode(m.key, "")
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := encode(m.value, "")
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func main() {
+	source := flag.String("source", "", "go file that declares the service interface, types are looked up in its package")
+	iface := flag.String("interface", "", "name of the service interface")
+	output := flag.String("output", "", "directory of generated schemas")
+	openapi := flag.String("openapi", "", "file of generated OpenAPI components, optional")
+	definitions := flag.String("definitions", "", "comma separated shared definition files that are inlined in OpenAPI components")
+	check := flag.Bool("check", os.Getenv("SCHEMA_CHECK") != "", "fail if generated files differ from existing files instead of writing them")
+	flag.Parse()
+
+	if *source == "" || *iface == "" || *output == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	files, err := generate(*source, *iface, *output, *openapi, *definitions)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var stale []string
+	for _, name := range names {
+		if *check {
+			current, err := os.ReadFile(name)
+			if err != nil || !bytes.Equal(current, files[name]) {
+				stale = append(stale, name)
+			}
+
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := os.WriteFile(name, files[name], 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if len(stale) > 0 {
+		log.Fatalf("schemas of %s are stale, run make generate: %s", *iface, strings.Join(stale, ", "))
+	}
+}
+
+// generate returns content of schema files of inputs of service interface by file name.
+func generate(source, iface, output, openapi, definitions string) (map[string][]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, filepath.Dir(source), func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{types: map[string]*ast.TypeSpec{}}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					if ts.Doc == nil && len(gd.Specs) == 1 {
+						ts.Doc = gd.Doc
+					}
+
+					g.types[ts.Name.Name] = ts
+				}
+			}
+		}
+	}
+
+	spec, ok := g.types[iface]
+	if !ok {
+		return nil, fmt.Errorf("interface %s is not declared in package of %s", iface, source)
+	}
+
+	it, ok := spec.Type.(*ast.InterfaceType)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an interface", iface)
+	}
+
+	inputs := g.inputs(it)
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%s has no input of a struct type", iface)
+	}
+
+	files := map[string][]byte{}
+	components := object{}
+
+	for _, name := range inputs {
+		schema, err := g.object(g.types[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		doc := object{
+			{"$schema", draft},
+			{"$id", name},
+			{"$comment", comment},
+			{"$protected", false},
+		}
+
+		b, err := marshal(append(doc, schema...))
+		if err != nil {
+			return nil, err
+		}
+
+		files[filepath.Join(output, name+".json")] = b
+		components.set(name, schema)
+	}
+
+	if openapi == "" {
+		return files, nil
+	}
+
+	defs := map[string]interface{}{}
+	for _, file := range strings.Split(definitions, ",") {
+		if file == "" {
+			continue
+		}
+
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc map[string]interface{}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		id, _ := doc["$id"].(string)
+		defs[id] = doc
+	}
+
+	inlined, err := inline(components, defs)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := marshal(object{{"components", object{{"schemas", inlined}}}})
+	if err != nil {
+		return nil, err
+	}
+
+	files[openapi] = b
+
+	return files, nil
+}
+
+// inputs returns names of struct types of package that methods of interface accept by pointer.
+func (g *generator) inputs(it *ast.InterfaceType) []string {
+	seen := map[string]bool{}
+	var names []string
+
+	for _, field := range it.Methods.List {
+		ft, ok := field.Type.(*ast.FuncType)
+		if !ok {
+			continue
+		}
+
+		for _, p := range ft.Params.List {
+			star, ok := p.Type.(*ast.StarExpr)
+			if !ok {
+				continue
+			}
+
+			ident, ok := star.X.(*ast.Ident)
+			if !ok || seen[ident.Name] {
+				continue
+			}
+
+			if ts, ok := g.types[ident.Name]; ok {
+				if _, ok := ts.Type.(*ast.StructType); ok {
+					seen[ident.Name] = true
+					names = append(names, ident.Name)
+				}
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// object returns schema of struct type.
+func (g *generator) object(ts *ast.TypeSpec) (object, error) {
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct", ts.Name.Name)
+	}
+
+	schema := object{{"type", "object"}}
+	if doc := text(ts.Doc); doc != "" {
+		schema.set("description", doc)
+	}
+
+	properties := object{}
+	var required []string
+
+	if err := g.fields(st, &properties, &required); err != nil {
+		return nil, err
+	}
+
+	schema.set("properties", properties)
+	if len(required) > 0 {
+		schema.set("required", required)
+	}
+
+	return schema, nil
+}
+
+// fields adds properties of fields of struct, fields of embedded structs are promoted.
+func (g *generator) fields(st *ast.StructType, properties *object, required *[]string) error {
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			v, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(v)
+		}
+
+		name, _, _ := strings.Cut(tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if len(field.Names) == 0 {
+			// embedded struct without json name is promoted
+			if ident, ok := field.Type.(*ast.Ident); ok && name == "" {
+				if ts, ok := g.types[ident.Name]; ok {
+					if embedded, ok := ts.Type.(*ast.StructType); ok {
+						if err := g.fields(embedded, properties, required); err != nil {
+							return err
+						}
+
+						continue
+					}
+				}
+			}
+
+			if name == "" {
+				continue
+			}
+		}
+
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+
+			key := name
+			if key == "" {
+				key = ident.Name
+			}
+
+			property, req, err := g.property(field, tag.Get("schema"))
+			if err != nil {
+				return fmt.Errorf("%s: %w", ident.Name, err)
+			}
+
+			properties.set(key, property)
+			if req {
+				*required = append(*required, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// property returns schema of struct field that is constrained by options of its schema tag.
+func (g *generator) property(field *ast.Field, options string) (object, bool, error) {
+	property, err := g.schema(field.Type)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if doc := text(field.Doc); doc != "" {
+		property.set("description", doc)
+	}
+
+	typ, _ := property.get("type")
+	required := false
+
+	for options != "" {
+		var option string
+
+		// pattern is the last option, it may contain commas
+		if strings.HasPrefix(options, "pattern=") {
+			option, options = options, ""
+		} else {
+			option, options, _ = strings.Cut(options, ",")
+		}
+
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "required":
+			required = true
+
+		case "pattern":
+			property.set("pattern", value)
+
+		case "format":
+			property.set("format", value)
+
+		case "ref":
+			// keywords next to $ref are ignored by draft-07
+			property = object{{"$ref", value}}
+
+		case "min", "max":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, false, fmt.Errorf("%s is not a number: %w", option, err)
+			}
+
+			property.set(limit(key, typ), number(n))
+
+		case "enum":
+			var values []interface{}
+			for _, v := range strings.Fields(value) {
+				if typ == "integer" || typ == "number" {
+					n, err := strconv.ParseFloat(v, 64)
+					if err != nil {
+						return nil, false, fmt.Errorf("enum value %s is not a number: %w", v, err)
+					}
+
+					values = append(values, number(n))
+					continue
+				}
+
+				values = append(values, v)
+			}
+
+			property.set("enum", values)
+
+		case "":
+
+		default:
+			return nil, false, fmt.Errorf("unknown schema option %q", key)
+		}
+	}
+
+	return property, required, nil
+}
+
+// schema returns schema of type expression.
+func (g *generator) schema(expr ast.Expr) (object, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		switch e.Name {
+		case "string":
+			return object{{"type", "string"}}, nil
+		case "bool":
+			return object{{"type", "boolean"}}, nil
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+			return object{{"type", "integer"}}, nil
+		case "float32", "float64":
+			return object{{"type", "number"}}, nil
+		}
+
+		ts, ok := g.types[e.Name]
+		if !ok {
+			return nil, fmt.Errorf("type %s is not supported", e.Name)
+		}
+
+		if _, ok := ts.Type.(*ast.StructType); ok {
+			return g.object(ts)
+		}
+
+		return g.schema(ts.Type)
+
+	case *ast.StarExpr:
+		return g.schema(e.X)
+
+	case *ast.ArrayType:
+		if ident, ok := e.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return object{{"type", "string"}, {"contentEncoding", "base64"}}, nil
+		}
+
+		items, err := g.schema(e.Elt)
+		if err != nil {
+			return nil, err
+		}
+
+		return object{{"type", "array"}, {"items", items}}, nil
+
+	case *ast.MapType:
+		values, err := g.schema(e.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return object{{"type", "object"}, {"additionalProperties", values}}, nil
+
+	case *ast.SelectorExpr:
+		if x, ok := e.X.(*ast.Ident); ok && x.Name == "time" && e.Sel.Name == "Time" {
+			return object{{"type", "string"}, {"format", "date-time"}}, nil
+		}
+
+		// types of other packages are not constrained
+		return object{}, nil
+
+	case *ast.InterfaceType:
+		return object{}, nil
+	}
+
+	return nil, fmt.Errorf("type %T is not supported", expr)
+}
+
+// inline returns copy of OpenAPI schemas that references of shared definitions are replaced by
+// definitions, OpenAPI components can not refer to json schema files.
+func inline(v interface{}, defs map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case object:
+		if ref, ok := v.get("$ref"); ok && len(v) == 1 {
+			def, err := resolve(ref.(string), defs)
+			if err != nil {
+				return nil, err
+			}
+
+			return inline(def, defs)
+		}
+
+		out := make(object, 0, len(v))
+		for _, m := range v {
+			value, err := inline(m.value, defs)
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, member{key: m.key, value: value})
+		}
+
+		return out, nil
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		out := make(object, 0, len(v))
+		for _, key := range keys {
+			value, err := inline(v[key], defs)
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, member{key: key, value: value})
+		}
+
+		return inline(out, defs)
+	}
+
+	return v, nil
+}
+
+// resolve returns definition that ref points to, e.g. definitions#/definitions/mobile.
+func resolve(ref string, defs map[string]interface{}) (interface{}, error) {
+	id, pointer, _ := strings.Cut(ref, "#")
+
+	doc, ok := defs[id]
+	if !ok {
+		return nil, fmt.Errorf("definitions of %s are not provided by -definitions", ref)
+	}
+
+	for _, token := range strings.Split(strings.Trim(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+
+		m, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s is not found", ref)
+		}
+
+		if doc, ok = m[token]; !ok {
+			return nil, fmt.Errorf("%s is not found", ref)
+		}
+	}
+
+	return doc, nil
+}
+
+// limit returns keyword of min or max option for schema type.
+func limit(key string, typ interface{}) string {
+	switch typ {
+	case "string":
+		return key + "Length"
+	case "array":
+		return key + "Items"
+	}
+
+	if key == "min" {
+		return "minimum"
+	}
+
+	return "maximum"
+}
+
+// number returns n as integer if it has no fraction so it is encoded without exponent.
+func number(n float64) interface{} {
+	if n == float64(int64(n)) {
+		return int64(n)
+	}
+
+	return n
+}
+
+// text returns doc comment as a single line.
+func text(doc *ast.CommentGroup) string {
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
+
+// marshal returns content of generated json file.
+func marshal(v interface{}) ([]byte, error) {
+	return encode(v, "    ")
+}
+
+// encode returns json encoding of v that characters of patterns are not escaped, it ends with new line.
+func encode(v interface{}, indent string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	if indent == "" {
+		return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+	}
+
+	return buf.Bytes(), nil
+}
@@ -1,12 +1,10 @@
 package otpapp
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 
-	"github.com/gin-gonic/gin"
 	"github.com/ppeymann/top-app.git/auth"
-	"github.com/ppeymann/top-app.git/utils"
 	"gorm.io/gorm"
 )
 
@@ -15,7 +13,8 @@ type (
 
 	// BaseRepository is abstract interface that all repositories must implement its methods
 	BaseRepository interface {
-		// Migrate runs AutoMigrate for expected repository model
+		// Migrate runs AutoMigrate for expected repository model.
+		// it only should be used in tests, database schema is managed by versioned migrations package.
 		Migrate() error
 
 		// Name repository associated table name
@@ -35,6 +34,12 @@ type (
 		// Errors provides list off error that occurred in processing request
 		Errors []string `json:"errors" mapstructure:"errors"`
 
+		// ErrorDetails provides machine-readable code of every error of Errors in the same order.
+		ErrorDetails []Error `json:"error_details,omitempty" mapstructure:"error_details"`
+
+		// Causes are errors of Errors that are not in error catalog, they are logged but never returned.
+		Causes []error `json:"-" mapstructure:"-"`
+
 		// ResultCount specified number of records that returned in result_count field expected result been array.
 		ResultCount int64 `json:"result_count,omitempty" mapstructure:"result_count"`
 
@@ -42,11 +47,6 @@ type (
 		Result interface{} `json:"result" mapstructure:"result"`
 	}
 
-	Error struct {
-		Code        int    `json:"code" mapstructure:"code"`
-		Description string `json:"description" mapstructure:"description"`
-	}
-
 	SearchInputDTO struct {
 		Query string `json:"query"`
 	}
@@ -67,15 +67,6 @@ type (
 	}
 )
 
-var (
-	ErrUnimplementedRequest = errors.New("request is not implemented")
-	ErrUnhandled            = errors.New("an unhandled error occurred during processing the request")
-	ErrNotFound             = errors.New("not found")
-	ErrInternalServer       = errors.New("internal server error")
-	ErrEntityAlreadyExist   = errors.New("entity with specified properties already exist")
-	ErrUnAuthorization      = errors.New("UnAuthorization Error")
-)
-
 const (
 	ContextUserKey          string = "CONTEXT_USER"
 	UserSessionKey          string = "USER_SESSION"
@@ -117,11 +108,11 @@ func (p *ContextUser) FromJson(val string) error {
 	return json.Unmarshal([]byte(val), p)
 }
 
-func CheckAuth(ctx *gin.Context) (*auth.Claims, error) {
-	claims := &auth.Claims{}
-
-	err := utils.CatchClaims(ctx, claims)
-	if err != nil {
+// CheckAuth returns the authenticated principal claims carried by ctx.
+// It returns ErrUnAuthorization if the request has not been authenticated.
+func CheckAuth(ctx context.Context) (*auth.Claims, error) {
+	claims, ok := auth.FromContext(ctx)
+	if !ok {
 		return nil, ErrUnAuthorization
 	}
 
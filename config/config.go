@@ -1,13 +1,5 @@
 package config
 
-import (
-	"encoding/json"
-	"io"
-	"log"
-	"os"
-	"runtime"
-)
-
 type AuthMode string
 
 const (
@@ -20,11 +12,55 @@ type (
 		// Listener http listener binding options.
 		Listener Listener `json:"listener"`
 
+		// Http is http server options.
+		Http HttpConfig `json:"http"`
+
+		// Database is database connection options.
+		Database DatabaseConfig `json:"database"`
+
+		// Paseto is token signing options.
+		Paseto PasetoConfig `json:"paseto"`
+
 		Jwt Jwt `json:"jwt"`
 
 		Redis RedisConfig `json:"redis"`
 
 		RateLimit RateLimitConfig `json:"rate_limit"`
+
+		// UserCache is options of redis cache of user lookups.
+		UserCache CacheConfig `json:"user_cache"`
+
+		// Risk is options of risk evaluation of sign in attempts.
+		Risk RiskConfig `json:"risk"`
+
+		// TrustedDevices is options of devices that sign in without one time password.
+		TrustedDevices TrustedDeviceConfig `json:"trusted_devices"`
+
+		// ProofOfWork is options of proof-of-work challenge of routes that send one time password.
+		ProofOfWork ProofOfWorkConfig `json:"proof_of_work"`
+
+		// Idempotency is options of Idempotency-Key header of POST endpoints.
+		Idempotency IdempotencyConfig `json:"idempotency"`
+
+		// Otp is one time password delivery options.
+		Otp OtpConfig `json:"otp"`
+
+		// Tracing is distributed tracing options.
+		Tracing TracingConfig `json:"tracing"`
+
+		// I18n is language options of messages.
+		I18n I18nConfig `json:"i18n"`
+
+		// Tenants is the tenant registry, if it is empty all requests belong to "default" tenant
+		// that uses global Jwt, Otp and RateLimit options.
+		Tenants []TenantConfig `json:"tenants"`
+
+		// DefaultTenant is the tenant of requests that no tenant resolved for them by
+		// X-Tenant header or host, requests are rejected if it is not specified.
+		DefaultTenant string `json:"default_tenant"`
+
+		// Deprecated are warnings of deprecated options that are set in configuration file.
+		Deprecated []string `json:"-"`
 	}
 
 	// Listener contains Server https listener options.
@@ -52,6 +88,67 @@ type (
 		// SSLHost is ssl host for gin secure configuration.
 		// It applied in production mode
 		SSLHost string `json:"ssl_host" mapstructure:"ssl_host"`
+
+		// MinTLSVersion is minimum accepted TLS version: "1.2" (default) or "1.3".
+		MinTLSVersion string `json:"min_tls_version" mapstructure:"min_tls_version"`
+
+		// CipherSuites are names of accepted TLS 1.2 cipher suites, e.g. "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256".
+		// if it is empty, secure defaults of Go are used. TLS 1.3 suites are not configurable.
+		CipherSuites []string `json:"cipher_suites" mapstructure:"cipher_suites"`
+
+		// RedirectPort is network port of http listener that redirects requests to https listener.
+		// if RedirectPort is 0, redirect listener is not started. it ignored if Cert is not specified.
+		RedirectPort int `json:"redirect_port" mapstructure:"redirect_port"`
+
+		// ClientCA is path to PEM bundle of certificate authorities that client certificates are verified with.
+		ClientCA string `json:"client_ca" mapstructure:"client_ca"`
+
+		// ClientAuth is client certificate policy: "none" (default), "request" verifies certificate
+		// if client sends it and internal endpoints require it, "require" requires it for every connection.
+		ClientAuth string `json:"client_auth" mapstructure:"client_auth"`
+	}
+
+	// HttpConfig contains http server options.
+	HttpConfig struct {
+		// Mode is gin mode: "debug" (default), "release" or "test".
+		Mode string `json:"mode"`
+
+		// SwaggerEnabled serves swagger documentation on /swagger.
+		SwaggerEnabled bool `json:"swagger_enabled"`
+
+		// HostURL is host of api server in swagger documentation.
+		HostURL string `json:"host_url"`
+
+		// CorsEnabled enables CORS middleware.
+		CorsEnabled bool `json:"cors_enabled"`
+
+		// LegacyErrors writes failed service and middleware results with status 200 as before error catalog,
+		// errors are still reported in body. it is for clients that are not migrated to http status codes.
+		LegacyErrors bool `json:"legacy_errors"`
+
+		// ShutdownDelaySeconds is delay between failing readiness probe and closing listeners on shutdown.
+		ShutdownDelaySeconds int `json:"shutdown_delay_seconds"`
+	}
+
+	// DatabaseConfig contains database connection options.
+	DatabaseConfig struct {
+		// Driver is database driver: "postgres" (default) or "sqlite".
+		Driver string `json:"driver"`
+
+		// DSN is data source name of database, it is file path for sqlite.
+		DSN string `json:"dsn"`
+
+		// Name is name of database.
+		Name string `json:"name"`
+
+		// AutoMigrate applies pending migrations on startup.
+		AutoMigrate bool `json:"auto_migrate"`
+	}
+
+	// PasetoConfig contains token signing options.
+	PasetoConfig struct {
+		// SymmetricKey is the 32 characters key that tokens are encrypted with.
+		SymmetricKey string `json:"symmetric_key"`
 	}
 
 	// Jwt contains JWT configuration options.
@@ -64,64 +161,258 @@ type (
 		IdentityKey   string `json:"identity_key"`
 	}
 
+	// RateLimitConfig contains rate limit options, global limit is applied to every request by client ip.
 	RateLimitConfig struct {
 		RateLimitExcludePaths       []string `json:"rate_limit_exclude_paths"`
 		RateLimitRequestPerDuration int64    `json:"rate_limit_request_per_duration"`
 		RateLimitDurationSeconds    int64    `json:"rate_limit_duration_seconds"`
 		Enabled                     bool     `json:"enabled"`
+
+		// Algorithm is default algorithm of limits: "sliding_window" (default) or "token_bucket".
+		Algorithm string `json:"algorithm"`
+
+		// Policies are named limits that route groups apply in addition to global limit.
+		Policies map[string]RateLimitPolicy `json:"policies"`
+
+		// Resilience is behaviour of rate limit when redis is unavailable.
+		// it is only read from global configuration and ignored in tenant configuration.
+		Resilience RateLimitResilience `json:"resilience"`
 	}
 
-	RedisConfig struct {
-		Addr     string `json:"addr"`
-		DB       int    `json:"db"`
-		Password string `json:"password"`
+	// RateLimitResilience contains options of rate limit when redis is unavailable.
+	RateLimitResilience struct {
+		// FailureMode is "local" (default) to count requests in process, "open" to allow
+		// or "closed" to reject requests while redis is unavailable.
+		FailureMode string `json:"failure_mode"`
+
+		// TimeoutMillis is timeout of redis calls, default is 100.
+		TimeoutMillis int64 `json:"timeout_ms"`
+
+		// BreakerThreshold is number of consecutive redis failures that opens circuit breaker, default is 5.
+		BreakerThreshold int `json:"breaker_threshold"`
+
+		// BreakerCooldownSeconds is time that circuit breaker stays open before redis is called again, default is 30.
+		BreakerCooldownSeconds int64 `json:"breaker_cooldown_seconds"`
 	}
-)
 
-// NewConfiguration returns new Configuration with default options.
-func NewConfiguration() (*Configuration, error) {
-	config := &Configuration{}
+	// RateLimitPolicy is a named limit that applied to routes.
+	RateLimitPolicy struct {
+		Requests        int64 `json:"requests"`
+		DurationSeconds int64 `json:"duration_seconds"`
+
+		// Key is identity that requests are counted for: "ip" (default), "mobile" of request body
+		// or "subject" of authorization token.
+		Key string `json:"key"`
 
-	err := config.loadConfig()
-	if err != nil {
-		return nil, err
+		// Algorithm overrides default algorithm of rate limit configuration.
+		Algorithm string `json:"algorithm"`
 	}
 
-	return config, nil
-}
+	// CacheConfig contains options of redis read-through cache of a repository.
+	CacheConfig struct {
+		Enabled bool `json:"enabled"`
+
+		// TTLSeconds is lifetime of cached entities, default is 300.
+		TTLSeconds int64 `json:"ttl_seconds"`
+	}
+
+	// RiskConfig contains options of risk engine that evaluates login and otp verification attempts.
+	RiskConfig struct {
+		Enabled bool `json:"enabled"`
+
+		// ASNDatabase is path of ip2asn tab separated file that resolves autonomous system of client ips,
+		// if it is empty "asn_changed" signal never matches.
+		ASNDatabase string `json:"asn_database"`
+
+		// VelocityWindowSeconds is window of velocity signals, default is 3600.
+		VelocityWindowSeconds int64 `json:"velocity_window_seconds"`
 
-func (c *Configuration) loadConfig() error {
-	path := ""
+		// ChallengeScore and BlockScore are scores that attempts are challenged or blocked from, zero disables them.
+		ChallengeScore int `json:"challenge_score"`
+		BlockScore     int `json:"block_score"`
 
-	if runtime.GOOS == "windows" {
-		path = ".\\config\\config.json"
-	} else {
-		path = "./config/config.json"
+		Rules []RiskRule `json:"rules"`
 	}
 
-	file, err := os.Open(path)
-	if err != nil {
-		return err
+	// RiskRule matches a signal of attempts: "new_device", "ip_changed", "asn_changed", "user_velocity",
+	// "ip_velocity" or "device_velocity".
+	RiskRule struct {
+		Name   string `json:"name"`
+		Signal string `json:"signal"`
+
+		// Threshold is the minimum value of velocity signals that matches rule, default is 1.
+		Threshold int64 `json:"threshold"`
+
+		// Score is added to score of matched attempts.
+		Score int `json:"score"`
+
+		// Action is "challenge" or "block" to decide matched attempts regardless of their score.
+		Action string `json:"action"`
 	}
 
-	defer closeFile(file)
+	// TrustedDeviceConfig contains options of trusted devices, after otp verification clients can request a
+	// device token that signs in same device without one time password.
+	TrustedDeviceConfig struct {
+		Enabled bool `json:"enabled"`
 
-	bytes, err := io.ReadAll(file)
-	if err != nil {
-		return err
+		// TTLHours is time that devices stay trusted after otp verification, default is 720.
+		TTLHours int64 `json:"ttl_hours"`
 	}
 
-	err = json.Unmarshal(bytes, c)
-	if err != nil {
-		return err
+	// ProofOfWorkConfig contains options of proof-of-work challenges, routes that send one time password require
+	// a solved challenge and difficulty of challenges grows with number of challenges that caller requests.
+	ProofOfWorkConfig struct {
+		Enabled bool `json:"enabled"`
+
+		// Secret is the key that challenges are signed with, it must be at least 32 characters.
+		Secret string `json:"secret"`
+
+		// TTLSeconds is time that challenges can be solved in, default is 120.
+		TTLSeconds int64 `json:"ttl_seconds"`
+
+		// BaseDifficulty is number of leading zero bits of solutions of first challenge of callers, default is 16.
+		BaseDifficulty int `json:"base_difficulty"`
+
+		// MaxDifficulty caps difficulty of challenges, default is 24 and it can not be more than 32.
+		MaxDifficulty int `json:"max_difficulty"`
+
+		// FactorDifficulty is the minimum difficulty of solved challenges that pass as extra factor of sign in
+		// attempts that risk engine challenges, default is 20. factor challenges are not capped by MaxDifficulty.
+		FactorDifficulty int `json:"factor_difficulty"`
+
+		// StepRequests is number of challenges of a caller in window that add a bit to difficulty, default is 5.
+		StepRequests int64 `json:"step_requests"`
+
+		// WindowSeconds is window of counting challenges of callers, default is 600.
+		WindowSeconds int64 `json:"window_seconds"`
 	}
 
-	return nil
-}
+	// IdempotencyConfig contains options of idempotent requests, responses of requests that sent with
+	// Idempotency-Key header are stored in redis and replayed for retries of request.
+	IdempotencyConfig struct {
+		Enabled bool `json:"enabled"`
 
-func closeFile(file *os.File) {
-	err := file.Close()
-	if err != nil {
-		log.Panicln(err.Error())
+		// TTLSeconds is time that responses are replayed for, default is 86400.
+		TTLSeconds int64 `json:"ttl_seconds"`
+
+		// LockSeconds is time that a key stays locked by a request in flight, default is 30.
+		LockSeconds int64 `json:"lock_seconds"`
 	}
+
+	// OtpConfig contains one time password delivery options.
+	OtpConfig struct {
+		// Driver is the sender that delivers one time passwords: "log" (default) or "http".
+		Driver string `json:"driver"`
+
+		// Endpoint is url of sms gateway that "http" driver posts messages to.
+		Endpoint string `json:"endpoint"`
+
+		// ApiKey is sent to sms gateway as Authorization bearer token.
+		ApiKey string `json:"api_key"`
+
+		// Sender is the line number that messages are sent from.
+		Sender string `json:"sender"`
+
+		// Template is the message text, {code} is replaced with one time password.
+		Template string `json:"template"`
+
+		// Templates are message texts of languages, e.g. "fa", Template is used for other languages.
+		Templates map[string]string `json:"templates"`
+
+		// ExposeCode returns one time password in api response, it must only be enabled for development.
+		ExposeCode bool `json:"expose_code"`
+	}
+
+	// I18nConfig contains language options of messages.
+	I18nConfig struct {
+		// FallbackLanguage is language of requests that Accept-Language header and saved locale of
+		// account do not specify a supported language: "en" (default) or "fa".
+		FallbackLanguage string `json:"fallback_language"`
+	}
+
+	// TracingConfig contains OpenTelemetry tracing options.
+	TracingConfig struct {
+		// Enabled records and exports spans of requests.
+		Enabled bool `json:"enabled"`
+
+		// ServiceName is service.name resource attribute of spans, default: "otpapp".
+		ServiceName string `json:"service_name"`
+
+		// Exporter is destination of spans: "otlp" (default) exports to an OpenTelemetry collector over http,
+		// "stdout" writes them to standard output and "file" appends them to File, one json document per span.
+		Exporter string `json:"exporter"`
+
+		// Endpoint is host:port of OTLP http receiver, if it is empty OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318 is used.
+		Endpoint string `json:"endpoint"`
+
+		// Insecure exports spans to Endpoint without TLS.
+		Insecure bool `json:"insecure"`
+
+		// Headers are sent with every OTLP export request, e.g. authorization of collector.
+		Headers map[string]string `json:"headers"`
+
+		// File is path of spans file of "file" exporter.
+		File string `json:"file"`
+
+		// SampleRatio is ratio of traces that are recorded when caller did not sample them, between 0 and 1, default: 1.
+		SampleRatio *float64 `json:"sample_ratio"`
+	}
+
+	// TenantConfig contains options of a tenant, every specified section replaces
+	// the global section of configuration for the tenant.
+	TenantConfig struct {
+		// ID is the unique identifier of tenant that clients send in X-Tenant header.
+		ID string `json:"id"`
+
+		// Hosts are request hosts that resolve to the tenant.
+		Hosts []string `json:"hosts"`
+
+		Jwt *Jwt `json:"jwt"`
+
+		Otp *OtpConfig `json:"otp"`
+
+		RateLimit *RateLimitConfig `json:"rate_limit"`
+	}
+
+	RedisConfig struct {
+		Addr     string `json:"addr"`
+		DB       int    `json:"db"`
+		Password string `json:"password"`
+	}
+)
+
+// Redacted returns copy of configuration that its secrets are masked.
+func (c Configuration) Redacted() Configuration {
+	mask := func(s *string) {
+		if *s != "" {
+			*s = "******"
+		}
+	}
+
+	mask(&c.Database.DSN)
+	mask(&c.Paseto.SymmetricKey)
+	mask(&c.Redis.Password)
+	mask(&c.Otp.ApiKey)
+	mask(&c.ProofOfWork.Secret)
+
+	if len(c.Tracing.Headers) > 0 {
+		headers := make(map[string]string, len(c.Tracing.Headers))
+		for k, v := range c.Tracing.Headers {
+			mask(&v)
+			headers[k] = v
+		}
+
+		c.Tracing.Headers = headers
+	}
+
+	c.Tenants = append([]TenantConfig(nil), c.Tenants...)
+	for i, t := range c.Tenants {
+		if t.Otp != nil {
+			otp := *t.Otp
+			mask(&otp.ApiKey)
+			c.Tenants[i].Otp = &otp
+		}
+	}
+
+	return c
 }
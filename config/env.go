@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is prefix of environment variables that override configuration options.
+//
+// name of variable is the prefix followed by json path of option in upper case joined by
+// underscore, e.g. OTPAPP_DATABASE_DSN overrides "dsn" of "database" and
+// OTPAPP_RATE_LIMIT_RESILIENCE_FAILURE_MODE overrides "failure_mode" of "resilience" of "rate_limit".
+// lists of strings are comma separated, other lists and maps are json documents.
+const EnvPrefix string = "OTPAPP"
+
+// legacyEnv maps environment variables that used before EnvPrefix naming to their options,
+// they are applied when the prefixed variable is not set.
+var legacyEnv = map[string]string{
+	"OTPAPP_HTTP_MODE":             "GIN_MODE",
+	"OTPAPP_HTTP_SWAGGER_ENABLED":  "SWAGGER_ENABLED",
+	"OTPAPP_HTTP_HOST_URL":         "HOST_URL",
+	"OTPAPP_HTTP_CORS_ENABLED":     "CORS_ENABLE",
+	"OTPAPP_DATABASE_DRIVER":       "DB_DRIVER",
+	"OTPAPP_DATABASE_DSN":          "DSN",
+	"OTPAPP_DATABASE_NAME":         "POSTGRES_DB",
+	"OTPAPP_DATABASE_AUTO_MIGRATE": "AUTO_MIGRATE",
+	"OTPAPP_PASETO_SYMMETRIC_KEY":  "JWT",
+}
+
+// overlayEnv sets options of conf that environment variables are set for.
+func overlayEnv(conf *Configuration, lookup func(string) (string, bool)) error {
+	return overlayStruct(reflect.ValueOf(conf).Elem(), EnvPrefix, func(name string) (string, bool) {
+		if val, ok := lookup(name); ok {
+			return val, true
+		}
+
+		if legacy, ok := legacyEnv[name]; ok {
+			return lookup(legacy)
+		}
+
+		return "", false
+	})
+}
+
+func overlayStruct(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || tag == "-" || tag == "" {
+			continue
+		}
+
+		name := prefix + "_" + strings.ToUpper(tag)
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			if err := overlayStruct(fv, name, lookup); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		val, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		if err := setValue(fv, val); err != nil {
+			return fmt.Errorf("environment variable %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func setValue(v reflect.Value, val string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(n)
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			items := []string{}
+			for _, item := range strings.Split(val, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+
+			v.Set(reflect.ValueOf(items))
+			return nil
+		}
+
+		return setJSON(v, val)
+
+	default:
+		return setJSON(v, val)
+	}
+
+	return nil
+}
+
+// setJSON replaces v with json document val.
+func setJSON(v reflect.Value, val string) error {
+	nv := reflect.New(v.Type())
+	if err := json.Unmarshal([]byte(val), nv.Interface()); err != nil {
+		return err
+	}
+
+	v.Set(nv.Elem())
+
+	return nil
+}
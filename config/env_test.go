@@ -0,0 +1,97 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOverlayEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		check func(conf *Configuration) bool
+	}{
+		{
+			name:  "nested option",
+			env:   map[string]string{"OTPAPP_RATE_LIMIT_RESILIENCE_FAILURE_MODE": "closed"},
+			check: func(c *Configuration) bool { return c.RateLimit.Resilience.FailureMode == "closed" },
+		},
+		{
+			name:  "bool and int",
+			env:   map[string]string{"OTPAPP_HTTP_CORS_ENABLED": "true", "OTPAPP_LISTENER_PORT": "9090"},
+			check: func(c *Configuration) bool { return c.Http.CorsEnabled && c.Listener.Port == 9090 },
+		},
+		{
+			name: "list of strings",
+			env:  map[string]string{"OTPAPP_LISTENER_ALLOWED_HOSTS": "a.com, b.com,"},
+			check: func(c *Configuration) bool {
+				return reflect.DeepEqual(c.Listener.AllowedHosts, []string{"a.com", "b.com"})
+			},
+		},
+		{
+			name: "json document",
+			env:  map[string]string{"OTPAPP_RATE_LIMIT_POLICIES": `{"signup": {"requests": 3, "duration_seconds": 60}}`},
+			check: func(c *Configuration) bool {
+				return c.RateLimit.Policies["signup"] == RateLimitPolicy{Requests: 3, DurationSeconds: 60}
+			},
+		},
+		{
+			name: "legacy variable",
+			env:  map[string]string{"DSN": "legacy", "JWT": "0123456789abcdef0123456789abcdef"},
+			check: func(c *Configuration) bool {
+				return c.Database.DSN == "legacy" && c.Paseto.SymmetricKey == "0123456789abcdef0123456789abcdef"
+			},
+		},
+		{
+			name:  "prefixed variable wins over legacy variable",
+			env:   map[string]string{"DSN": "legacy", "OTPAPP_DATABASE_DSN": "prefixed"},
+			check: func(c *Configuration) bool { return c.Database.DSN == "prefixed" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := Default()
+
+			err := overlayEnv(conf, func(name string) (string, bool) {
+				val, ok := tt.env[name]
+				return val, ok
+			})
+			if err != nil {
+				t.Fatalf("overlayEnv() error = %v", err)
+			}
+
+			if !tt.check(conf) {
+				t.Errorf("overlayEnv() with %v did not set option", tt.env)
+			}
+		})
+	}
+}
+
+func TestOverlayEnvInvalidValue(t *testing.T) {
+	err := overlayEnv(Default(), func(name string) (string, bool) {
+		return "many", name == "OTPAPP_LISTENER_PORT"
+	})
+	if err == nil {
+		t.Fatal("overlayEnv() error = nil, want error of OTPAPP_LISTENER_PORT")
+	}
+}
+
+func TestLegacyEnvNames(t *testing.T) {
+	// every legacy variable must override an existing option
+	for name := range legacyEnv {
+		found := false
+
+		_ = overlayEnv(Default(), func(n string) (string, bool) {
+			if n == name {
+				found = true
+			}
+
+			return "", false
+		})
+
+		if !found {
+			t.Errorf("legacy variable of %s does not match any option", name)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPath is the environment variable that specifies path of configuration file.
+const EnvPath string = "OTPAPP_CONFIG"
+
+// DefaultPath is path of configuration file when no path is specified by flag or EnvPath.
+var DefaultPath = filepath.Join("config", "config.json")
+
+// Default returns Configuration with default options, configuration file and
+// environment variables are applied on top of it.
+func Default() *Configuration {
+	return &Configuration{
+		Listener: Listener{
+			Host: "0.0.0.0",
+			Port: 8080,
+		},
+		Http: HttpConfig{
+			HostURL: "localhost:8080",
+		},
+		Database: DatabaseConfig{
+			Driver:      "postgres",
+			AutoMigrate: true,
+		},
+		Redis: RedisConfig{
+			Addr: "localhost:6379",
+		},
+	}
+}
+
+// Load returns configuration of file at path, environment variables are applied on top of file
+// and result is validated. if path is empty, it is read from OTPAPP_CONFIG environment variable
+// and defaults to DefaultPath. file format is specified by extension: .json, .yaml, .yml or .toml.
+//
+// variables of .env file in working directory are loaded to environment if the file exists.
+func Load(path string) (*Configuration, error) {
+	// .env is optional, variables that already set in environment are not overridden
+	_ = godotenv.Load()
+
+	path = resolvePath(path)
+	conf := Default()
+
+	if err := decodeFile(path, conf); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	if err := overlayEnv(conf, os.LookupEnv); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+// resolvePath returns path of configuration file, empty path is read from OTPAPP_CONFIG
+// environment variable and defaults to DefaultPath.
+func resolvePath(path string) string {
+	if path == "" {
+		path = os.Getenv(EnvPath)
+	}
+
+	if path == "" {
+		path = DefaultPath
+	}
+
+	return path
+}
+
+// deprecatedKeys are options that are no longer used, they are accepted in configuration files so
+// files of previous releases keep working and are reported in Configuration.Deprecated.
+var deprecatedKeys = map[string]string{
+	"listener.sessions_secret": "sessions are not supported, tokens are signed by paseto.symmetric_key",
+}
+
+// decodeFile decodes configuration file into conf, yaml and toml documents are converted
+// to json so json tags of Configuration are the single naming of options in every format.
+// deprecated options are removed from document and reported in conf.Deprecated.
+func decodeFile(path string, conf *Configuration) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	doc := map[string]interface{}{}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(b, &doc)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &doc)
+	case ".toml":
+		err = toml.Unmarshal(b, &doc)
+	default:
+		return fmt.Errorf("unsupported configuration format %q", ext)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	conf.Deprecated = removeDeprecated(doc, "")
+
+	if b, err = json.Marshal(doc); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	return dec.Decode(conf)
+}
+
+// removeDeprecated removes deprecatedKeys of doc and returns warnings of removed options in order of their path.
+func removeDeprecated(doc map[string]interface{}, prefix string) []string {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var warnings []string
+	for _, key := range keys {
+		path := prefix + key
+
+		if note, ok := deprecatedKeys[path]; ok {
+			delete(doc, key)
+			warnings = append(warnings, path+" is deprecated and ignored: "+note)
+
+			continue
+		}
+
+		if child, ok := doc[key].(map[string]interface{}); ok {
+			warnings = append(warnings, removeDeprecated(child, path+".")...)
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// baseline is configuration file of releases before layered configuration.
+const baseline = `{
+  "listener": {"host": "0.0.0.0", "port": 8080, "cert": "", "key": "", "allowed_hosts": ["*"], "ssl_host": "", "sessions_secret": ""},
+  "jwt": {"token_expire": 518400, "refresh_expire": 1036800, "issuer": "otpapp.com", "audience": "otpapp.com", "subject_key": "sub", "identity_key": "jti"},
+  "redis": {"addr": "localhost:6379", "password": "secret", "db": 0},
+  "rate_limit": {"rate_limit_exclude_paths": [], "rate_limit_request_per_duration": 1000, "rate_limit_duration_seconds": 10, "enabled": true}
+}`
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestDecodeFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		content    string
+		err        string
+		deprecated int
+	}{
+		{
+			name:       "baseline json",
+			file:       "config.json",
+			content:    baseline,
+			deprecated: 1,
+		},
+		{
+			name:    "yaml",
+			file:    "config.yaml",
+			content: "jwt:\n  issuer: otpapp.com\n  audience: otpapp.com\n  token_expire: 60\n  refresh_expire: 120\nredis:\n  addr: localhost:6379\n",
+		},
+		{
+			name:       "toml",
+			file:       "config.toml",
+			content:    "[listener]\nport = 8080\nsessions_secret = \"\"\n\n[jwt]\nissuer = \"otpapp.com\"\naudience = \"otpapp.com\"\ntoken_expire = 60\nrefresh_expire = 120\n",
+			deprecated: 1,
+		},
+		{
+			name:    "unknown option",
+			file:    "config.json",
+			content: `{"jwt": {"issuer": "otpapp.com", "issuers": ["otpapp.com"]}}`,
+			err:     `unknown field "issuers"`,
+		},
+		{
+			name:    "unsupported format",
+			file:    "config.ini",
+			content: "",
+			err:     "unsupported configuration format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := Default()
+
+			err := decodeFile(writeFile(t, tt.file, tt.content), conf)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("decodeFile() error = %v, want %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("decodeFile() error = %v", err)
+			}
+
+			if conf.Jwt.Issuer != "otpapp.com" || conf.Jwt.Audience != "otpapp.com" {
+				t.Errorf("Jwt = %+v, want issuer and audience otpapp.com", conf.Jwt)
+			}
+
+			if conf.Redis.Addr != "localhost:6379" {
+				t.Errorf("Redis.Addr = %q, want default localhost:6379", conf.Redis.Addr)
+			}
+
+			if len(conf.Deprecated) != tt.deprecated {
+				t.Fatalf("Deprecated = %v, want %d warnings", conf.Deprecated, tt.deprecated)
+			}
+
+			for _, warning := range conf.Deprecated {
+				if !strings.HasPrefix(warning, "listener.sessions_secret ") {
+					t.Errorf("Deprecated warning = %q, want warning of listener.sessions_secret", warning)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Store holds the active configuration, it is replaced atomically when configuration is reloaded.
+	// listener, database, redis, paseto and rate limit resilience options are only applied on startup.
+	Store struct {
+		path   string
+		mu     sync.Mutex
+		active atomic.Pointer[snapshot]
+		hooks  []ReloadHook
+	}
+
+	snapshot struct {
+		conf    *Configuration
+		version string
+	}
+
+	// ReloadHook prepares a component for new configuration and returns the func that applies it.
+	// apply funcs are called after every hook prepared successfully and configuration is swapped.
+	ReloadHook func(conf *Configuration) (apply func(), err error)
+)
+
+// NewStore returns Store of configuration file at path, see Load for resolving of path.
+func NewStore(path string) (*Store, error) {
+	path = resolvePath(path)
+
+	conf, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{path: path}
+	s.active.Store(&snapshot{conf: conf, version: Version(conf)})
+
+	return s, nil
+}
+
+// Config returns the active configuration, it must not be modified.
+func (s *Store) Config() *Configuration {
+	return s.active.Load().conf
+}
+
+// Version returns version of the active configuration.
+func (s *Store) Version() string {
+	return s.active.Load().version
+}
+
+// Paths returns configuration file of store.
+func (s *Store) Paths() []string {
+	return []string{s.path}
+}
+
+// OnReload registers hook that prepares a component for reloaded configuration.
+func (s *Store) OnReload(hook ReloadHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hooks = append(s.hooks, hook)
+}
+
+// Reload loads and validates configuration file and swaps the active configuration if it is changed.
+// active configuration is kept if new configuration is invalid or any hook fails to prepare.
+func (s *Store) Reload() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conf, err := Load(s.path)
+	if err != nil {
+		return false, err
+	}
+
+	version := Version(conf)
+	if version == s.Version() {
+		return false, nil
+	}
+
+	applies := make([]func(), 0, len(s.hooks))
+	for _, hook := range s.hooks {
+		apply, err := hook(conf)
+		if err != nil {
+			return false, err
+		}
+
+		applies = append(applies, apply)
+	}
+
+	s.active.Store(&snapshot{conf: conf, version: version})
+
+	for _, apply := range applies {
+		apply()
+	}
+
+	return true, nil
+}
+
+// Version returns version of configuration, it is derived from content of configuration.
+func Version(conf *Configuration) string {
+	b, _ := json.Marshal(conf)
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists every problem of configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	return "config: invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// validator collects problems of configuration options.
+type validator struct {
+	problems []string
+}
+
+func (v *validator) check(ok bool, option, format string, args ...interface{}) {
+	if !ok {
+		v.problems = append(v.problems, option+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+func (v *validator) required(val, option string) {
+	// options of list items can not be set by environment variables
+	if strings.Contains(option, "[") {
+		v.check(val != "", option, "is required")
+		return
+	}
+
+	v.check(val != "", option, "is required (env %s)", envName(option))
+}
+
+func (v *validator) oneOf(val, option string, allowed ...string) {
+	for _, a := range allowed {
+		if val == a {
+			return
+		}
+	}
+
+	names := []string{}
+	for _, a := range allowed {
+		if a != "" {
+			names = append(names, a)
+		}
+	}
+
+	v.check(false, option, "%q is not one of %s", val, strings.Join(names, ", "))
+}
+
+// Validate checks required options and ranges of configuration.
+func (c *Configuration) Validate() error {
+	v := &validator{}
+
+	v.check(c.Listener.Port > 0 && c.Listener.Port <= 65535, "listener.port", "%d is not a valid port", c.Listener.Port)
+	validateListener(v, c.Listener)
+	v.oneOf(c.Http.Mode, "http.mode", "", "debug", "release", "test")
+	v.check(c.Http.ShutdownDelaySeconds >= 0, "http.shutdown_delay_seconds", "must not be negative")
+
+	v.oneOf(c.Database.Driver, "database.driver", "postgres", "sqlite")
+	v.required(c.Database.DSN, "database.dsn")
+
+	v.check(len(c.Paseto.SymmetricKey) == 32, "paseto.symmetric_key",
+		"must be exactly 32 characters, got %d (env %s)", len(c.Paseto.SymmetricKey), envName("paseto.symmetric_key"))
+
+	v.required(c.Redis.Addr, "redis.addr")
+	v.check(c.Redis.DB >= 0, "redis.db", "must not be negative")
+
+	validateJwt(v, c.Jwt, "jwt")
+	validateOtp(v, c.Otp, "otp")
+	v.oneOf(c.I18n.FallbackLanguage, "i18n.fallback_language", "", "en", "fa")
+	validateRateLimit(v, c.RateLimit, "rate_limit")
+
+	res := c.RateLimit.Resilience
+	v.oneOf(res.FailureMode, "rate_limit.resilience.failure_mode", "", "local", "open", "closed")
+	v.check(res.TimeoutMillis >= 0, "rate_limit.resilience.timeout_ms", "must not be negative")
+	v.check(res.BreakerThreshold >= 0, "rate_limit.resilience.breaker_threshold", "must not be negative")
+	v.check(res.BreakerCooldownSeconds >= 0, "rate_limit.resilience.breaker_cooldown_seconds", "must not be negative")
+
+	validateRisk(v, c.Risk)
+	v.check(c.TrustedDevices.TTLHours >= 0, "trusted_devices.ttl_hours", "must not be negative")
+	validateProofOfWork(v, c.ProofOfWork)
+	v.check(c.UserCache.TTLSeconds >= 0, "user_cache.ttl_seconds", "must not be negative")
+	v.check(c.Idempotency.TTLSeconds >= 0, "idempotency.ttl_seconds", "must not be negative")
+	v.check(c.Idempotency.LockSeconds >= 0, "idempotency.lock_seconds", "must not be negative")
+
+	if c.Tracing.Enabled {
+		v.oneOf(c.Tracing.Exporter, "tracing.exporter", "", "otlp", "stdout", "file")
+		if c.Tracing.Exporter == "file" {
+			v.required(c.Tracing.File, "tracing.file")
+		}
+
+		if r := c.Tracing.SampleRatio; r != nil {
+			v.check(*r >= 0 && *r <= 1, "tracing.sample_ratio", "%v is not between 0 and 1", *r)
+		}
+	}
+
+	ids := map[string]bool{}
+	for i, t := range c.Tenants {
+		prefix := fmt.Sprintf("tenants[%d]", i)
+
+		v.required(t.ID, prefix+".id")
+		v.check(!ids[t.ID], prefix+".id", "duplicate tenant id %q", t.ID)
+		ids[t.ID] = true
+
+		if t.Jwt != nil {
+			validateJwt(v, *t.Jwt, prefix+".jwt")
+		}
+
+		if t.Otp != nil {
+			validateOtp(v, *t.Otp, prefix+".otp")
+		}
+
+		if t.RateLimit != nil {
+			validateRateLimit(v, *t.RateLimit, prefix+".rate_limit")
+		}
+	}
+
+	if c.DefaultTenant != "" {
+		v.check(ids[c.DefaultTenant], "default_tenant", "tenant %q is not configured", c.DefaultTenant)
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+
+	return nil
+}
+
+func validateRisk(v *validator, r RiskConfig) {
+	v.check(r.VelocityWindowSeconds >= 0, "risk.velocity_window_seconds", "must not be negative")
+	v.check(r.ChallengeScore >= 0, "risk.challenge_score", "must not be negative")
+	v.check(r.BlockScore >= 0, "risk.block_score", "must not be negative")
+
+	for i, rule := range r.Rules {
+		prefix := fmt.Sprintf("risk.rules[%d]", i)
+
+		v.required(rule.Name, prefix+".name")
+		v.oneOf(rule.Signal, prefix+".signal", "new_device", "ip_changed", "asn_changed", "user_velocity", "ip_velocity", "device_velocity")
+		v.oneOf(rule.Action, prefix+".action", "", "allow", "challenge", "block")
+		v.check(rule.Threshold >= 0, prefix+".threshold", "must not be negative")
+	}
+}
+
+func validateProofOfWork(v *validator, p ProofOfWorkConfig) {
+	if !p.Enabled {
+		return
+	}
+
+	v.check(len(p.Secret) >= 32, "proof_of_work.secret",
+		"must be at least 32 characters, got %d (env %s)", len(p.Secret), envName("proof_of_work.secret"))
+	v.check(p.TTLSeconds >= 0, "proof_of_work.ttl_seconds", "must not be negative")
+	v.check(p.BaseDifficulty >= 0 && p.BaseDifficulty <= 32, "proof_of_work.base_difficulty", "%d is not between 0 and 32", p.BaseDifficulty)
+	v.check(p.MaxDifficulty >= 0 && p.MaxDifficulty <= 32, "proof_of_work.max_difficulty", "%d is not between 0 and 32", p.MaxDifficulty)
+	v.check(p.FactorDifficulty >= 0 && p.FactorDifficulty <= 32, "proof_of_work.factor_difficulty", "%d is not between 0 and 32", p.FactorDifficulty)
+	v.check(p.MaxDifficulty == 0 || p.MaxDifficulty >= p.BaseDifficulty, "proof_of_work.max_difficulty", "must not be less than base_difficulty")
+	v.check(p.StepRequests >= 0, "proof_of_work.step_requests", "must not be negative")
+	v.check(p.WindowSeconds >= 0, "proof_of_work.window_seconds", "must not be negative")
+}
+
+func validateListener(v *validator, l Listener) {
+	if l.Cert == "" {
+		v.check(l.ClientAuth == "" || l.ClientAuth == "none", "listener.client_auth", "requires listener.cert")
+		return
+	}
+
+	v.required(l.Key, "listener.key")
+	v.oneOf(l.MinTLSVersion, "listener.min_tls_version", "", "1.2", "1.3")
+
+	suites := map[string]bool{}
+	for _, s := range tls.CipherSuites() {
+		suites[s.Name] = true
+	}
+
+	for i, name := range l.CipherSuites {
+		v.check(suites[name], fmt.Sprintf("listener.cipher_suites[%d]", i), "%q is not a secure cipher suite", name)
+	}
+
+	v.check(l.RedirectPort >= 0 && l.RedirectPort <= 65535, "listener.redirect_port", "%d is not a valid port", l.RedirectPort)
+	v.check(l.RedirectPort != l.Port, "listener.redirect_port", "must not be listener.port")
+
+	v.oneOf(l.ClientAuth, "listener.client_auth", "", "none", "request", "require")
+	if l.ClientAuth == "request" || l.ClientAuth == "require" {
+		v.required(l.ClientCA, "listener.client_ca")
+	}
+}
+
+func validateJwt(v *validator, jwt Jwt, prefix string) {
+	v.check(jwt.TokenExpire > 0, prefix+".token_expire", "must be positive")
+	v.check(jwt.RefreshExpire >= jwt.TokenExpire, prefix+".refresh_expire", "must not be less than token_expire")
+	v.required(jwt.Issuer, prefix+".issuer")
+	v.required(jwt.Audience, prefix+".audience")
+}
+
+func validateOtp(v *validator, otp OtpConfig, prefix string) {
+	v.oneOf(otp.Driver, prefix+".driver", "", "log", "http")
+	if otp.Driver == "http" {
+		v.required(otp.Endpoint, prefix+".endpoint")
+	}
+
+	for lang := range otp.Templates {
+		v.oneOf(lang, prefix+".templates", "en", "fa")
+	}
+}
+
+func validateRateLimit(v *validator, rl RateLimitConfig, prefix string) {
+	if !rl.Enabled {
+		return
+	}
+
+	v.check(rl.RateLimitRequestPerDuration > 0, prefix+".rate_limit_request_per_duration", "must be positive")
+	v.check(rl.RateLimitDurationSeconds > 0, prefix+".rate_limit_duration_seconds", "must be positive")
+	v.oneOf(rl.Algorithm, prefix+".algorithm", "", "sliding_window", "token_bucket")
+
+	names := make([]string, 0, len(rl.Policies))
+	for name := range rl.Policies {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		p := rl.Policies[name]
+		option := prefix + ".policies." + name
+
+		v.check(p.Requests > 0, option+".requests", "must be positive")
+		v.check(p.DurationSeconds > 0, option+".duration_seconds", "must be positive")
+		v.oneOf(p.Key, option+".key", "", "ip", "mobile", "subject")
+		v.oneOf(p.Algorithm, option+".algorithm", "", "sliding_window", "token_bucket")
+	}
+}
+
+// envName returns environment variable of option that specified by its json path.
+func envName(option string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(option, ".", "_"))
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func validConfig() *Configuration {
+	conf := Default()
+	conf.Database.DSN = "host=localhost"
+	conf.Paseto.SymmetricKey = "0123456789abcdef0123456789abcdef"
+	conf.Jwt = Jwt{TokenExpire: 60, RefreshExpire: 120, Issuer: "otpapp.com", Audience: "otpapp.com"}
+
+	return conf
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(c *Configuration)
+		problems []string
+	}{
+		{
+			name:   "valid",
+			modify: func(c *Configuration) {},
+		},
+		{
+			name: "required options name their environment variable",
+			modify: func(c *Configuration) {
+				c.Database.DSN = ""
+				c.Paseto.SymmetricKey = "short"
+			},
+			problems: []string{
+				"database.dsn: is required (env OTPAPP_DATABASE_DSN)",
+				"paseto.symmetric_key: must be exactly 32 characters, got 5 (env OTPAPP_PASETO_SYMMETRIC_KEY)",
+			},
+		},
+		{
+			name: "ranges and enums",
+			modify: func(c *Configuration) {
+				c.Listener.Port = 70000
+				c.Database.Driver = "mysql"
+				c.Jwt.RefreshExpire = 30
+			},
+			problems: []string{
+				"listener.port: 70000 is not a valid port",
+				"database.driver: \"mysql\" is not one of postgres, sqlite",
+				"jwt.refresh_expire: must not be less than token_expire",
+			},
+		},
+		{
+			name: "tenants",
+			modify: func(c *Configuration) {
+				c.Tenants = []TenantConfig{{ID: "acme"}, {ID: "acme", Jwt: &Jwt{TokenExpire: 60, RefreshExpire: 120}}}
+				c.DefaultTenant = "globex"
+			},
+			problems: []string{
+				"tenants[1].id: duplicate tenant id \"acme\"",
+				"tenants[1].jwt.issuer: is required",
+				"tenants[1].jwt.audience: is required",
+				"default_tenant: tenant \"globex\" is not configured",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := validConfig()
+			tt.modify(conf)
+
+			err := conf.Validate()
+			if tt.problems == nil {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate() error = %v, want ValidationError", err)
+			}
+
+			if !reflect.DeepEqual(verr.Problems, tt.problems) {
+				t.Errorf("Validate() problems = %q, want %q", verr.Problems, tt.problems)
+			}
+		})
+	}
+}
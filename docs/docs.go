@@ -15,6 +15,115 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/v1/admin/config": {
+            "get": {
+                "description": "get active version of configuration and schemas, it requires PLATFORM_ADMIN role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "config version",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/otpapp.BaseResult"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/reload.Status"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "PERMISSION_DENIED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/config/reload": {
+            "post": {
+                "description": "reload configuration and schemas, invalid versions are rejected and active versions are kept, it requires PLATFORM_ADMIN role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "reload config",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/otpapp.BaseResult"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/reload.Status"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "PERMISSION_DENIED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "422": {
+                        "description": "RELOAD_FAILED",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/otpapp.BaseResult"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/reload.Status"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/user": {
             "get": {
                 "description": "get user information",
@@ -28,9 +137,17 @@ const docTemplate = `{
                     "user"
                 ],
                 "summary": "user info",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of cached user info",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "always return status 200 but body contains error",
+                        "description": "OK",
                         "schema": {
                             "allOf": [
                                 {
@@ -45,6 +162,231 @@ const docTemplate = `{
                                     }
                                 }
                             ]
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "entity tag of user info"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "user info is not modified since If-None-Match"
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "404": {
+                        "description": "ACCOUNT_NOT_FOUND",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/devices": {
+            "get": {
+                "security": [
+                    {
+                        "bearer": []
+                    }
+                ],
+                "description": "list trusted devices of user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "trusted devices",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/otpapp.BaseResult"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.DeviceEntity"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/devices/signin": {
+            "post": {
+                "description": "sign in without one time password by device token that issued in otp verification",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "trusted device sign in",
+                "parameters": [
+                    {
+                        "description": "DeviceSignInInput",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceSignInInput"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "key that makes retries of request idempotent",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "stable identifier of app installation",
+                        "name": "X-Device-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "token of solved proof-of-work challenge",
+                        "name": "X-PoW-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nonce that solves proof-of-work challenge",
+                        "name": "X-PoW-Nonce",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/otpapp.BaseResult"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "$ref": "#/definitions/models.TokenBundlerOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "invalid body or validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "DEVICE_NOT_TRUSTED or EXTRA_FACTOR_REQUIRED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "ACCOUNT_SUSPENDED, RISK_BLOCKED or POW_INVALID",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "404": {
+                        "description": "ACCOUNT_NOT_FOUND",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/devices/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "bearer": []
+                    }
+                ],
+                "description": "revoke trust of device, its device token can not sign in anymore",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "revoke trusted device",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "device id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "400": {
+                        "description": "INVALID_PARAM",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "404": {
+                        "description": "DEVICE_NOT_FOUND",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
                         }
                     }
                 }
@@ -63,9 +405,35 @@ const docTemplate = `{
                     "user"
                 ],
                 "summary": "log in",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "key that makes retries of request idempotent",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "stable identifier of app installation",
+                        "name": "X-Device-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "token of solved proof-of-work challenge",
+                        "name": "X-PoW-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nonce that solves proof-of-work challenge",
+                        "name": "X-PoW-Nonce",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "always return status 200 but body contains error",
+                        "description": "OK",
                         "schema": {
                             "allOf": [
                                 {
@@ -75,12 +443,48 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "$ref": "#/definitions/models.TokenBundlerOutput"
+                                            "$ref": "#/definitions/models.OtpOutput"
                                         }
                                     }
                                 }
                             ]
                         }
+                    },
+                    "400": {
+                        "description": "invalid body or validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "EXTRA_FACTOR_REQUIRED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "ACCOUNT_SUSPENDED, RISK_BLOCKED or POW_INVALID",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "404": {
+                        "description": "ACCOUNT_NOT_FOUND",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "428": {
+                        "description": "POW_REQUIRED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
                     }
                 }
             }
@@ -94,6 +498,32 @@ const docTemplate = `{
                     "user"
                 ],
                 "summary": "otp verification",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "key that makes retries of request idempotent",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "stable identifier of app installation",
+                        "name": "X-Device-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "token of solved proof-of-work challenge",
+                        "name": "X-PoW-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nonce that solves proof-of-work challenge",
+                        "name": "X-PoW-Nonce",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -112,6 +542,94 @@ const docTemplate = `{
                                 }
                             ]
                         }
+                    },
+                    "400": {
+                        "description": "invalid body or validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "OTP_INVALID, OTP_EXPIRED or EXTRA_FACTOR_REQUIRED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "ACCOUNT_SUSPENDED, RISK_BLOCKED or POW_INVALID",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "404": {
+                        "description": "ACCOUNT_NOT_FOUND",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/user/refresh": {
+            "post": {
+                "description": "exchange refresh token for a new token bundle",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "refresh token",
+                "parameters": [
+                    {
+                        "description": "RefreshInput",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshInput"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "key that makes retries of request idempotent",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/otpapp.BaseResult"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "$ref": "#/definitions/models.TokenBundlerOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "invalid body or validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "REFRESH_INVALID",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
                     }
                 }
             }
@@ -138,11 +656,29 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.MobileInput"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "key that makes retries of request idempotent",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "token of solved proof-of-work challenge",
+                        "name": "X-PoW-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "nonce that solves proof-of-work challenge",
+                        "name": "X-PoW-Nonce",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "always return status 200 but body contains error",
+                        "description": "OK",
                         "schema": {
                             "allOf": [
                                 {
@@ -152,12 +688,48 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "$ref": "#/definitions/models.TokenBundlerOutput"
+                                            "$ref": "#/definitions/models.OtpOutput"
                                         }
                                     }
                                 }
                             ]
                         }
+                    },
+                    "400": {
+                        "description": "invalid body or validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "POW_INVALID",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "409": {
+                        "description": "ACCOUNT_EXISTS or OTP_NOT_EXPIRED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "428": {
+                        "description": "POW_REQUIRED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "429": {
+                        "description": "RATE_LIMITED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "502": {
+                        "description": "OTP_SEND_FAILED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
                     }
                 }
             }
@@ -169,7 +741,7 @@ const docTemplate = `{
                         "bearer": []
                     }
                 ],
-                "description": "get all user",
+                "description": "get all user, requires ADMIN role",
                 "consumes": [
                     "application/json"
                 ],
@@ -198,6 +770,24 @@ const docTemplate = `{
                                 }
                             ]
                         }
+                    },
+                    "400": {
+                        "description": "INVALID_PARAM",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "401": {
+                        "description": "UNAUTHORIZED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
+                    },
+                    "403": {
+                        "description": "PERMISSION_DENIED",
+                        "schema": {
+                            "$ref": "#/definitions/otpapp.BaseResult"
+                        }
                     }
                 }
             }
@@ -216,10 +806,94 @@ const docTemplate = `{
                 }
             }
         },
+        "models.DeviceEntity": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "fingerprint": {
+                    "description": "Fingerprint is hash of device identifier or its user agent and client hints",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_asn": {
+                    "type": "integer"
+                },
+                "last_ip": {
+                    "description": "LastIP and LastASN are network of last sign in from device",
+                    "type": "string"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name describes device, e.g. \"Chrome 120 on Android\"",
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "trusted_until": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DeviceSignInInput": {
+            "type": "object",
+            "properties": {
+                "device_token": {
+                    "description": "DeviceToken is the token that issued when device is trusted",
+                    "type": "string"
+                },
+                "mobile": {
+                    "description": "Mobile is the mobile number of user",
+                    "type": "string"
+                }
+            }
+        },
         "models.MobileInput": {
             "type": "object",
             "properties": {
                 "mobile": {
+                    "description": "Mobile is the mobile number of user",
+                    "type": "string"
+                }
+            }
+        },
+        "models.OtpOutput": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is the issued one time password, it is only returned in development mode",
+                    "type": "string"
+                },
+                "expire": {
+                    "description": "Expire is time for expire one time password",
+                    "type": "string"
+                },
+                "mobile": {
+                    "description": "Mobile is the mobile number that one time password issued for",
+                    "type": "string"
+                }
+            }
+        },
+        "models.RefreshInput": {
+            "type": "object",
+            "properties": {
+                "refresh": {
+                    "description": "Refresh is the refresh token issued by otp verification",
                     "type": "string"
                 }
             }
@@ -227,6 +901,13 @@ const docTemplate = `{
         "models.TokenBundlerOutput": {
             "type": "object",
             "properties": {
+                "device_expire": {
+                    "type": "string"
+                },
+                "device_token": {
+                    "description": "DeviceToken signs in device without one time password until DeviceExpire, it is only\nreturned when device trust is requested",
+                    "type": "string"
+                },
                 "expire": {
                     "description": "Expire is time for expire token",
                     "type": "string"
@@ -253,10 +934,29 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
+                "locale": {
+                    "description": "Locale is language of messages and one time passwords of account, e.g. \"fa\"",
+                    "type": "string"
+                },
                 "mobile": {
                     "description": "Mobile",
                     "type": "string"
                 },
+                "roles": {
+                    "description": "Roles assigned to account",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "suspended": {
+                    "description": "Suspended accounts can not sign in or refresh their tokens",
+                    "type": "boolean"
+                },
+                "tenant_id": {
+                    "description": "TenantID is the tenant that account belongs to",
+                    "type": "string"
+                },
                 "updatedAt": {
                     "type": "string"
                 }
@@ -265,6 +965,13 @@ const docTemplate = `{
         "otpapp.BaseResult": {
             "type": "object",
             "properties": {
+                "error_details": {
+                    "description": "ErrorDetails provides machine-readable code of every error of Errors in the same order.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/otpapp.Error"
+                    }
+                },
                 "errors": {
                     "description": "Errors provides list off error that occurred in processing request",
                     "type": "array",
@@ -280,6 +987,44 @@ const docTemplate = `{
                     "type": "integer"
                 }
             }
+        },
+        "otpapp.Error": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is stable machine-readable code of error, e.g. OTP_EXPIRED.",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description is human-readable message of error.",
+                    "type": "string"
+                },
+                "field": {
+                    "description": "Field is the input field that error is reported for, e.g. mobile for validation errors.",
+                    "type": "string"
+                }
+            }
+        },
+        "reload.Status": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Error is error of last reload, active state is kept when reload fails.",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of component.",
+                    "type": "string"
+                },
+                "reloaded_at": {
+                    "description": "ReloadedAt is time of last change of state.",
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version of active state.",
+                    "type": "string"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,132 @@
+package otpapp
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ppeymann/top-app.git/api"
+)
+
+type (
+	// Error is an api error of the error catalog, clients must check Code that is stable
+	// instead of Description that may change or be translated.
+	Error struct {
+		// Code is stable machine-readable code of error, e.g. OTP_EXPIRED.
+		Code string `json:"code" mapstructure:"code"`
+
+		// Description is human-readable message of error.
+		Description string `json:"description" mapstructure:"description"`
+
+		// Field is the input field that error is reported for, e.g. mobile for validation errors.
+		Field string `json:"field,omitempty" mapstructure:"field"`
+
+		// Status is http status code of responses that fail with error.
+		Status int `json:"-" mapstructure:"-"`
+	}
+
+	// causedError is catalog error with the message of its cause.
+	causedError struct {
+		err   *Error
+		cause error
+		field string
+	}
+)
+
+// NewError returns catalog error with code, http status and description.
+func NewError(code string, status int, description string) *Error {
+	return &Error{
+		Code:        code,
+		Description: description,
+		Status:      status,
+	}
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return e.Description
+}
+
+// Because returns error of e that its message is cause, e.g. reason of authentication failure.
+// errors.Is reports true for both e and cause.
+func (e *Error) Because(cause error) error {
+	return &causedError{err: e, cause: cause}
+}
+
+// OnField returns error of e for input field that its message is cause, e.g. a validation error.
+func (e *Error) OnField(field string, cause error) error {
+	return &causedError{err: e, cause: cause, field: field}
+}
+
+// Error implements error.
+func (c *causedError) Error() string {
+	return c.cause.Error()
+}
+
+// Unwrap returns catalog error and cause.
+func (c *causedError) Unwrap() []error {
+	return []error{c.err, c.cause}
+}
+
+// error catalog, codes must never change once released.
+var (
+	ErrUnimplementedRequest = NewError(api.CodeNotImplemented, http.StatusNotImplemented, "request is not implemented")
+	ErrUnhandled            = NewError(api.CodeUnhandled, http.StatusInternalServerError, "an unhandled error occurred during processing the request")
+	ErrNotFound             = NewError(api.CodeNotFound, http.StatusNotFound, "not found")
+	ErrInternalServer       = NewError(api.CodeInternal, http.StatusInternalServerError, "internal server error")
+	ErrEntityAlreadyExist   = NewError(api.CodeAlreadyExists, http.StatusConflict, "entity with specified properties already exist")
+	ErrUnAuthorization      = NewError(api.CodeUnauthorized, http.StatusUnauthorized, "UnAuthorization Error")
+	ErrPermissionDenied     = NewError(api.CodePermissionDenied, http.StatusForbidden, "permission denied")
+	ErrInvalidBody          = NewError(api.CodeInvalidBody, http.StatusBadRequest, ProvideRequiredJsonBody)
+	ErrInvalidParam         = NewError(api.CodeInvalidParam, http.StatusBadRequest, ProvideRequiredParam)
+	ErrValidation           = NewError(api.CodeValidationFailed, http.StatusBadRequest, "request is not valid")
+	ErrPreconditionFailed   = NewError(api.CodePreconditionFailed, http.StatusPreconditionFailed, "resource is modified since it is read")
+	ErrPreconditionRequired = NewError(api.CodePreconditionRequired, http.StatusPreconditionRequired, "If-Match header is required")
+	ErrRateLimited          = NewError(api.CodeRateLimited, http.StatusTooManyRequests, "rate limit exceeded")
+	ErrUnavailable          = NewError(api.CodeServiceUnavailable, http.StatusServiceUnavailable, "service is temporarily unavailable")
+)
+
+// CatalogError returns catalog error of err, errors that are not in catalog are reported as ErrInternalServer.
+func CatalogError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	return ErrInternalServer
+}
+
+// NewErrorResult returns BaseResult of errs, status of result is status of first error.
+// messages of errors are kept in Errors and their codes are reported in ErrorDetails.
+// errors that are not in catalog are reported by description of ErrInternalServer and
+// only kept in Causes to be logged, their messages must not reach clients.
+func NewErrorResult(errs ...error) *BaseResult {
+	result := &BaseResult{}
+
+	for _, err := range errs {
+		e := CatalogError(err)
+		if result.Status == 0 {
+			result.Status = e.Status
+		}
+
+		msg := err.Error()
+		if !errors.As(err, new(*Error)) {
+			msg = e.Description
+			result.Causes = append(result.Causes, err)
+		}
+
+		detail := Error{
+			Code:        e.Code,
+			Description: msg,
+		}
+
+		var c *causedError
+		if errors.As(err, &c) {
+			detail.Field = c.field
+		}
+
+		result.Errors = append(result.Errors, msg)
+		result.ErrorDetails = append(result.ErrorDetails, detail)
+	}
+
+	return result
+}
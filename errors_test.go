@@ -0,0 +1,39 @@
+package otpapp_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
+)
+
+func TestNewErrorResult(t *testing.T) {
+	cause := errors.New("pq: password authentication failed for user \"otpapp\"")
+
+	result := otpapp.NewErrorResult(cause, otpapp.ErrValidation.OnField("mobile", errors.New("mobile is required")))
+
+	if result.Status != http.StatusInternalServerError {
+		t.Errorf("Status = %d, want %d", result.Status, http.StatusInternalServerError)
+	}
+
+	want := []otpapp.Error{
+		{Code: api.CodeInternal, Description: otpapp.ErrInternalServer.Description},
+		{Code: api.CodeValidationFailed, Description: "mobile is required", Field: "mobile"},
+	}
+
+	for i, detail := range result.ErrorDetails {
+		if detail != want[i] {
+			t.Errorf("ErrorDetails[%d] = %+v, want %+v", i, detail, want[i])
+		}
+
+		if result.Errors[i] != want[i].Description {
+			t.Errorf("Errors[%d] = %q, want %q", i, result.Errors[i], want[i].Description)
+		}
+	}
+
+	if len(result.Causes) != 1 || result.Causes[0] != cause {
+		t.Errorf("Causes = %v, want [%v]", result.Causes, cause)
+	}
+}
@@ -0,0 +1,187 @@
+// Package health reports liveness and readiness of api server by checking its dependencies.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaults of Checker.
+const (
+	DefaultTimeout time.Duration = 2 * time.Second
+	DefaultTTL     time.Duration = 2 * time.Second
+)
+
+// statuses of Report and Result.
+const (
+	StatusReady        string = "ready"
+	StatusNotReady     string = "not_ready"
+	StatusShuttingDown string = "shutting_down"
+	StatusUp           string = "up"
+	StatusDown         string = "down"
+)
+
+// ErrShuttingDown is reported when server is shutting down gracefully.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+type (
+	// Check reports error if dependency is not available, it must return when ctx is done.
+	Check func(ctx context.Context) error
+
+	// Result is result of a dependency check.
+	Result struct {
+		// Status is "up" or "down".
+		Status string `json:"status"`
+
+		// Error is error of check if dependency is down.
+		Error string `json:"error,omitempty"`
+
+		// LatencyMillis is duration of check.
+		LatencyMillis int64 `json:"latency_ms"`
+
+		// Optional is true for dependencies that server works without, they do not affect readiness.
+		Optional bool `json:"optional,omitempty"`
+	}
+
+	// Report is readiness of server and result of its dependency checks.
+	Report struct {
+		// Status is "ready", "not_ready" or "shutting_down".
+		Status string `json:"status"`
+
+		// Checks are results by dependency name.
+		Checks map[string]Result `json:"checks"`
+
+		// CheckedAt is time of checks, results are cached for a short time.
+		CheckedAt time.Time `json:"checked_at"`
+	}
+
+	// Checker checks registered dependencies concurrently and caches report to avoid
+	// hammering dependencies by frequent probes.
+	Checker struct {
+		ttl      time.Duration
+		shutdown atomic.Bool
+
+		mu     sync.Mutex
+		checks []check
+		report *Report
+	}
+
+	check struct {
+		name     string
+		timeout  time.Duration
+		fn       Check
+		optional bool
+	}
+)
+
+// NewChecker returns Checker that caches reports for ttl, DefaultTTL is used if ttl is not positive.
+func NewChecker(ttl time.Duration) *Checker {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	return &Checker{ttl: ttl}
+}
+
+// Register adds dependency check that is canceled after timeout, DefaultTimeout is used if timeout is not positive.
+// server is not ready while dependency is down.
+func (c *Checker) Register(name string, timeout time.Duration, fn Check) {
+	c.add(check{name: name, timeout: timeout, fn: fn})
+}
+
+// RegisterOptional adds check of a dependency that server works without, e.g. a cache that requests fall back from.
+// its result is reported but server is ready while it is down.
+func (c *Checker) RegisterOptional(name string, timeout time.Duration, fn Check) {
+	c.add(check{name: name, timeout: timeout, fn: fn, optional: true})
+}
+
+func (c *Checker) add(ch check) {
+	if ch.timeout <= 0 {
+		ch.timeout = DefaultTimeout
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, ch)
+	c.report = nil
+}
+
+// Shutdown marks server as shutting down, readiness is reported as not ready from now on.
+func (c *Checker) Shutdown() {
+	c.shutdown.Store(true)
+}
+
+// Ready returns readiness report, dependencies are checked if cached report is expired.
+// concurrent callers wait for the same checks.
+func (c *Checker) Ready(ctx context.Context) Report {
+	if c.shutdown.Load() {
+		return Report{
+			Status:    StatusShuttingDown,
+			Checks:    map[string]Result{},
+			CheckedAt: time.Now().UTC(),
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report != nil && time.Since(c.report.CheckedAt) < c.ttl {
+		return *c.report
+	}
+
+	report := c.run(ctx)
+	c.report = &report
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+
+	wg := sync.WaitGroup{}
+	for i, ch := range c.checks {
+		wg.Add(1)
+
+		go func(i int, ch check) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), ch.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := ch.fn(ctx)
+
+			results[i] = Result{
+				Status:        StatusUp,
+				LatencyMillis: time.Since(start).Milliseconds(),
+				Optional:      ch.optional,
+			}
+
+			if err != nil {
+				results[i].Status = StatusDown
+				results[i].Error = err.Error()
+			}
+		}(i, ch)
+	}
+
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusReady,
+		Checks:    make(map[string]Result, len(c.checks)),
+		CheckedAt: time.Now().UTC(),
+	}
+
+	for i, ch := range c.checks {
+		report.Checks[ch.name] = results[i]
+		if results[i].Status != StatusUp && !ch.optional {
+			report.Status = StatusNotReady
+		}
+	}
+
+	return report
+}
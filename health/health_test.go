@@ -0,0 +1,53 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ppeymann/top-app.git/health"
+)
+
+func TestReady(t *testing.T) {
+	down := func(context.Context) error { return errors.New("connection refused") }
+	up := func(context.Context) error { return nil }
+
+	tests := []struct {
+		name     string
+		register func(c *health.Checker)
+		want     string
+	}{
+		{
+			name: "required dependency is down",
+			register: func(c *health.Checker) {
+				c.Register("database", 0, down)
+				c.RegisterOptional("redis", 0, up)
+			},
+			want: health.StatusNotReady,
+		},
+		{
+			name: "optional dependency is down",
+			register: func(c *health.Checker) {
+				c.Register("database", 0, up)
+				c.RegisterOptional("redis", 0, down)
+			},
+			want: health.StatusReady,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := health.NewChecker(0)
+			tt.register(c)
+
+			report := c.Ready(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("Ready().Status = %q, want %q", report.Status, tt.want)
+			}
+
+			if r := report.Checks["redis"]; !r.Optional {
+				t.Errorf("Ready().Checks[redis].Optional = false, want true")
+			}
+		})
+	}
+}
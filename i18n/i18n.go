@@ -0,0 +1,160 @@
+// Package i18n translates api messages and one time password texts, language of request is negotiated
+// from Accept-Language header or saved locale of account and carried in context.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	otpapp "github.com/ppeymann/top-app.git"
+	"golang.org/x/text/language"
+)
+
+// supported languages, messages are written in English and translated to other languages.
+const (
+	English string = "en"
+	Persian string = "fa"
+)
+
+//go:embed locales/*.json
+var files embed.FS
+
+var (
+	// Supported are languages that messages are translated to, first one is the language of messages.
+	Supported = []string{English, Persian}
+
+	matcher = language.NewMatcher([]language.Tag{language.English, language.Persian})
+
+	// translations maps language to translations of its messages, messages are keys.
+	translations = map[string]map[string]string{}
+)
+
+type (
+	languageKey struct{}
+
+	// preference is language of request.
+	preference struct {
+		lang      string
+		requested bool
+	}
+)
+
+func init() {
+	for _, lang := range Supported[1:] {
+		b, err := files.ReadFile(path.Join("locales", lang+".json"))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: %v", err))
+		}
+
+		messages := map[string]string{}
+		if err := json.Unmarshal(b, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: locales/%s.json: %v", lang, err))
+		}
+
+		translations[lang] = messages
+	}
+}
+
+// IsSupported reports whether messages are translated to lang.
+func IsSupported(lang string) bool {
+	for _, l := range Supported {
+		if l == lang {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Negotiate returns supported language that best matches Accept-Language header, it reports false if
+// header is empty or none of its languages are supported.
+func Negotiate(acceptLanguage string) (string, bool) {
+	if strings.TrimSpace(acceptLanguage) == "" {
+		return "", false
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return "", false
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return "", false
+	}
+
+	return Supported[index], true
+}
+
+// NewContext returns a copy of parent that carries language of request, requested reports whether
+// language is asked by client instead of a fallback.
+func NewContext(parent context.Context, lang string, requested bool) context.Context {
+	return context.WithValue(parent, languageKey{}, preference{lang: lang, requested: requested})
+}
+
+// Prefer returns a copy of ctx that carries locale, e.g. saved locale of account, if client has not asked
+// for a language and locale is supported.
+func Prefer(ctx context.Context, locale string) context.Context {
+	if _, ok := Requested(ctx); ok || !IsSupported(locale) {
+		return ctx
+	}
+
+	return NewContext(ctx, locale, false)
+}
+
+// Language returns language carried by ctx, it is English if ctx carries no language.
+func Language(ctx context.Context) string {
+	if p, ok := ctx.Value(languageKey{}).(preference); ok {
+		return p.lang
+	}
+
+	return English
+}
+
+// Requested returns language that client asked for by Accept-Language header.
+func Requested(ctx context.Context) (string, bool) {
+	p, ok := ctx.Value(languageKey{}).(preference)
+	if !ok || !p.requested {
+		return "", false
+	}
+
+	return p.lang, true
+}
+
+// Translate returns translation of message in lang, message is returned if it has no translation.
+func Translate(lang, message string) string {
+	if t, ok := translations[lang][message]; ok {
+		return t
+	}
+
+	return message
+}
+
+// Render translates template and replaces its {name} placeholders with vars.
+func Render(lang, template string, vars map[string]string) string {
+	out := Translate(lang, template)
+	for name, value := range vars {
+		out = strings.ReplaceAll(out, "{"+name+"}", value)
+	}
+
+	return out
+}
+
+// Localize translates messages of result errors in lang, codes of errors are not changed.
+func Localize(lang string, result *otpapp.BaseResult) {
+	if lang == English || result == nil {
+		return
+	}
+
+	for i, msg := range result.Errors {
+		result.Errors[i] = Translate(lang, msg)
+	}
+
+	for i, detail := range result.ErrorDetails {
+		result.ErrorDetails[i].Description = Translate(lang, detail.Description)
+	}
+}
@@ -0,0 +1,114 @@
+// Package idempotency stores responses of requests that clients sent with an Idempotency-Key header,
+// so retries of a request are answered with its first response instead of running it again.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
+)
+
+// headers of idempotent requests.
+const (
+	// Header is the request header that carries idempotency key of client.
+	Header string = api.IdempotencyKeyHeader
+
+	// ReplayedHeader is set on responses that are replayed from store.
+	ReplayedHeader string = api.IdempotentReplayedHeader
+)
+
+// MaxKeyLength is the maximum length of idempotency keys.
+const MaxKeyLength int = 255
+
+// default lifetimes of keys.
+const (
+	DefaultTTL     time.Duration = 24 * time.Hour
+	DefaultLockTTL time.Duration = 30 * time.Second
+)
+
+var (
+	// ErrInvalidKey is returned for keys that are empty, too long or contain non printable characters.
+	ErrInvalidKey = otpapp.NewError(api.CodeInvalidIdempotencyKey, http.StatusBadRequest, "idempotency key must be 1 to 255 printable ascii characters")
+
+	// ErrKeyReused is returned when a key is sent again with a different request body.
+	ErrKeyReused = otpapp.NewError(api.CodeIdempotencyKeyReused, http.StatusUnprocessableEntity, "idempotency key is already used for a different request")
+
+	// ErrInFlight is returned when a request with the same key is still being processed.
+	ErrInFlight = otpapp.NewError(api.CodeIdempotencyInFlight, http.StatusConflict, "a request with this idempotency key is in progress")
+
+	// ErrNotOwner is returned by Store when lock of key is expired and taken by another request.
+	ErrNotOwner = errors.New("idempotency: lock of key is not owned")
+)
+
+type (
+	// Record is the state of an idempotency key.
+	Record struct {
+		// Fingerprint is hash of request that key is first used for.
+		Fingerprint string
+
+		// Completed reports whether response of request is stored, it is false while request is in flight.
+		Completed bool
+
+		Status      int
+		ContentType string
+		Body        []byte
+	}
+
+	// Store keeps records of idempotency keys.
+	Store interface {
+		// Lock marks key as in flight for lockTTL and returns owner token of lock, if key is already
+		// used its record is returned instead.
+		Lock(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (string, *Record, error)
+
+		// Save stores response of key for ttl, it returns ErrNotOwner if owner does not hold lock of key.
+		Save(ctx context.Context, key, owner string, rec *Record, ttl time.Duration) error
+
+		// Release removes lock of key so request can be retried, completed keys are not removed.
+		Release(ctx context.Context, key, owner string) error
+	}
+)
+
+// ValidKey reports whether key is an acceptable idempotency key.
+func ValidKey(key string) bool {
+	if len(key) == 0 || len(key) > MaxKeyLength {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Fingerprint returns hash of request method, route and body.
+func Fingerprint(method, route string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(route))
+	h.Write([]byte{0})
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// StoreKey returns store key of client key, keys are scoped to tenant, route and subject of request
+// so clients can not replay responses of each other.
+func StoreKey(tenantID, route, subject, key string) string {
+	if subject == "" {
+		subject = "-"
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	return "idempotency:" + tenantID + ":" + route + ":" + subject + ":" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// lockScript marks key as in flight if it is not used, otherwise it returns fields of key.
+//
+// KEYS[1] is key, ARGV is fingerprint, owner and lock ttl in milliseconds.
+var lockScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return redis.call('HGETALL', KEYS[1])
+end
+
+redis.call('HSET', KEYS[1], 'fingerprint', ARGV[1], 'owner', ARGV[2], 'completed', 0)
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+
+return false
+`)
+
+// saveScript stores response of key if lock of key is held by owner.
+//
+// KEYS[1] is key, ARGV is owner, status, content type, body and ttl in milliseconds.
+var saveScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'owner') ~= ARGV[1] then
+	return 0
+end
+
+redis.call('HSET', KEYS[1], 'completed', 1, 'status', ARGV[2], 'content_type', ARGV[3], 'body', ARGV[4])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+
+return 1
+`)
+
+// releaseScript removes key if it is in flight and its lock is held by owner.
+//
+// KEYS[1] is key, ARGV[1] is owner.
+var releaseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'owner') == ARGV[1] and redis.call('HGET', KEYS[1], 'completed') == '0' then
+	return redis.call('DEL', KEYS[1])
+end
+
+return 0
+`)
+
+type redisStore struct {
+	client redis.Scripter
+}
+
+// NewRedisStore returns Store that keeps records in redis hashes, every operation is a single
+// atomic lua script, so concurrent requests of a key are serialized between api server instances.
+func NewRedisStore(client redis.Scripter) Store {
+	return &redisStore{
+		client: client,
+	}
+}
+
+// Lock implements Store.
+func (s *redisStore) Lock(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (string, *Record, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+
+	owner := hex.EncodeToString(b)
+
+	fields, err := lockScript.Run(ctx, s.client, []string{key}, fingerprint, owner, lockTTL.Milliseconds()).StringSlice()
+	if errors.Is(err, redis.Nil) {
+		return owner, nil, nil
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	rec, err := record(fields)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return "", rec, nil
+}
+
+// Save implements Store.
+func (s *redisStore) Save(ctx context.Context, key, owner string, rec *Record, ttl time.Duration) error {
+	saved, err := saveScript.Run(ctx, s.client, []string{key}, owner, rec.Status, rec.ContentType, rec.Body, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	if saved == 0 {
+		return ErrNotOwner
+	}
+
+	return nil
+}
+
+// Release implements Store.
+func (s *redisStore) Release(ctx context.Context, key, owner string) error {
+	return releaseScript.Run(ctx, s.client, []string{key}, owner).Err()
+}
+
+// record converts HGETALL result of key to Record.
+func record(fields []string) (*Record, error) {
+	if len(fields)%2 != 0 {
+		return nil, errors.New("idempotency: unexpected script result")
+	}
+
+	rec := &Record{}
+	for i := 0; i < len(fields); i += 2 {
+		value := fields[i+1]
+
+		switch fields[i] {
+		case "fingerprint":
+			rec.Fingerprint = value
+
+		case "completed":
+			rec.Completed = value == "1"
+
+		case "status":
+			status, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("idempotency: invalid status %q", value)
+			}
+
+			rec.Status = status
+
+		case "content_type":
+			rec.ContentType = value
+
+		case "body":
+			rec.Body = []byte(value)
+		}
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,121 @@
+// Package logging carries request scoped logger and request id in context and redacts sensitive values of logs.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/ppeymann/top-app.git/api"
+)
+
+// RequestIDHeader is the request and response header of request id.
+const RequestIDHeader string = api.RequestIDHeader
+
+// redacted replaces values that must not be logged.
+const redacted string = "[REDACTED]"
+
+// sensitiveKeys are log keys that their values are replaced, mobile numbers are masked instead.
+var sensitiveKeys = map[string]bool{
+	"code":          true,
+	"otp":           true,
+	"verification":  true,
+	"password":      true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"refresh":       true,
+	"device_token":  true,
+	"id_token":      true,
+	"authorization": true,
+}
+
+// mobilePattern matches mobile numbers in log values, e.g. in error messages.
+var mobilePattern = regexp.MustCompile(`\b(?:0098|98|0)?9\d{9}\b`)
+
+type (
+	loggerKey    struct{}
+	requestIDKey struct{}
+
+	// redactor is logger that redacts sensitive values before passing them to next logger.
+	redactor struct {
+		next kitlog.Logger
+	}
+)
+
+// NewRequestID returns a random request id.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// NewContext returns a copy of parent that carries request scoped logger and request id.
+func NewContext(parent context.Context, logger kitlog.Logger, requestID string) context.Context {
+	ctx := context.WithValue(parent, loggerKey{}, logger)
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// FromContext returns request scoped logger carried by ctx, it returns nop logger if ctx carries no logger
+// so services and repositories can log without checking it.
+func FromContext(ctx context.Context) kitlog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(kitlog.Logger); ok && logger != nil {
+		return logger
+	}
+
+	return kitlog.NewNopLogger()
+}
+
+// RequestID returns request id carried by ctx.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRedactor returns logger that masks mobile numbers and replaces one time passwords,
+// tokens and other sensitive values before passing them to next.
+func NewRedactor(next kitlog.Logger) kitlog.Logger {
+	return &redactor{next: next}
+}
+
+// Log implements kitlog.Logger.
+func (r *redactor) Log(keyvals ...interface{}) error {
+	out := make([]interface{}, len(keyvals))
+	copy(out, keyvals)
+
+	for i := 1; i < len(out); i += 2 {
+		key := strings.ToLower(fmt.Sprint(out[i-1]))
+		if sensitiveKeys[key] {
+			out[i] = redacted
+			continue
+		}
+
+		switch v := out[i].(type) {
+		case string:
+			out[i] = RedactString(v)
+		case error:
+			out[i] = RedactString(v.Error())
+		}
+	}
+
+	return r.next.Log(out...)
+}
+
+// RedactString masks mobile numbers of s.
+func RedactString(s string) string {
+	return mobilePattern.ReplaceAllStringFunc(s, MaskMobile)
+}
+
+// MaskMobile keeps first four and last two digits of mobile number, e.g. 0912*****67.
+func MaskMobile(mobile string) string {
+	if len(mobile) <= 6 {
+		return strings.Repeat("*", len(mobile))
+	}
+
+	return mobile[:4] + strings.Repeat("*", len(mobile)-6) + mobile[len(mobile)-2:]
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	otpapp "github.com/ppeymann/top-app.git"
+)
+
+// Call calls next and logs service method with its duration, masked inputs and errors of returned result.
+// request scoped logger of ctx is used if ctx carries it so calls are logged with their request id,
+// otherwise logger is used. it is the body of logging decorators of services, see cmd/decorator.
+func Call(ctx context.Context, logger kitlog.Logger, method string, next func() *otpapp.BaseResult, inputs ...interface{}) *otpapp.BaseResult {
+	begin := time.Now()
+	result := next()
+
+	if l, ok := ctx.Value(loggerKey{}).(kitlog.Logger); ok && l != nil {
+		logger = l
+	} else {
+		logger = NewRedactor(logger)
+	}
+
+	keyvals := []interface{}{
+		"method", method,
+		"took_ms", float64(time.Since(begin).Microseconds()) / 1000,
+	}
+
+	switch len(inputs) {
+	case 0:
+	case 1:
+		keyvals = append(keyvals, "input", Mask(inputs[0]))
+	default:
+		masked := make([]interface{}, 0, len(inputs))
+		for _, in := range inputs {
+			masked = append(masked, Mask(in))
+		}
+
+		keyvals = append(keyvals, "input", masked)
+	}
+
+	if result != nil && len(result.Errors) > 0 {
+		keyvals = append(keyvals, "errors", RedactString(strings.Join(result.Errors, "; ")))
+	}
+
+	if result != nil && len(result.Causes) > 0 {
+		keyvals = append(keyvals, "causes", RedactString(errors.Join(result.Causes...).Error()))
+	}
+
+	_ = logger.Log(keyvals...)
+
+	return result
+}
+
+// Mask returns json representation of v that its sensitive fields are replaced and mobile numbers are masked.
+func Mask(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return redacted
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return redacted
+	}
+
+	return mask(doc)
+}
+
+func mask(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if sensitiveKeys[strings.ToLower(key)] {
+				v[key] = redacted
+				continue
+			}
+
+			v[key] = mask(val)
+		}
+
+	case []interface{}:
+		for i, val := range v {
+			v[i] = mask(val)
+		}
+
+	case string:
+		return RedactString(v)
+	}
+
+	return v
+}
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ppeymann/top-app.git/models"
+)
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "device sign in",
+			in:   &models.DeviceSignInInput{Mobile: "09121234567", DeviceToken: "c2VjcmV0LWRldmljZS10b2tlbg"},
+			want: map[string]interface{}{"mobile": "0912*****67", "device_token": redacted},
+		},
+		{
+			name: "refresh",
+			in:   &models.RefreshInput{Refresh: "v4.local.refresh"},
+			want: map[string]interface{}{"refresh": redacted},
+		},
+		{
+			name: "nested tokens",
+			in: map[string]interface{}{
+				"result": map[string]interface{}{"access_token": "a", "refresh_token": "r", "id_token": "i", "name": "x"},
+			},
+			want: map[string]interface{}{
+				"result": map[string]interface{}{"access_token": redacted, "refresh_token": redacted, "id_token": redacted, "name": "x"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mask(tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mask() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// CommandUsage describes arguments of Run.
+const CommandUsage = `migrate up [-dry-run]                 apply pending migrations
+migrate down [-steps <n>] [-dry-run]  revert applied migrations (default 1 step)
+migrate status                        print migrations state
+`
+
+// Run executes migrate subcommand specified by args (up, down or status) and writes its report to w.
+func Run(ctx context.Context, m *Migrator, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("migrate: subcommand is required\n" + CommandUsage)
+	}
+
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "print migrations without applying them")
+	steps := fs.Int("steps", 1, "number of migrations to revert")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx, *dryRun)
+		report(w, "applying", applied, func(mig Migration) string { return mig.Up }, *dryRun)
+		return err
+
+	case "down":
+		if *steps < 1 {
+			return errors.New("migrate down: -steps must be positive")
+		}
+
+		reverted, err := m.Down(ctx, *steps, *dryRun)
+		report(w, "reverting", reverted, func(mig Migration) string { return mig.Down }, *dryRun)
+		return err
+
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			at := "pending"
+			if s.Applied {
+				at = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, at)
+		}
+
+		return tw.Flush()
+	}
+
+	return fmt.Errorf("migrate: unknown subcommand %q\n%s", args[0], CommandUsage)
+}
+
+func report(w io.Writer, action string, migrations []Migration, sql func(Migration) string, dryRun bool) {
+	if len(migrations) == 0 {
+		fmt.Fprintln(w, "no migrations to run")
+		return
+	}
+
+	for _, mig := range migrations {
+		fmt.Fprintf(w, "%s %d_%s\n", action, mig.Version, mig.Name)
+		if dryRun {
+			fmt.Fprintln(w, sql(mig))
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	file := func(body string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(body)}
+	}
+
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		versions []int64
+		err      string
+	}{
+		{
+			name: "pairs in version order",
+			fsys: fstest.MapFS{
+				"sql/0010_add_locale.up.sql":     file("ALTER TABLE users ADD locale TEXT;"),
+				"sql/0010_add_locale.down.sql":   file("ALTER TABLE users DROP locale;"),
+				"sql/0002_create_users.up.sql":   file("CREATE TABLE users ();"),
+				"sql/0002_create_users.down.sql": file("DROP TABLE users;"),
+			},
+			versions: []int64{2, 10},
+		},
+		{
+			name: "missing down file",
+			fsys: fstest.MapFS{
+				"sql/0001_create_users.up.sql": file("CREATE TABLE users ();"),
+			},
+			err: "must have both up and down files",
+		},
+		{
+			name: "missing up file",
+			fsys: fstest.MapFS{
+				"sql/0001_create_users.down.sql": file("DROP TABLE users;"),
+			},
+			err: "must have both up and down files",
+		},
+		{
+			name: "different names of version",
+			fsys: fstest.MapFS{
+				"sql/0001_create_users.up.sql":      file("CREATE TABLE users ();"),
+				"sql/0001_create_accounts.down.sql": file("DROP TABLE accounts;"),
+			},
+			err: "has different names",
+		},
+		{
+			name: "duplicate files of version",
+			fsys: fstest.MapFS{
+				"sql/1_create_users.up.sql":      file("CREATE TABLE users ();"),
+				"sql/0001_create_users.up.sql":   file("CREATE TABLE users ();"),
+				"sql/0001_create_users.down.sql": file("DROP TABLE users;"),
+			},
+			err: "more than one up file",
+		},
+		{
+			name: "invalid file name",
+			fsys: fstest.MapFS{
+				"sql/create_users.sql": file("CREATE TABLE users ();"),
+			},
+			err: "invalid migration file name",
+		},
+		{
+			name: "version out of range",
+			fsys: fstest.MapFS{
+				"sql/99999999999999999999_create_users.up.sql": file("CREATE TABLE users ();"),
+			},
+			err: "value out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(tt.fsys, "sql")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("load() error = %v, want %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+
+			if len(migrations) != len(tt.versions) {
+				t.Fatalf("load() returned %d migrations, want %d", len(migrations), len(tt.versions))
+			}
+
+			for i, mig := range migrations {
+				if mig.Version != tt.versions[i] || mig.Up == "" || mig.Down == "" {
+					t.Errorf("migration %d = %d %q, want version %d with up and down", i, mig.Version, mig.Name, tt.versions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := load(files, "sql")
+	if err != nil {
+		t.Fatalf("load() of embedded migrations error = %v", err)
+	}
+
+	for i, mig := range migrations {
+		if mig.Version != int64(i+1) {
+			t.Errorf("migration %s has version %d, want %d", mig.Name, mig.Version, i+1)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS user_entities (
+    id                  BIGSERIAL PRIMARY KEY,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ,
+    mobile              TEXT,
+    verification        TEXT,
+    verification_expire BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_entities_deleted_at ON user_entities (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_user_entities_mobile ON user_entities (mobile);
+CREATE INDEX IF NOT EXISTS idx_user_entities_verification ON user_entities (verification);
+CREATE INDEX IF NOT EXISTS idx_user_entities_verification_expire ON user_entities (verification_expire);
+
+-- databases that created by gorm AutoMigrate already have this constraint.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'uni_user_entities_mobile') THEN
+        ALTER TABLE user_entities ADD CONSTRAINT uni_user_entities_mobile UNIQUE (mobile);
+    END IF;
+END
+$$;
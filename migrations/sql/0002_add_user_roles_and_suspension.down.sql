@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_user_entities_suspended;
+
+ALTER TABLE user_entities DROP COLUMN IF EXISTS suspended;
+ALTER TABLE user_entities DROP COLUMN IF EXISTS roles;
@@ -0,0 +1,4 @@
+ALTER TABLE user_entities ADD COLUMN IF NOT EXISTS roles TEXT;
+ALTER TABLE user_entities ADD COLUMN IF NOT EXISTS suspended BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_user_entities_suspended ON user_entities (suspended);
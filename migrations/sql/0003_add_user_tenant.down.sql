@@ -0,0 +1,14 @@
+-- mobile numbers can not be unique globally again once tenants share them, reverting fails instead of
+-- dropping accounts of tenants. accounts of a mobile must be merged or deleted manually before reverting.
+DO $$
+BEGIN
+    IF EXISTS (SELECT mobile FROM user_entities GROUP BY mobile HAVING COUNT(*) > 1) THEN
+        RAISE EXCEPTION 'migration 0003 is irreversible: mobile numbers are used by more than one tenant, remove duplicate accounts before reverting';
+    END IF;
+END
+$$;
+
+DROP INDEX IF EXISTS idx_user_entities_tenant_mobile;
+
+ALTER TABLE user_entities ADD CONSTRAINT uni_user_entities_mobile UNIQUE (mobile);
+ALTER TABLE user_entities DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,6 @@
+ALTER TABLE user_entities ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+
+-- mobile numbers are unique per tenant instead of globally
+ALTER TABLE user_entities DROP CONSTRAINT IF EXISTS uni_user_entities_mobile;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_entities_tenant_mobile ON user_entities (tenant_id, mobile);
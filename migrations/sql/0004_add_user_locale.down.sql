@@ -0,0 +1 @@
+ALTER TABLE user_entities DROP COLUMN IF EXISTS locale;
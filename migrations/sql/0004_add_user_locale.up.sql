@@ -0,0 +1,2 @@
+-- locale is the language that messages and one time passwords are sent to account in
+ALTER TABLE user_entities ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
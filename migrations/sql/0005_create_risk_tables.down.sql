@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS risk_decision_entities;
+DROP TABLE IF EXISTS device_entities;
@@ -0,0 +1,41 @@
+-- devices that accounts signed in from, they are used for new device and network change detection
+CREATE TABLE IF NOT EXISTS device_entities (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    deleted_at   TIMESTAMPTZ,
+    tenant_id    TEXT NOT NULL DEFAULT 'default',
+    user_id      BIGINT NOT NULL,
+    fingerprint  TEXT NOT NULL,
+    name         TEXT,
+    last_ip      TEXT,
+    last_asn     BIGINT,
+    last_seen_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_entities_deleted_at ON device_entities (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_device_entities_last_seen_at ON device_entities (last_seen_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_entities_user_fingerprint ON device_entities (user_id, fingerprint);
+
+-- risk decisions of sign in attempts, they are kept for review
+CREATE TABLE IF NOT EXISTS risk_decision_entities (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    tenant_id   TEXT NOT NULL DEFAULT 'default',
+    user_id     BIGINT,
+    event       TEXT,
+    action      TEXT,
+    score       BIGINT,
+    reasons     TEXT,
+    fingerprint TEXT,
+    device      TEXT,
+    ip          TEXT,
+    asn         BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_risk_decision_entities_deleted_at ON risk_decision_entities (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_risk_decision_entities_tenant_id ON risk_decision_entities (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_risk_decision_entities_user_id ON risk_decision_entities (user_id);
+CREATE INDEX IF NOT EXISTS idx_risk_decision_entities_action ON risk_decision_entities (action);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_device_entities_trusted_until;
+
+ALTER TABLE device_entities DROP COLUMN IF EXISTS trusted_until;
+ALTER TABLE device_entities DROP COLUMN IF EXISTS token_hash;
@@ -0,0 +1,5 @@
+-- trusted devices sign in by their device token without one time password until trusted_until
+ALTER TABLE device_entities ADD COLUMN IF NOT EXISTS token_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE device_entities ADD COLUMN IF NOT EXISTS trusted_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_device_entities_trusted_until ON device_entities (trusted_until);
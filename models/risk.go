@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"time"
+
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
+	"gorm.io/gorm"
+)
+
+// Errors of device domain, they are part of the error catalog.
+var (
+	ErrDeviceNotExist   error = otpapp.NewError(api.CodeDeviceNotFound, http.StatusNotFound, "specified device does not exist")
+	ErrDeviceNotTrusted error = otpapp.NewError(api.CodeDeviceNotTrusted, http.StatusUnauthorized, "device is not trusted or its trust is expired")
+)
+
+type (
+	// RiskRepository represents method signatures for risk domain repository, it stores devices that
+	// accounts signed in from, their trust and risk decisions of sign in attempts.
+	RiskRepository interface {
+		// FindDevice returns device of account by fingerprint.
+		FindDevice(ctx context.Context, userID uint, fingerprint string) (*DeviceEntity, error)
+
+		// LastDevice returns device that account most recently signed in from.
+		LastDevice(ctx context.Context, userID uint) (*DeviceEntity, error)
+
+		// SaveDevice creates device or updates device with same account and fingerprint, trust of device is not changed.
+		SaveDevice(ctx context.Context, device *DeviceEntity) error
+
+		// TrustDevice saves device like SaveDevice and replaces its trust.
+		TrustDevice(ctx context.Context, device *DeviceEntity) error
+
+		// TrustedDevices returns devices of account that their trust is not expired.
+		TrustedDevices(ctx context.Context, userID uint) ([]DeviceEntity, error)
+
+		// RevokeDevice removes trust of device of account, it returns ErrDeviceNotExist if account has no such trusted device.
+		RevokeDevice(ctx context.Context, userID, id uint) error
+
+		// RecordDecision stores risk decision for review.
+		RecordDecision(ctx context.Context, decision *RiskDecisionEntity) error
+
+		// FindDecisions returns a page of decisions of tenant, newest first, they are filtered by account if userID is not zero.
+		FindDecisions(ctx context.Context, tenant string, userID uint, page, limit int32) ([]RiskDecisionEntity, error)
+
+		otpapp.BaseRepository
+	}
+
+	// DeviceEntity is a device that account signed in from
+	//
+	// swagger: model DeviceEntity
+	DeviceEntity struct {
+		gorm.Model
+
+		TenantID string `json:"tenant_id" gorm:"column:tenant_id;not null;default:default"`
+
+		UserID uint `json:"user_id" gorm:"column:user_id;not null;uniqueIndex:idx_device_entities_user_fingerprint"`
+
+		// Fingerprint is hash of device identifier or its user agent and client hints
+		Fingerprint string `json:"fingerprint" gorm:"column:fingerprint;not null;uniqueIndex:idx_device_entities_user_fingerprint"`
+
+		// Name describes device, e.g. "Chrome 120 on Android"
+		Name string `json:"name" gorm:"column:name"`
+
+		// LastIP and LastASN are network of last sign in from device
+		LastIP  string `json:"last_ip" gorm:"column:last_ip"`
+		LastASN uint32 `json:"last_asn" gorm:"column:last_asn"`
+
+		LastSeenAt time.Time `json:"last_seen_at" gorm:"column:last_seen_at;index"`
+
+		// TokenHash is hash of device token, device is trusted until TrustedUntil
+		TokenHash    string     `json:"-" gorm:"column:token_hash"`
+		TrustedUntil *time.Time `json:"trusted_until,omitempty" gorm:"column:trusted_until;index"`
+	}
+
+	// RiskDecisionEntity is risk decision of a sign in attempt
+	//
+	// swagger: model RiskDecisionEntity
+	RiskDecisionEntity struct {
+		gorm.Model
+
+		TenantID string `json:"tenant_id" gorm:"column:tenant_id;not null;default:default;index"`
+		UserID   uint   `json:"user_id" gorm:"column:user_id;index"`
+
+		// Event is the evaluated operation: "login" or "otp_verify"
+		Event string `json:"event" gorm:"column:event"`
+
+		// Action is the decision: "allow", "challenge" or "block"
+		Action string `json:"action" gorm:"column:action;index"`
+
+		Score int `json:"score" gorm:"column:score"`
+
+		// Reasons are names of rules that matched attempt
+		Reasons Reasons `json:"reasons" gorm:"column:reasons;type:text"`
+
+		Fingerprint string `json:"fingerprint" gorm:"column:fingerprint"`
+		Device      string `json:"device" gorm:"column:device"`
+		IP          string `json:"ip" gorm:"column:ip"`
+		ASN         uint32 `json:"asn" gorm:"column:asn"`
+	}
+
+	// Reasons is list of matched risk rules that stored as comma separated text column.
+	Reasons []string
+)
+
+// Value implements driver.Valuer.
+func (r Reasons) Value() (driver.Value, error) {
+	return Roles(r).Value()
+}
+
+// Scan implements sql.Scanner.
+func (r *Reasons) Scan(value interface{}) error {
+	return (*Roles)(r).Scan(value)
+}
@@ -1,51 +1,80 @@
 package models
 
+//go:generate go run ../cmd/schema -source user.go -interface UserService -output ../schemas/user -definitions ../schemas/shared/definitions.json -openapi ../docs/components/user.json
+
 import (
-	"errors"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
 	"gorm.io/gorm"
 )
 
-// Errors
+// OtpLifetime is time that one time passwords are valid for.
+const OtpLifetime time.Duration = 180 * time.Second
+
+// Errors of user domain, they are part of the error catalog.
 var (
-	ErrAccountExist     error = errors.New("account with specified params already exists")
-	ErrSignInFailed     error = errors.New("account not found or password error")
-	ErrPermissionDenied error = errors.New("specified role is not available for user")
-	ErrAccountNotExist  error = errors.New("specified account does not exist")
+	ErrAccountExist     error = otpapp.NewError(api.CodeAccountExists, http.StatusConflict, "account with specified params already exists")
+	ErrSignInFailed     error = otpapp.NewError(api.CodeSignInFailed, http.StatusUnauthorized, "account not found or password error")
+	ErrPermissionDenied error = otpapp.NewError(api.CodeRoleNotAvailable, http.StatusForbidden, "specified role is not available for user")
+	ErrAccountNotExist  error = otpapp.NewError(api.CodeAccountNotFound, http.StatusNotFound, "specified account does not exist")
+	ErrOtpInvalid       error = otpapp.NewError(api.CodeOtpInvalid, http.StatusUnauthorized, "OTP is not correct")
+	ErrOtpExpired       error = otpapp.NewError(api.CodeOtpExpired, http.StatusUnauthorized, "OTP Expired")
+	ErrOtpNotExpired    error = otpapp.NewError(api.CodeOtpNotExpired, http.StatusConflict, "previous otp not expired, please wait a few minutes")
+	ErrInvalidRefresh   error = otpapp.NewError(api.CodeRefreshInvalid, http.StatusUnauthorized, "refresh token is not valid or expired")
+	ErrAccountSuspended error = otpapp.NewError(api.CodeAccountSuspended, http.StatusForbidden, "account is suspended")
 )
 
 type (
 	// UserService represents method signatures for api user endpoint.
 	// so any object that stratifying this interface can be used as user service for api endpoint.
+	// the authenticated principal (if any) is carried by ctx, see auth.NewContext.
 	UserService interface {
 		// Register a new user account.
-		Register(ctx *gin.Context, mobile string) *otpapp.BaseResult
+		Register(ctx context.Context, in *MobileInput) *otpapp.BaseResult
 
 		// Login a user account.
-		Login(ctx *gin.Context, mobile string) *otpapp.BaseResult
+		Login(ctx context.Context, in *MobileInput) *otpapp.BaseResult
 
 		// OtpVerify verifies the one time password for user account.
-		OtpVerify(in *OtpInput, ctx *gin.Context) *otpapp.BaseResult
+		OtpVerify(ctx context.Context, in *OtpInput) *otpapp.BaseResult
+
+		// Refresh exchanges a refresh token for a new token bundle.
+		Refresh(ctx context.Context, in *RefreshInput) *otpapp.BaseResult
+
+		// GetUserByPhone returns the account of the authenticated principal.
+		GetUserByPhone(ctx context.Context) *otpapp.BaseResult
+
+		// GetAllUser returns a page of user accounts.
+		GetAllUser(ctx context.Context, in *PageInput) *otpapp.BaseResult
 
-		// GetUserByPhone
-		GetUserByPhone(ctx *gin.Context) *otpapp.BaseResult
+		// DeviceSignIn signs in a trusted device by its device token without one time password.
+		DeviceSignIn(ctx context.Context, in *DeviceSignInInput) *otpapp.BaseResult
 
-		// GetAllUser
-		GetAllUser(ctx *gin.Context, page, limit int32) *otpapp.BaseResult
+		// TrustedDevices returns trusted devices of the authenticated principal.
+		TrustedDevices(ctx context.Context) *otpapp.BaseResult
+
+		// RevokeDevice revokes trust of a device of the authenticated principal.
+		RevokeDevice(ctx context.Context, in *DeviceInput) *otpapp.BaseResult
 	}
 
 	// UserRepository represents method signatures for user domain repository.
 	// so any object that stratifying this interface can be used as user domain repository.
+	// mobile numbers are unique per tenant.
 	UserRepository interface {
-		Create(mobile string) (*UserEntity, error)
-		Find(mobile string) (*UserEntity, error)
-		FindByID(id uint) (*UserEntity, error)
-		SetOtp(id uint, otp string, expire int64) error
-		Update(user *UserEntity) error
-		FindAllUser(page, limit int32) ([]UserEntity, error)
+		Create(ctx context.Context, tenant, mobile string) (*UserEntity, error)
+		Find(ctx context.Context, tenant, mobile string) (*UserEntity, error)
+		FindByID(ctx context.Context, id uint) (*UserEntity, error)
+		SetOtp(ctx context.Context, id uint, otp string, expire int64) error
+		Update(ctx context.Context, user *UserEntity) error
+		FindAllUser(ctx context.Context, tenant string, page, limit int32) ([]UserEntity, error)
 
 		otpapp.BaseRepository
 	}
@@ -56,8 +85,12 @@ type (
 		SignUp(ctx *gin.Context)
 		SignIn(ctx *gin.Context)
 		OtpVerify(ctx *gin.Context)
+		Refresh(ctx *gin.Context)
 		GetUser(ctx *gin.Context)
 		GetAllUsers(ctx *gin.Context)
+		DeviceSignIn(ctx *gin.Context)
+		TrustedDevices(ctx *gin.Context)
+		RevokeDevice(ctx *gin.Context)
 	}
 
 	// UserEntity contains user info for stored on database
@@ -66,23 +99,90 @@ type (
 	UserEntity struct {
 		gorm.Model
 
+		// TenantID is the tenant that account belongs to
+		TenantID string `json:"tenant_id" gorm:"column:tenant_id;not null;default:default;uniqueIndex:idx_user_entities_tenant_mobile"`
+
 		// Mobile
-		Mobile string `json:"mobile" gorm:"column:mobile;index;unique"`
+		Mobile string `json:"mobile" gorm:"column:mobile;index;uniqueIndex:idx_user_entities_tenant_mobile"`
 
 		// Verification code for using as one time password for logging in to account
 		Verification string `json:"-" gorm:"verification;index"`
 
 		VerificationExpire int64 `json:"-" gorm:"verification_expire;index"`
+
+		// Roles assigned to account
+		Roles Roles `json:"roles" gorm:"column:roles;type:text"`
+
+		// Suspended accounts can not sign in or refresh their tokens
+		Suspended bool `json:"suspended" gorm:"column:suspended;index"`
+
+		// Locale is language of messages and one time passwords of account, e.g. "fa"
+		Locale string `json:"locale" gorm:"column:locale;not null;default:''"`
 	}
 
+	// Roles is list of account roles that stored as comma separated text column.
+	Roles []string
+
+	// OtpInput verifies one time password of account.
 	OtpInput struct {
 		// Mobile is the mobile number of user
-		Mobile       string `json:"mobile"`
-		Verification string `json:"verification"`
+		Mobile string `json:"mobile" schema:"required,ref=definitions#/definitions/mobile"`
+
+		// Verification is the one time password that sent to mobile
+		Verification string `json:"verification" schema:"required,ref=definitions#/definitions/otp"`
+
+		// TrustDevice requests a device token that signs in device without one time password
+		TrustDevice bool `json:"trust_device"`
+	}
+
+	// DeviceSignInInput signs in a trusted device.
+	DeviceSignInInput struct {
+		// Mobile is the mobile number of user
+		Mobile string `json:"mobile" schema:"required,ref=definitions#/definitions/mobile"`
+
+		// DeviceToken is the token that issued when device is trusted
+		DeviceToken string `json:"device_token" schema:"required,ref=definitions#/definitions/token"`
 	}
 
+	// DeviceInput specifies a device of account.
+	DeviceInput struct {
+		// ID is the id of device
+		ID uint `json:"id" schema:"required,min=1"`
+	}
+
+	// MobileInput specifies account of sign up and sign in requests.
 	MobileInput struct {
+		// Mobile is the mobile number of user
+		Mobile string `json:"mobile" schema:"required,ref=definitions#/definitions/mobile"`
+	}
+
+	// RefreshInput exchanges refresh token for a new token bundle.
+	RefreshInput struct {
+		// Refresh is the refresh token issued by otp verification
+		Refresh string `json:"refresh" schema:"required,ref=definitions#/definitions/token"`
+	}
+
+	// PageInput specifies requested page of a list.
+	PageInput struct {
+		// Page is the 1-based page number
+		Page int32 `json:"page" schema:"min=0"`
+
+		// Limit is the maximum number of records in page
+		Limit int32 `json:"limit" schema:"min=0,max=100"`
+	}
+
+	// OtpOutput
+	//
+	// swagger: model OtpOutput
+	OtpOutput struct {
+		// Mobile is the mobile number that one time password issued for
 		Mobile string `json:"mobile"`
+
+		// Code is the issued one time password, it is only returned in development mode
+		Code string `json:"code,omitempty"`
+
+		// Expire is time for expire one time password
+		Expire time.Time `json:"expire"`
 	}
 
 	// TokenBundlerOutput
@@ -97,6 +197,11 @@ type (
 
 		// Expire is time for expire token
 		Expire time.Time `json:"expire"`
+
+		// DeviceToken signs in device without one time password until DeviceExpire, it is only
+		// returned when device trust is requested
+		DeviceToken  string     `json:"device_token,omitempty"`
+		DeviceExpire *time.Time `json:"device_expire,omitempty"`
 	}
 )
 
@@ -106,3 +211,32 @@ func (a *UserEntity) IsVerificationExpired() bool {
 	}
 	return false
 }
+
+// Value implements driver.Valuer.
+func (r Roles) Value() (driver.Value, error) {
+	return strings.Join(r, ","), nil
+}
+
+// Scan implements sql.Scanner.
+func (r *Roles) Scan(value interface{}) error {
+	var str string
+
+	switch v := value.(type) {
+	case nil:
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		return fmt.Errorf("unsupported roles column type: %T", value)
+	}
+
+	*r = Roles{}
+	for _, role := range strings.Split(str, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			*r = append(*r, role)
+		}
+	}
+
+	return nil
+}
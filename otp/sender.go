@@ -0,0 +1,196 @@
+package otp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/i18n"
+	"github.com/ppeymann/top-app.git/logging"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// sender drivers
+const (
+	LogDriver  string = "log"
+	HttpDriver string = "http"
+)
+
+// DefaultTemplate is the message text that used if template is not configured.
+const DefaultTemplate string = "Your verification code: {code}"
+
+// ErrSendFailed is returned when one time password could not be delivered.
+var ErrSendFailed error = otpapp.NewError(api.CodeOtpSendFailed, http.StatusBadGateway, "failed to send one time password")
+
+type (
+	// Sender delivers one time passwords to mobile numbers.
+	Sender interface {
+		Send(ctx context.Context, mobile, code string) error
+
+		// Check reports error if messages can not be delivered, it does not send any message.
+		Check(ctx context.Context) error
+	}
+
+	// logSender writes one time passwords to logger, it is intended for development.
+	logSender struct {
+		logger    kitlog.Logger
+		templates *templates
+	}
+
+	// httpSender posts one time password messages to a sms gateway.
+	httpSender struct {
+		client    *http.Client
+		endpoint  string
+		apiKey    string
+		sender    string
+		templates *templates
+	}
+
+	// templates renders message text in language of request.
+	templates struct {
+		fallback  string
+		languages map[string]string
+	}
+
+	// message is the request body of sms gateway.
+	message struct {
+		Sender   string `json:"sender"`
+		Receptor string `json:"receptor"`
+		Message  string `json:"message"`
+	}
+)
+
+// NewSender returns the Sender of configured driver.
+func NewSender(conf config.OtpConfig, logger kitlog.Logger) (Sender, error) {
+	tmpl := &templates{
+		fallback:  conf.Template,
+		languages: conf.Templates,
+	}
+
+	switch conf.Driver {
+	case LogDriver, "":
+		return &logSender{
+			logger:    kitlog.With(logger, "component", "otp"),
+			templates: tmpl,
+		}, nil
+
+	case HttpDriver:
+		if conf.Endpoint == "" {
+			return nil, errors.New("otp: endpoint is required for http driver")
+		}
+
+		return &httpSender{
+			client:    &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+			endpoint:  conf.Endpoint,
+			apiKey:    conf.ApiKey,
+			sender:    conf.Sender,
+			templates: tmpl,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("otp: unsupported sender driver: %s", conf.Driver)
+}
+
+// Send implements Sender.
+func (s *logSender) Send(ctx context.Context, mobile, code string) error {
+	return s.logger.Log("mobile", mobile, "message", s.templates.render(i18n.Language(ctx), code))
+}
+
+// Check implements Sender.
+func (s *logSender) Check(context.Context) error {
+	return nil
+}
+
+// Check implements Sender, it connects to sms gateway.
+func (s *httpSender) Check(ctx context.Context) error {
+	u, err := url.Parse(s.endpoint)
+	if err != nil {
+		return err
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSendFailed, err)
+	}
+
+	return conn.Close()
+}
+
+// Send implements Sender.
+func (s *httpSender) Send(ctx context.Context, mobile, code string) error {
+	body, err := json.Marshal(&message{
+		Sender:   s.sender,
+		Receptor: mobile,
+		Message:  s.templates.render(i18n.Language(ctx), code),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
+
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSendFailed, err)
+	}
+
+	defer func() {
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: gateway responded %s", ErrSendFailed, res.Status)
+	}
+
+	return nil
+}
+
+// render returns message text of code in lang, template of lang is used if it is configured, otherwise
+// configured template or translation of DefaultTemplate is used.
+func (t *templates) render(lang, code string) string {
+	template, ok := t.languages[lang]
+	if !ok {
+		template = t.fallback
+	}
+
+	if template == "" {
+		template = i18n.Translate(lang, DefaultTemplate)
+	}
+
+	return strings.ReplaceAll(template, "{code}", code)
+}
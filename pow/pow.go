@@ -0,0 +1,167 @@
+// Package pow issues hashcash style proof-of-work challenges and verifies their solutions statelessly,
+// challenges are signed by HMAC so they are not stored by server.
+package pow
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
+)
+
+// headers of requests that carry solution of a challenge.
+const (
+	// ChallengeHeader carries token of solved challenge.
+	ChallengeHeader string = api.PowChallengeHeader
+
+	// NonceHeader carries nonce that solves challenge.
+	NonceHeader string = api.PowNonceHeader
+)
+
+// Algorithm is hash function of challenges.
+const Algorithm string = api.PowAlgorithm
+
+// MaxDifficulty is the maximum difficulty of challenges, it is number of leading zero bits of hash.
+const MaxDifficulty int = 32
+
+// default options of challenges.
+const (
+	DefaultTTL              time.Duration = 2 * time.Minute
+	DefaultBaseDifficulty   int           = 16
+	DefaultFactorDifficulty int           = 20
+	DefaultMaxDifficulty    int           = 24
+	DefaultStep             int64         = 5
+	DefaultWindow           time.Duration = 10 * time.Minute
+)
+
+// version is the first field of challenge tokens.
+const version string = "v1"
+
+var (
+	// ErrRequired is returned for requests that did not send a solution of challenge.
+	ErrRequired = otpapp.NewError(api.CodePowRequired, http.StatusPreconditionRequired, "proof of work is required")
+
+	// ErrInvalid is returned for solutions that are wrong or their challenge is forged, expired or issued for another client.
+	ErrInvalid = otpapp.NewError(api.CodePowInvalid, http.StatusForbidden, "proof of work is not valid or expired")
+
+	// errMalformed is reason of ErrInvalid for tokens that can not be parsed.
+	errMalformed = errors.New("pow: malformed challenge token")
+)
+
+type (
+	// Challenge is a puzzle that client solves by finding a nonce that sha256 hash of
+	// "<token>:<subject>:<nonce>" starts with Difficulty zero bits, subject is mobile of request.
+	Challenge struct {
+		// Token is the signed challenge that is sent back in X-PoW-Challenge header
+		Token string `json:"token"`
+
+		// Difficulty is number of leading zero bits of hash of solution
+		Difficulty int `json:"difficulty"`
+
+		Algorithm string    `json:"algorithm"`
+		Expire    time.Time `json:"expire"`
+	}
+
+	// Policy scales difficulty of challenges by number of challenges that caller requested in Window,
+	// every Step challenges add a bit to Base until Max, so work of callers grows exponentially with their rate.
+	// challenges of at least Factor difficulty pass as extra factor of sign in attempts that risk engine challenges.
+	Policy struct {
+		Base   int
+		Max    int
+		Factor int
+		Step   int64
+		Window time.Duration
+		TTL    time.Duration
+	}
+)
+
+// Difficulty returns difficulty of a challenge that is count-th challenge of caller in window.
+func (p Policy) Difficulty(count int64) int {
+	step := p.Step
+	if step < 1 {
+		step = DefaultStep
+	}
+
+	d := p.Base
+	if count > 1 {
+		d += int((count - 1) / step)
+	}
+
+	return min(d, p.Max, MaxDifficulty)
+}
+
+// Issue returns a challenge of difficulty that is signed by secret and bound to resource, e.g. client ip,
+// so it is only accepted from the same resource until ttl.
+func Issue(secret []byte, resource string, difficulty int, ttl time.Duration) (*Challenge, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	expire := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	payload := fmt.Sprintf("%s:%d:%d:%s", version, difficulty, expire.Unix(), hex.EncodeToString(salt))
+
+	return &Challenge{
+		Token:      payload + ":" + sign(secret, payload, resource),
+		Difficulty: difficulty,
+		Algorithm:  Algorithm,
+		Expire:     expire,
+	}, nil
+}
+
+// Verify checks that nonce solves challenge of token for subject, token must be signed by secret for resource
+// and not expired. it returns difficulty of solved challenge.
+func Verify(secret []byte, token, resource, subject, nonce string) (int, error) {
+	i := strings.LastIndexByte(token, ':')
+	if i < 0 {
+		return 0, ErrInvalid.Because(errMalformed)
+	}
+
+	payload, mac := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(mac), []byte(sign(secret, payload, resource))) {
+		return 0, ErrInvalid
+	}
+
+	fields := strings.Split(payload, ":")
+	if len(fields) != 4 || fields[0] != version {
+		return 0, ErrInvalid.Because(errMalformed)
+	}
+
+	difficulty, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, ErrInvalid.Because(errMalformed)
+	}
+
+	expire, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return 0, ErrInvalid.Because(errMalformed)
+	}
+
+	if time.Now().Unix() > expire {
+		return 0, ErrInvalid
+	}
+
+	if nonce == "" || api.LeadingZeros(api.Hash(token, subject, nonce)) < difficulty {
+		return 0, ErrInvalid
+	}
+
+	return difficulty, nil
+}
+
+// sign returns HMAC of payload and resource.
+func sign(secret []byte, payload, resource string) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(payload + "\x00" + resource))
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
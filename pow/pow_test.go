@@ -0,0 +1,113 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppeymann/top-app.git/api"
+)
+
+var secret = []byte("fedcba9876543210fedcba9876543210")
+
+func solve(t *testing.T, c *Challenge, subject string) string {
+	t.Helper()
+
+	nonce, err := api.Solve(context.Background(), c.Token, c.Difficulty, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return nonce
+}
+
+func TestVerify(t *testing.T) {
+	c, err := Issue(secret, "10.0.0.1", 8, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := solve(t, c, "09120000001")
+
+	expired, err := Issue(secret, "10.0.0.1", 8, -2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a nonce that does not reach difficulty of challenge
+	weak := ""
+	for i := 0; ; i++ {
+		if api.LeadingZeros(api.Hash(c.Token, "09120000001", strconv.Itoa(i))) < c.Difficulty {
+			weak = strconv.Itoa(i)
+			break
+		}
+	}
+
+	// difficulty of token is lowered without signing it again
+	tampered := strings.Replace(c.Token, "v1:8:", "v1:0:", 1)
+
+	tests := []struct {
+		name     string
+		secret   []byte
+		token    string
+		resource string
+		subject  string
+		nonce    string
+		valid    bool
+	}{
+		{name: "solved", secret: secret, token: c.Token, resource: "10.0.0.1", subject: "09120000001", nonce: nonce, valid: true},
+		{name: "tampered token", secret: secret, token: tampered, resource: "10.0.0.1", subject: "09120000001", nonce: weak},
+		{name: "another secret", secret: []byte("0123456789abcdef0123456789abcdef"), token: c.Token, resource: "10.0.0.1", subject: "09120000001", nonce: nonce},
+		{name: "expired challenge", secret: secret, token: expired.Token, resource: "10.0.0.1", subject: "09120000001", nonce: solve(t, expired, "09120000001")},
+		{name: "wrong resource", secret: secret, token: c.Token, resource: "10.0.0.2", subject: "09120000001", nonce: nonce},
+		{name: "wrong subject", secret: secret, token: c.Token, resource: "10.0.0.1", subject: "09120000002", nonce: nonce},
+		{name: "insufficient difficulty", secret: secret, token: c.Token, resource: "10.0.0.1", subject: "09120000001", nonce: weak},
+		{name: "missing nonce", secret: secret, token: c.Token, resource: "10.0.0.1", subject: "09120000001"},
+		{name: "malformed token", secret: secret, token: "token", resource: "10.0.0.1", subject: "09120000001", nonce: nonce},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			difficulty, err := Verify(tt.secret, tt.token, tt.resource, tt.subject, tt.nonce)
+			if tt.valid {
+				if err != nil || difficulty != c.Difficulty {
+					t.Fatalf("Verify() = %d, %v, want %d", difficulty, err, c.Difficulty)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Verify() error = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestPolicyDifficulty(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		count  int64
+		want   int
+	}{
+		{name: "first challenge", policy: Policy{Base: 16, Max: 24, Step: 5}, count: 1, want: 16},
+		{name: "last challenge of first step", policy: Policy{Base: 16, Max: 24, Step: 5}, count: 5, want: 16},
+		{name: "first challenge of second step", policy: Policy{Base: 16, Max: 24, Step: 5}, count: 6, want: 17},
+		{name: "third step", policy: Policy{Base: 16, Max: 24, Step: 5}, count: 11, want: 18},
+		{name: "capped at max", policy: Policy{Base: 16, Max: 24, Step: 5}, count: 1000, want: 24},
+		{name: "capped at MaxDifficulty", policy: Policy{Base: 30, Max: 40, Step: 1}, count: 10, want: MaxDifficulty},
+		{name: "default step", policy: Policy{Base: 16, Max: 24}, count: DefaultStep + 1, want: 17},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Difficulty(tt.count); got != tt.want {
+				t.Errorf("Difficulty(%d) = %d, want %d", tt.count, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// State is state of circuit breaker.
+type State int
+
+const (
+	// Closed breaker lets calls through.
+	Closed State = iota
+
+	// Open breaker rejects calls until its cooldown is elapsed.
+	Open
+
+	// HalfOpen breaker lets a single probe call through, it closes if probe succeeds.
+	HalfOpen
+)
+
+// String implements fmt.Stringer.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	}
+
+	return "unknown"
+}
+
+// Breaker is a circuit breaker that stops calling a failing dependency for a cooldown period.
+// It is safe for concurrent use by multiple goroutines.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	gauge     metrics.Gauge
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker returns closed Breaker that opens after threshold consecutive failures and probes
+// dependency again after cooldown. state of breaker is reported to gauge if it is not nil.
+func NewBreaker(threshold int, cooldown time.Duration, gauge metrics.Gauge) *Breaker {
+	b := &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		gauge:     gauge,
+	}
+
+	b.report()
+
+	return b
+}
+
+// Allow reports whether a call may be made, a call that allowed must be followed by Success or Failure.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Closed:
+		return true
+
+	case Open:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+
+		b.setState(HalfOpen)
+	}
+
+	// only one probe call is made at a time in half open state
+	if b.probing {
+		return false
+	}
+
+	b.probing = true
+
+	return true
+}
+
+// Success records a successful call.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	b.setState(Closed)
+}
+
+// Failure records a failed call.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+
+	if b.state == HalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		b.setState(Open)
+	}
+}
+
+// Cancel records a call that abandoned before its result is known.
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// State returns current state of breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// RetryAfter returns remaining cooldown of open breaker.
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != Open {
+		return 0
+	}
+
+	return max(0, b.cooldown-time.Since(b.openedAt))
+}
+
+// setState changes state of breaker, it must be called with mu held.
+func (b *Breaker) setState(s State) {
+	if b.state == s {
+		return
+	}
+
+	b.state = s
+	b.report()
+}
+
+func (b *Breaker) report() {
+	if b.gauge != nil {
+		b.gauge.Set(float64(b.state))
+	}
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	b := NewBreaker(2, time.Minute, nil)
+
+	// closed breaker opens after threshold consecutive failures
+	for i := 0; i < 2; i++ {
+		if !b.Allow() {
+			t.Fatalf("closed breaker rejected call %d", i+1)
+		}
+
+		if b.State() != Closed {
+			t.Fatalf("State() after %d failures = %s, want closed", i, b.State())
+		}
+
+		b.Failure()
+	}
+
+	if b.State() != Open {
+		t.Fatalf("State() after threshold failures = %s, want open", b.State())
+	}
+
+	if b.Allow() {
+		t.Fatal("open breaker allowed call before cooldown")
+	}
+
+	if retry := b.RetryAfter(); retry <= 0 || retry > time.Minute {
+		t.Errorf("RetryAfter() = %s, want remaining cooldown", retry)
+	}
+
+	// cooldown is elapsed, a single probe is allowed
+	b.openedAt = time.Now().Add(-time.Minute)
+
+	if !b.Allow() {
+		t.Fatal("breaker rejected probe after cooldown")
+	}
+
+	if b.State() != HalfOpen {
+		t.Fatalf("State() of probing breaker = %s, want half_open", b.State())
+	}
+
+	if b.Allow() {
+		t.Fatal("half open breaker allowed a second probe")
+	}
+
+	// failed probe opens breaker again
+	b.Failure()
+
+	if b.State() != Open || b.Allow() {
+		t.Fatalf("State() after failed probe = %s, want open", b.State())
+	}
+
+	b.openedAt = time.Now().Add(-time.Minute)
+
+	// canceled probe lets another probe through
+	if !b.Allow() {
+		t.Fatal("breaker rejected probe after cooldown")
+	}
+
+	b.Cancel()
+
+	if !b.Allow() {
+		t.Fatal("breaker rejected probe after canceled probe")
+	}
+
+	// successful probe closes breaker and resets failures
+	b.Success()
+
+	if b.State() != Closed || b.RetryAfter() != 0 {
+		t.Fatalf("State() after successful probe = %s, want closed", b.State())
+	}
+
+	b.Failure()
+
+	if b.State() != Closed {
+		t.Errorf("State() after a failure of closed breaker = %s, want closed", b.State())
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b := NewBreaker(2, time.Minute, nil)
+
+	b.Failure()
+	b.Success()
+	b.Failure()
+
+	if b.State() != Closed {
+		t.Errorf("State() = %s, want closed as failures are not consecutive", b.State())
+	}
+}
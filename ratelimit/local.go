@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is interval of removing expired counters of local limiter.
+const sweepInterval time.Duration = time.Minute
+
+type (
+	// localLimiter is in-process Limiter, counters are not shared between api server instances.
+	localLimiter struct {
+		mu        sync.Mutex
+		windows   map[string]*windowCounter
+		buckets   map[string]*bucket
+		nextSweep time.Time
+	}
+
+	windowCounter struct {
+		index    int64
+		current  int64
+		previous int64
+		expire   time.Time
+	}
+
+	bucket struct {
+		tokens float64
+		ts     int64
+		expire time.Time
+	}
+)
+
+// NewLocalLimiter returns in-process Limiter that implements same algorithms as redis limiter.
+// it is used as fallback when redis is unavailable.
+func NewLocalLimiter() Limiter {
+	return &localLimiter{
+		windows: make(map[string]*windowCounter),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow implements Limiter.
+func (l *localLimiter) Allow(_ context.Context, key string, limit Limit) (*Decision, error) {
+	window := limit.Window.Milliseconds()
+	if limit.Requests < 1 || window < 1 {
+		return nil, fmt.Errorf("ratelimit: invalid limit of %d requests per %s", limit.Requests, limit.Window)
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	switch limit.Algorithm {
+	case "", SlidingWindow:
+		return l.slidingWindow(key, limit, window, now), nil
+
+	case TokenBucket:
+		return l.tokenBucket(key, limit, window, now), nil
+	}
+
+	return nil, fmt.Errorf("ratelimit: unsupported algorithm %q", limit.Algorithm)
+}
+
+func (l *localLimiter) slidingWindow(key string, limit Limit, window int64, now time.Time) *Decision {
+	ms := now.UnixMilli()
+	index := ms / window
+	elapsed := ms - index*window
+
+	c, ok := l.windows[key]
+	if !ok {
+		c = &windowCounter{index: index}
+		l.windows[key] = c
+	}
+
+	switch c.index {
+	case index:
+	case index - 1:
+		c.previous, c.current = c.current, 0
+	default:
+		c.previous, c.current = 0, 0
+	}
+
+	c.index = index
+	c.expire = now.Add(time.Duration(2*window-elapsed) * time.Millisecond)
+
+	d := &Decision{
+		Limit:  limit.Requests,
+		Reset:  time.Duration(window-elapsed) * time.Millisecond,
+		Source: SourceLocal,
+	}
+
+	count := c.previous*(window-elapsed)/window + c.current
+	if count >= limit.Requests {
+		var retry int64
+		if c.current >= limit.Requests {
+			retry = window - elapsed + int64(math.Floor(float64(window)-float64(limit.Requests*window)/float64(c.current))) + 1
+		} else {
+			retry = int64(math.Floor(float64(window-elapsed)-float64((limit.Requests-c.current)*window)/float64(c.previous))) + 1
+		}
+
+		d.RetryAfter = time.Duration(retry) * time.Millisecond
+		return d
+	}
+
+	c.current++
+
+	d.Allowed = true
+	d.Remaining = limit.Requests - count - 1
+
+	return d
+}
+
+func (l *localLimiter) tokenBucket(key string, limit Limit, window int64, now time.Time) *Decision {
+	ms := now.UnixMilli()
+	capacity := float64(limit.Requests)
+	rate := capacity / float64(window)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, ts: ms}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+float64(max(0, ms-b.ts))*rate)
+	b.ts = ms
+	b.expire = now.Add(limit.Window)
+
+	d := &Decision{
+		Limit:  limit.Requests,
+		Source: SourceLocal,
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = time.Duration(math.Ceil((1-b.tokens)/rate)) * time.Millisecond
+	}
+
+	d.Remaining = int64(math.Floor(b.tokens))
+	d.Reset = time.Duration(math.Ceil((capacity-b.tokens)/rate)) * time.Millisecond
+
+	return d
+}
+
+// sweep removes expired counters, it must be called with mu held.
+func (l *localLimiter) sweep(now time.Time) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+
+	for key, c := range l.windows {
+		if now.After(c.expire) {
+			delete(l.windows, key)
+		}
+	}
+
+	for key, b := range l.buckets {
+		if now.After(b.expire) {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.nextSweep = now.Add(sweepInterval)
+}
@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// step is a request of key at ms and its expected decision.
+type step struct {
+	ms        int64
+	allowed   bool
+	remaining int64
+	retry     int64
+	reset     int64
+}
+
+func (s step) check(t *testing.T, d *Decision) {
+	t.Helper()
+
+	want := Decision{
+		Allowed:    s.allowed,
+		Remaining:  s.remaining,
+		RetryAfter: time.Duration(s.retry) * time.Millisecond,
+		Reset:      time.Duration(s.reset) * time.Millisecond,
+	}
+
+	got := Decision{Allowed: d.Allowed, Remaining: d.Remaining, RetryAfter: d.RetryAfter, Reset: d.Reset}
+	if got != want {
+		t.Errorf("request at %dms = %+v, want %+v", s.ms, got, want)
+	}
+}
+
+var slidingWindowSteps = []step{
+	{ms: 0, allowed: true, remaining: 1, reset: 1000},
+	{ms: 1, allowed: true, remaining: 0, reset: 999},
+	{ms: 2, retry: 999, reset: 998},
+	{ms: 999, retry: 2, reset: 1},
+	// previous window is fully weighted at start of window
+	{ms: 1000, retry: 1, reset: 1000},
+	{ms: 1001, allowed: true, remaining: 0, reset: 999},
+	{ms: 1500, retry: 1, reset: 500},
+	{ms: 1501, allowed: true, remaining: 0, reset: 499},
+	// counters of windows before previous window are not counted
+	{ms: 3000, allowed: true, remaining: 1, reset: 1000},
+}
+
+// tokenBucketSteps are requests of bucket of 2 tokens that refills in 1024ms, so a token is refilled every 512ms.
+var tokenBucketSteps = []step{
+	{ms: 0, allowed: true, remaining: 1, reset: 512},
+	{ms: 0, allowed: true, remaining: 0, reset: 1024},
+	{ms: 0, retry: 512, reset: 1024},
+	{ms: 256, retry: 256, reset: 768},
+	{ms: 512, allowed: true, remaining: 0, reset: 1024},
+	// bucket is not refilled above its capacity
+	{ms: 5000, allowed: true, remaining: 1, reset: 512},
+}
+
+func TestLocalSlidingWindow(t *testing.T) {
+	l := NewLocalLimiter().(*localLimiter)
+	limit := Limit{Requests: 2, Window: time.Second}
+
+	for _, s := range slidingWindowSteps {
+		s.check(t, l.slidingWindow("key", limit, 1000, time.UnixMilli(s.ms)))
+	}
+}
+
+func TestLocalTokenBucket(t *testing.T) {
+	l := NewLocalLimiter().(*localLimiter)
+	limit := Limit{Algorithm: TokenBucket, Requests: 2, Window: 1024 * time.Millisecond}
+
+	for _, s := range tokenBucketSteps {
+		s.check(t, l.tokenBucket("key", limit, 1024, time.UnixMilli(s.ms)))
+	}
+}
+
+// TestLocalMatchesScripts runs same requests against lua scripts of redis limiter and local limiter.
+func TestLocalMatchesScripts(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	ctx := context.Background()
+
+	t.Run("sliding window", func(t *testing.T) {
+		limit := Limit{Requests: 2, Window: time.Second}
+
+		for _, s := range slidingWindowSteps {
+			index := s.ms / 1000
+			keys := []string{fmt.Sprintf("{key}:%d", index), fmt.Sprintf("{key}:%d", index-1)}
+
+			res, err := slidingWindowScript.Run(ctx, client, keys, limit.Requests, 1000, s.ms-index*1000).Slice()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := decision(limit, res)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s.check(t, d)
+		}
+	})
+
+	t.Run("token bucket", func(t *testing.T) {
+		limit := Limit{Algorithm: TokenBucket, Requests: 2, Window: 1024 * time.Millisecond}
+
+		for _, s := range tokenBucketSteps {
+			res, err := tokenBucketScript.Run(ctx, client, []string{"bucket"}, limit.Requests, 1024, s.ms).Slice()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := decision(limit, res)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s.check(t, d)
+		}
+	})
+}
+
+func TestLocalSweep(t *testing.T) {
+	l := NewLocalLimiter().(*localLimiter)
+	start := time.Now()
+
+	l.sweep(start)
+	l.slidingWindow("window", Limit{Requests: 1, Window: time.Second}, 1000, start)
+	l.tokenBucket("bucket", Limit{Requests: 1, Window: time.Second}, 1000, start)
+
+	// counters are kept until next sweep even if they are expired
+	l.sweep(start.Add(sweepInterval / 2))
+	if len(l.windows) != 1 || len(l.buckets) != 1 {
+		t.Fatalf("counters are removed before sweep interval, %d windows and %d buckets remain", len(l.windows), len(l.buckets))
+	}
+
+	// counters that are used recently are not removed
+	now := start.Add(sweepInterval)
+	l.slidingWindow("recent", Limit{Requests: 1, Window: time.Second}, 1000, now)
+
+	l.sweep(now)
+	if _, ok := l.windows["recent"]; !ok || len(l.windows) != 1 || len(l.buckets) != 0 {
+		t.Errorf("sweep() kept %d windows and %d buckets, want only recent window", len(l.windows), len(l.buckets))
+	}
+}
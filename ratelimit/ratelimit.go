@@ -0,0 +1,73 @@
+// Package ratelimit provides request rate limiters that are shared between api server instances.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLimitExceeded is returned to clients that exceeded their request quota.
+var ErrLimitExceeded = errors.New("rate limit exceeded")
+
+// Algorithm is the strategy that a limiter counts requests with.
+type Algorithm string
+
+const (
+	// SlidingWindow weights counter of previous window by its overlap with sliding window,
+	// it smooths bursts at window boundaries of fixed window counters.
+	SlidingWindow Algorithm = "sliding_window"
+
+	// TokenBucket refills bucket of Requests tokens over Window and allows bursts up to bucket size.
+	TokenBucket Algorithm = "token_bucket"
+)
+
+// Source is the limiter that made a Decision.
+type Source string
+
+const (
+	// SourceRedis decisions are made by shared redis counters.
+	SourceRedis Source = "redis"
+
+	// SourceLocal decisions are made by in-process counters while redis is unavailable.
+	SourceLocal Source = "local"
+
+	// SourceFailOpen decisions allow requests without counting them while redis is unavailable.
+	SourceFailOpen Source = "fail_open"
+)
+
+type (
+	// Limit is the quota of requests that allowed for a key.
+	Limit struct {
+		Algorithm Algorithm
+		Requests  int64
+		Window    time.Duration
+	}
+
+	// Decision is result of checking a request against its Limit.
+	Decision struct {
+		// Allowed reports whether request is within quota.
+		Allowed bool
+
+		// Limit is number of requests that allowed in window.
+		Limit int64
+
+		// Remaining is number of requests that are still allowed in current window.
+		Remaining int64
+
+		// Reset is time until quota is fully restored.
+		Reset time.Duration
+
+		// RetryAfter is time until next request is allowed, it is zero for allowed requests.
+		RetryAfter time.Duration
+
+		// Source is the limiter that made decision.
+		Source Source
+	}
+
+	// Limiter checks requests of keys against their limits.
+	Limiter interface {
+		// Allow records a request for key and reports whether it is within limit.
+		Allow(ctx context.Context, key string, limit Limit) (*Decision, error)
+	}
+)
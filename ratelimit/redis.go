@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript counts requests of current and previous fixed windows, previous window is weighted
+// by its overlap with sliding window. request is counted only if it is allowed.
+//
+// KEYS[1] is counter of current window and KEYS[2] is counter of previous window.
+// ARGV are limit, window and elapsed time of current window in milliseconds.
+// it returns allowed, remaining, retry after and reset in milliseconds.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+local count = math.floor(previous * (window - elapsed) / window) + current
+
+if count >= limit then
+	local retry
+	if current >= limit then
+		retry = window - elapsed + math.floor(window - limit * window / current) + 1
+	else
+		retry = math.floor(window - elapsed - (limit - current) * window / previous) + 1
+	end
+
+	return {0, 0, retry, window - elapsed}
+end
+
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], window * 2)
+
+return {1, limit - count - 1, 0, window - elapsed}
+`)
+
+// tokenBucketScript refills bucket continuously by limit tokens per window and takes a token for request.
+//
+// KEYS[1] is hash of bucket state.
+// ARGV are bucket capacity, window and current time in milliseconds.
+// it returns allowed, remaining, retry after and reset in milliseconds.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local rate = capacity / window
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], window)
+
+return {allowed, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}
+`)
+
+type redisLimiter struct {
+	client redis.Scripter
+}
+
+// NewRedisLimiter returns Limiter that keeps counters in redis, every check is a single atomic lua script,
+// so counters never lose their expiration and are consistent between api server instances.
+func NewRedisLimiter(client redis.Scripter) Limiter {
+	return &redisLimiter{
+		client: client,
+	}
+}
+
+// Allow implements Limiter.
+func (l *redisLimiter) Allow(ctx context.Context, key string, limit Limit) (*Decision, error) {
+	window := limit.Window.Milliseconds()
+	if limit.Requests < 1 || window < 1 {
+		return nil, fmt.Errorf("ratelimit: invalid limit of %d requests per %s", limit.Requests, limit.Window)
+	}
+
+	now := time.Now().UnixMilli()
+
+	var (
+		res []interface{}
+		err error
+	)
+
+	switch limit.Algorithm {
+	case "", SlidingWindow:
+		// hash tag keeps counters of key in same slot of redis cluster
+		current := now / window
+		keys := []string{
+			fmt.Sprintf("{%s}:%d", key, current),
+			fmt.Sprintf("{%s}:%d", key, current-1),
+		}
+
+		res, err = slidingWindowScript.Run(ctx, l.client, keys, limit.Requests, window, now-current*window).Slice()
+
+	case TokenBucket:
+		res, err = tokenBucketScript.Run(ctx, l.client, []string{key}, limit.Requests, window, now).Slice()
+
+	default:
+		return nil, fmt.Errorf("ratelimit: unsupported algorithm %q", limit.Algorithm)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decision(limit, res)
+}
+
+// decision converts result of lua scripts to Decision.
+func decision(limit Limit, res []interface{}) (*Decision, error) {
+	if len(res) != 4 {
+		return nil, errors.New("ratelimit: unexpected script result")
+	}
+
+	values := make([]int64, len(res))
+	for i, v := range res {
+		n, ok := v.(int64)
+		if !ok {
+			return nil, errors.New("ratelimit: unexpected script result")
+		}
+
+		values[i] = n
+	}
+
+	return &Decision{
+		Allowed:    values[0] == 1,
+		Limit:      limit.Requests,
+		Remaining:  values[1],
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+		Source:     SourceRedis,
+	}, nil
+}
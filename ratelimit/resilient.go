@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// ErrUnavailable is returned by fail-closed limiter when redis is unavailable.
+var ErrUnavailable = errors.New("rate limit is unavailable")
+
+// FailureMode is behaviour of resilient limiter when redis is unavailable.
+type FailureMode string
+
+const (
+	// FailLocal counts requests by in-process limiter, limits are applied per api server instance.
+	FailLocal FailureMode = "local"
+
+	// FailOpen allows all requests.
+	FailOpen FailureMode = "open"
+
+	// FailClosed rejects all requests.
+	FailClosed FailureMode = "closed"
+)
+
+// resilient limiter defaults.
+const (
+	DefaultTimeout          time.Duration = 100 * time.Millisecond
+	DefaultBreakerThreshold int           = 5
+	DefaultBreakerCooldown  time.Duration = 30 * time.Second
+)
+
+// ParseFailureMode returns FailureMode of name, empty name is FailLocal.
+func ParseFailureMode(name string) (FailureMode, error) {
+	switch FailureMode(name) {
+	case "", FailLocal:
+		return FailLocal, nil
+	case FailOpen:
+		return FailOpen, nil
+	case FailClosed:
+		return FailClosed, nil
+	}
+
+	return "", fmt.Errorf("ratelimit: unsupported failure mode %q", name)
+}
+
+type resilientLimiter struct {
+	primary   Limiter
+	local     Limiter
+	breaker   *Breaker
+	mode      FailureMode
+	timeout   time.Duration
+	fallbacks metrics.Counter
+}
+
+// NewResilientLimiter returns Limiter that calls primary through breaker with timeout, when primary fails or
+// breaker is open decision is made according to mode. fallbacks counts fallback activations by reason and mode.
+func NewResilientLimiter(primary Limiter, breaker *Breaker, mode FailureMode, timeout time.Duration,
+	fallbacks metrics.Counter) Limiter {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &resilientLimiter{
+		primary:   primary,
+		local:     NewLocalLimiter(),
+		breaker:   breaker,
+		mode:      mode,
+		timeout:   timeout,
+		fallbacks: fallbacks,
+	}
+}
+
+// Allow implements Limiter.
+func (l *resilientLimiter) Allow(ctx context.Context, key string, limit Limit) (*Decision, error) {
+	if !l.breaker.Allow() {
+		return l.fallback(ctx, key, limit, "circuit_open")
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	d, err := l.primary.Allow(callCtx, key, limit)
+	if err == nil {
+		l.breaker.Success()
+		return d, nil
+	}
+
+	// request is canceled by client, it is not a failure of primary
+	if ctx.Err() != nil {
+		l.breaker.Cancel()
+		return nil, ctx.Err()
+	}
+
+	l.breaker.Failure()
+
+	return l.fallback(ctx, key, limit, "error")
+}
+
+func (l *resilientLimiter) fallback(ctx context.Context, key string, limit Limit, reason string) (*Decision, error) {
+	if l.fallbacks != nil {
+		l.fallbacks.With("reason", reason, "mode", string(l.mode)).Add(1)
+	}
+
+	switch l.mode {
+	case FailOpen:
+		return &Decision{
+			Allowed:   true,
+			Limit:     limit.Requests,
+			Remaining: limit.Requests,
+			Source:    SourceFailOpen,
+		}, nil
+
+	case FailClosed:
+		return nil, ErrUnavailable
+	}
+
+	return l.local.Allow(ctx, key, limit)
+}
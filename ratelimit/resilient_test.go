@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeLimiter is primary limiter that fails with err or blocks until its context is done.
+type fakeLimiter struct {
+	err   error
+	block bool
+	calls int
+}
+
+func (f *fakeLimiter) Allow(ctx context.Context, _ string, limit Limit) (*Decision, error) {
+	f.calls++
+
+	if f.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &Decision{Allowed: true, Limit: limit.Requests, Remaining: limit.Requests - 1, Source: SourceRedis}, nil
+}
+
+func TestResilientLimiter(t *testing.T) {
+	limit := Limit{Requests: 1, Window: time.Minute}
+	down := errors.New("connection refused")
+
+	tests := []struct {
+		name    string
+		primary *fakeLimiter
+		mode    FailureMode
+		source  Source
+		err     error
+		allowed []bool
+	}{
+		{name: "primary is up", primary: &fakeLimiter{}, mode: FailClosed, source: SourceRedis, allowed: []bool{true, true}},
+		{name: "local on error", primary: &fakeLimiter{err: down}, mode: FailLocal, source: SourceLocal, allowed: []bool{true, false}},
+		{name: "local on timeout", primary: &fakeLimiter{block: true}, mode: FailLocal, source: SourceLocal, allowed: []bool{true, false}},
+		{name: "open on error", primary: &fakeLimiter{err: down}, mode: FailOpen, source: SourceFailOpen, allowed: []bool{true, true}},
+		{name: "open on timeout", primary: &fakeLimiter{block: true}, mode: FailOpen, source: SourceFailOpen, allowed: []bool{true, true}},
+		{name: "closed on error", primary: &fakeLimiter{err: down}, mode: FailClosed, err: ErrUnavailable},
+		{name: "closed on timeout", primary: &fakeLimiter{block: true}, mode: FailClosed, err: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewResilientLimiter(tt.primary, NewBreaker(5, time.Minute, nil), tt.mode, 10*time.Millisecond, nil)
+
+			if tt.err != nil {
+				if _, err := l.Allow(context.Background(), "key", limit); !errors.Is(err, tt.err) {
+					t.Fatalf("Allow() error = %v, want %v", err, tt.err)
+				}
+
+				return
+			}
+
+			for i, want := range tt.allowed {
+				d, err := l.Allow(context.Background(), "key", limit)
+				if err != nil {
+					t.Fatalf("Allow() error = %v", err)
+				}
+
+				if d.Allowed != want || d.Source != tt.source {
+					t.Errorf("request %d: Allow() = %v from %s, want %v from %s", i+1, d.Allowed, d.Source, want, tt.source)
+				}
+			}
+		})
+	}
+}
+
+func TestResilientLimiterBreaker(t *testing.T) {
+	limit := Limit{Requests: 10, Window: time.Minute}
+	primary := &fakeLimiter{err: errors.New("connection refused")}
+	breaker := NewBreaker(2, time.Minute, nil)
+	l := NewResilientLimiter(primary, breaker, FailOpen, 10*time.Millisecond, nil)
+
+	for i := 0; i < 3; i++ {
+		if _, err := l.Allow(context.Background(), "key", limit); err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+	}
+
+	// third request is decided without calling primary as breaker is open
+	if primary.calls != 2 || breaker.State() != Open {
+		t.Fatalf("primary is called %d times with %s breaker, want 2 calls and open breaker", primary.calls, breaker.State())
+	}
+
+	// primary recovers, probe after cooldown closes breaker
+	primary.err = nil
+	breaker.openedAt = time.Now().Add(-time.Minute)
+
+	d, err := l.Allow(context.Background(), "key", limit)
+	if err != nil || d.Source != SourceRedis {
+		t.Fatalf("Allow() after cooldown = %+v, %v, want decision of primary", d, err)
+	}
+
+	if breaker.State() != Closed {
+		t.Errorf("State() after successful probe = %s, want closed", breaker.State())
+	}
+}
+
+func TestResilientLimiterCanceled(t *testing.T) {
+	primary := &fakeLimiter{block: true}
+	breaker := NewBreaker(1, time.Minute, nil)
+	l := NewResilientLimiter(primary, breaker, FailOpen, time.Minute, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// request that client abandoned is not a failure of primary
+	if _, err := l.Allow(ctx, "key", Limit{Requests: 1, Window: time.Minute}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Allow() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	if breaker.State() != Closed {
+		t.Errorf("State() after canceled request = %s, want closed", breaker.State())
+	}
+}
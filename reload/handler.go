@@ -0,0 +1,92 @@
+package reload
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
+	"github.com/ppeymann/top-app.git/auth"
+	"github.com/ppeymann/top-app.git/server"
+)
+
+// ErrReloadFailed is returned for every component that failed to reload, its active version is kept.
+var ErrReloadFailed = otpapp.NewError(api.CodeReloadFailed, http.StatusUnprocessableEntity, "reload is failed, active version is kept")
+
+type handler struct {
+	manager *Manager
+	server  *server.Server
+}
+
+// Status is handler for reporting active configuration and schema versions
+//
+// @BasePath			/api/v1/admin
+// @Summary				config version
+// @Description			get active version of configuration and schemas, it requires PLATFORM_ADMIN role
+// @Tags				admin
+// @Accept				json
+// @Produce				json
+//
+// @Success				200	{object}	otpapp.BaseResult{result=[]reload.Status}
+// @Failure				403	{object}	otpapp.BaseResult	"PERMISSION_DENIED"
+// @Router				/api/v1/admin/config	[get]
+func (h *handler) Status(ctx *gin.Context) {
+	statuses := h.manager.Statuses()
+
+	h.server.Reply(ctx, &otpapp.BaseResult{
+		Status:      http.StatusOK,
+		Result:      statuses,
+		ResultCount: int64(len(statuses)),
+	})
+}
+
+// Reload is handler for reloading configuration and schemas
+//
+// @BasePath			/api/v1/admin
+// @Summary				reload config
+// @Description			reload configuration and schemas, invalid versions are rejected and active versions are kept, it requires PLATFORM_ADMIN role
+// @Tags				admin
+// @Accept				json
+// @Produce				json
+//
+// @Success				200	{object}	otpapp.BaseResult{result=[]reload.Status}
+// @Failure				403	{object}	otpapp.BaseResult	"PERMISSION_DENIED"
+// @Failure				422	{object}	otpapp.BaseResult{result=[]reload.Status}	"RELOAD_FAILED"
+// @Router				/api/v1/admin/config/reload	[post]
+func (h *handler) Reload(ctx *gin.Context) {
+	statuses := h.manager.Reload("admin endpoint")
+
+	var errs []error
+	for _, s := range statuses {
+		if s.Error != "" {
+			errs = append(errs, ErrReloadFailed.Because(errors.New(s.Name+": "+s.Error)))
+		}
+	}
+
+	result := &otpapp.BaseResult{Status: http.StatusOK}
+	if len(errs) > 0 {
+		result = otpapp.NewErrorResult(errs...)
+	}
+
+	// statuses are reported even if reload failed, so failed components are identified
+	result.Result = statuses
+	result.ResultCount = int64(len(statuses))
+
+	h.server.Reply(ctx, result)
+}
+
+// NewHandler registers administration endpoints of manager. configuration is shared by every tenant,
+// so endpoints require PLATFORM_ADMIN role instead of ADMIN role of a tenant.
+func NewHandler(m *Manager, s *server.Server) {
+	h := &handler{
+		manager: m,
+		server:  s,
+	}
+
+	group := s.Router.Group("/api/v1/admin", s.Tenant(), s.Authenticate(), s.Authorize(auth.RolePlatformAdmin))
+	{
+		group.GET("/config", h.Status)
+		group.POST("/config/reload", h.Reload)
+	}
+}
@@ -0,0 +1,260 @@
+// Package reload reloads configuration and schemas of running api server on file changes and SIGHUP.
+package reload
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-kit/kit/metrics"
+	kitlog "github.com/go-kit/log"
+)
+
+// debounce is the delay between a file change and reload, editors write files in several steps.
+const debounce time.Duration = 250 * time.Millisecond
+
+type (
+	// Component is state that reloads from files.
+	Component interface {
+		// Reload loads and validates new state and swaps it atomically, it reports whether state is changed.
+		Reload() (bool, error)
+
+		// Version identifies the active state.
+		Version() string
+
+		// Paths returns files and directories that state is loaded from.
+		Paths() []string
+	}
+
+	// Status is state of a registered component.
+	Status struct {
+		// Name of component.
+		Name string `json:"name"`
+
+		// Version of active state.
+		Version string `json:"version"`
+
+		// ReloadedAt is time of last change of state.
+		ReloadedAt time.Time `json:"reloaded_at"`
+
+		// Error is error of last reload, active state is kept when reload fails.
+		Error string `json:"error,omitempty"`
+	}
+
+	// Manager reloads registered components.
+	Manager struct {
+		logger   kitlog.Logger
+		reloads  metrics.Counter
+		versions metrics.Gauge
+
+		mu         sync.Mutex
+		components []Component
+		statuses   []*Status
+	}
+)
+
+// NewManager returns Manager that counts reloads by component and result in reloads and reports
+// version of active state of components in versions.
+func NewManager(logger kitlog.Logger, reloads metrics.Counter, versions metrics.Gauge) *Manager {
+	return &Manager{
+		logger:   logger,
+		reloads:  reloads,
+		versions: versions,
+	}
+}
+
+// Register adds component that is reloaded by manager.
+func (m *Manager) Register(name string, c Component) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := &Status{
+		Name:       name,
+		Version:    c.Version(),
+		ReloadedAt: time.Now().UTC(),
+	}
+
+	m.components = append(m.components, c)
+	m.statuses = append(m.statuses, status)
+	m.versions.With("component", name, "version", status.Version).Set(1)
+}
+
+// Reload reloads every component and returns their status.
+func (m *Manager) Reload(reason string) []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, c := range m.components {
+		status := m.statuses[i]
+
+		changed, err := c.Reload()
+		if err != nil {
+			status.Error = err.Error()
+			m.reloads.With("component", status.Name, "result", "failure").Add(1)
+			_ = m.logger.Log("component", status.Name, "reason", reason, "err", err)
+
+			continue
+		}
+
+		status.Error = ""
+		if !changed {
+			continue
+		}
+
+		m.versions.With("component", status.Name, "version", status.Version).Set(0)
+
+		status.Version = c.Version()
+		status.ReloadedAt = time.Now().UTC()
+
+		m.versions.With("component", status.Name, "version", status.Version).Set(1)
+		m.reloads.With("component", status.Name, "result", "success").Add(1)
+		_ = m.logger.Log("component", status.Name, "reason", reason, "version", status.Version, "msg", "reloaded")
+	}
+
+	return m.snapshot()
+}
+
+// Statuses returns status of every component.
+func (m *Manager) Statuses() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.snapshot()
+}
+
+func (m *Manager) snapshot() []Status {
+	statuses := make([]Status, 0, len(m.statuses))
+	for _, s := range m.statuses {
+		statuses = append(statuses, *s)
+	}
+
+	return statuses
+}
+
+// Watch reloads components when their files change or process receives SIGHUP, it blocks until ctx is done.
+func (m *Manager) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = watcher.Close()
+	}()
+
+	paths, err := m.watch(watcher)
+	if err != nil {
+		return err
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-hup:
+			m.Reload("SIGHUP")
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if event.Has(fsnotify.Chmod) || !matches(paths, event.Name) {
+				continue
+			}
+
+			timer.Reset(debounce)
+
+		case <-timer.C:
+			m.Reload("file change")
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			_ = m.logger.Log("method", "Watch", "err", err)
+		}
+	}
+}
+
+// watch adds directories of component paths to watcher, directories of files are watched because
+// editors and config management tools replace files instead of writing them.
+func (m *Manager) watch(watcher *fsnotify.Watcher) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var paths []string
+	dirs := map[string]bool{}
+
+	for _, c := range m.components {
+		for _, path := range c.Paths() {
+			path, err := filepath.Abs(path)
+			if err != nil {
+				return nil, err
+			}
+
+			paths = append(paths, path)
+
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+
+			if !info.IsDir() {
+				dirs[filepath.Dir(path)] = true
+				continue
+			}
+
+			err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+				if err == nil && info.IsDir() {
+					dirs[p] = true
+				}
+
+				return err
+			})
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
+
+// matches reports whether changed file is one of paths or is in one of them.
+func matches(paths []string, name string) bool {
+	name, err := filepath.Abs(name)
+	if err != nil {
+		return false
+	}
+
+	for _, path := range paths {
+		if name == path || strings.HasPrefix(name, path+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
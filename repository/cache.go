@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/models"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultCacheTTL is lifetime of cached users.
+const DefaultCacheTTL time.Duration = 5 * time.Minute
+
+// cache lookup results that are counted by metrics.
+const (
+	cacheHit   string = "hit"
+	cacheMiss  string = "miss"
+	cacheError string = "error"
+)
+
+// fillScript caches user if its generation is not changed since user is read from database,
+// so a read that raced with an update never caches the old user.
+//
+// KEYS[1] is user key, KEYS[2] is generation key, ARGV is generation, encoded user and ttl in milliseconds.
+var fillScript = redis.NewScript(`
+if (redis.call('GET', KEYS[2]) or '0') ~= ARGV[1] then
+	return 0
+end
+
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+
+return 1
+`)
+
+// invalidateScript removes cached user and changes its generation, so reads that are in flight do not cache it.
+//
+// KEYS[1] is user key, KEYS[2] is generation key, ARGV[1] is ttl of generation in milliseconds.
+var invalidateScript = redis.NewScript(`
+redis.call('DEL', KEYS[1])
+redis.call('INCR', KEYS[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[1])
+
+return 1
+`)
+
+// cachedUserRepo caches users of next repository in redis by id, mobile numbers are cached as index to id.
+type cachedUserRepo struct {
+	models.UserRepository
+
+	client   redis.Cmdable
+	store    *config.Store
+	group    singleflight.Group
+	requests metrics.Counter
+}
+
+// NewCachedUserRepo returns models.UserRepository that reads users through redis cache, cached users are
+// invalidated when they are updated, concurrent misses of a user are merged into one database query.
+// requests counts cache lookups by "method" and "result" (hit, miss or error) labels.
+// cache failures are not returned to callers, users are read from next repository instead.
+//
+// user_cache options of store are read on every call. users are read from next repository while cache is
+// disabled but updates still invalidate cached users, so enabling cache again does not serve stale users.
+func NewCachedUserRepo(next models.UserRepository, client redis.Cmdable, store *config.Store, requests metrics.Counter) models.UserRepository {
+	return &cachedUserRepo{
+		UserRepository: next,
+		client:         client,
+		store:          store,
+		requests:       requests,
+	}
+}
+
+// Find implements models.UserRepository.
+func (r *cachedUserRepo) Find(ctx context.Context, tenant, mobile string) (*models.UserEntity, error) {
+	ttl, ok := r.ttl()
+	if !ok {
+		return r.UserRepository.Find(ctx, tenant, mobile)
+	}
+
+	index := mobileCacheKey(tenant, mobile)
+
+	id, err := r.client.Get(ctx, index).Uint64()
+	switch {
+	case err == nil:
+		user, err := r.FindByID(ctx, uint(id))
+		if err == nil && user.TenantID == tenant && user.Mobile == mobile {
+			r.count("Find", cacheHit)
+			return user, nil
+		}
+
+		if err != nil && !errors.Is(err, models.ErrAccountNotExist) {
+			return nil, err
+		}
+
+		// index is stale, mobile number of account is changed or account is deleted
+		r.client.Del(ctx, index)
+
+	case !errors.Is(err, redis.Nil):
+		r.count("Find", cacheError)
+		return r.UserRepository.Find(ctx, tenant, mobile)
+	}
+
+	r.count("Find", cacheMiss)
+
+	// load is shared by merged callers, so it is not canceled with the caller that started it
+	load := context.WithoutCancel(ctx)
+
+	v, err, _ := r.group.Do("mobile:"+index, func() (interface{}, error) {
+		user, err := r.UserRepository.Find(load, tenant, mobile)
+		if err != nil {
+			return nil, err
+		}
+
+		r.client.Set(load, index, user.ID, ttl)
+
+		return user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clone(v.(*models.UserEntity)), nil
+}
+
+// FindByID implements models.UserRepository.
+func (r *cachedUserRepo) FindByID(ctx context.Context, id uint) (*models.UserEntity, error) {
+	ttl, ok := r.ttl()
+	if !ok {
+		return r.UserRepository.FindByID(ctx, id)
+	}
+
+	key := userCacheKey(id)
+
+	b, err := r.client.Get(ctx, key).Bytes()
+	if err == nil {
+		user := &models.UserEntity{}
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(user); err == nil {
+			r.count("FindByID", cacheHit)
+			return user, nil
+		}
+	}
+
+	if err != nil && !errors.Is(err, redis.Nil) {
+		r.count("FindByID", cacheError)
+		return r.UserRepository.FindByID(ctx, id)
+	}
+
+	r.count("FindByID", cacheMiss)
+
+	// load is shared by merged callers, so it is not canceled with the caller that started it
+	load := context.WithoutCancel(ctx)
+
+	v, err, _ := r.group.Do(key, func() (interface{}, error) {
+		// generation is read before database, so updates that happen meanwhile prevent caching
+		gen, err := r.client.Get(load, generationKey(key)).Result()
+		if errors.Is(err, redis.Nil) {
+			gen, err = "0", nil
+		}
+
+		user, uErr := r.UserRepository.FindByID(load, id)
+		if uErr != nil || err != nil {
+			return user, uErr
+		}
+
+		buf := &bytes.Buffer{}
+		if gob.NewEncoder(buf).Encode(user) == nil {
+			fillScript.Run(load, r.client, []string{key, generationKey(key)}, gen, buf.Bytes(), ttl.Milliseconds())
+		}
+
+		return user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clone(v.(*models.UserEntity)), nil
+}
+
+// SetOtp implements models.UserRepository.
+func (r *cachedUserRepo) SetOtp(ctx context.Context, id uint, otp string, expire int64) error {
+	err := r.UserRepository.SetOtp(ctx, id, otp, expire)
+	r.invalidate(ctx, id)
+
+	return err
+}
+
+// Update implements models.UserRepository.
+func (r *cachedUserRepo) Update(ctx context.Context, user *models.UserEntity) error {
+	err := r.UserRepository.Update(ctx, user)
+	r.invalidate(ctx, user.ID)
+
+	return err
+}
+
+// invalidate removes cached user, it is called even if update failed because database may have applied it.
+func (r *cachedUserRepo) invalidate(ctx context.Context, id uint) {
+	key := userCacheKey(id)
+	ttl, _ := r.ttl()
+
+	// generation outlives cached users so reads that started before invalidation can not cache stale user
+	err := invalidateScript.Run(context.WithoutCancel(ctx), r.client, []string{key, generationKey(key)}, 2*ttl.Milliseconds()).Err()
+	if err != nil {
+		r.count("invalidate", cacheError)
+	}
+}
+
+// ttl returns lifetime of cached users, it reports false if cache is disabled.
+func (r *cachedUserRepo) ttl() (time.Duration, bool) {
+	conf := r.store.Config().UserCache
+
+	ttl := time.Duration(conf.TTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+
+	return ttl, conf.Enabled
+}
+
+func (r *cachedUserRepo) count(method, result string) {
+	if r.requests != nil {
+		r.requests.With("method", method, "result", result).Add(1)
+	}
+}
+
+// userCacheKey returns cache key of user, hash tag keeps user and its generation in same slot of redis cluster.
+func userCacheKey(id uint) string {
+	return "user_cache:{" + strconv.FormatUint(uint64(id), 10) + "}"
+}
+
+func generationKey(key string) string {
+	return key + ":gen"
+}
+
+func mobileCacheKey(tenant, mobile string) string {
+	return fmt.Sprintf("user_cache:mobile:%s:%s", tenant, mobile)
+}
@@ -0,0 +1,147 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/models"
+	"github.com/ppeymann/top-app.git/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// hookedRepo calls hook of FindByID after user is read from next repository and before it is returned.
+type hookedRepo struct {
+	models.UserRepository
+
+	hook func(ctx context.Context) error
+}
+
+func (r *hookedRepo) FindByID(ctx context.Context, id uint) (*models.UserEntity, error) {
+	user, err := r.UserRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if hook := r.hook; hook != nil {
+		r.hook = nil
+		if err := hook(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// newCachedRepo returns cached repository of next with enabled cache in miniredis.
+func newCachedRepo(t *testing.T, next models.UserRepository) models.UserRepository {
+	t.Helper()
+
+	conf := config.Default()
+	conf.Database.DSN = "otpapp.db"
+	conf.Paseto.SymmetricKey = "0123456789abcdef0123456789abcdef"
+	conf.Jwt = config.Jwt{TokenExpire: 60, RefreshExpire: 120, Issuer: "otpapp.com", Audience: "otpapp.com"}
+	conf.UserCache = config.CacheConfig{Enabled: true, TTLSeconds: 60}
+
+	b, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := config.NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+
+	return repository.NewCachedUserRepo(next, client, store, nil)
+}
+
+func TestCachedUserRepoGeneration(t *testing.T) {
+	ctx := context.Background()
+	next := &hookedRepo{UserRepository: repository.NewMemoryUserRepo()}
+	repo := newCachedRepo(t, next)
+
+	user, err := next.Create(ctx, "default", "09120000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// user is updated after the miss read it from database and before it is cached
+	next.hook = func(ctx context.Context) error {
+		updated := *user
+		updated.Suspended = true
+
+		return repo.Update(ctx, &updated)
+	}
+
+	stale, err := repo.FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+
+	if stale.Suspended {
+		t.Fatal("FindByID() returned user of update that happened after read")
+	}
+
+	found, err := repo.FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+
+	if !found.Suspended {
+		t.Error("FindByID() returned user that is cached by read that raced with update")
+	}
+}
+
+func TestCachedUserRepoMergedLoad(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+
+	next := &hookedRepo{UserRepository: repository.NewMemoryUserRepo()}
+	next.hook = func(ctx context.Context) error {
+		close(started)
+		<-release
+
+		return ctx.Err()
+	}
+
+	repo := newCachedRepo(t, next)
+
+	user, err := next.Create(context.Background(), "default", "09120000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, _ = repo.FindByID(first, user.ID)
+	}()
+
+	<-started
+
+	// second caller is merged into load of first caller that is canceled meanwhile
+	merged := make(chan error)
+	go func() {
+		_, err := repo.FindByID(context.Background(), user.ID)
+		merged <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	close(release)
+
+	if err := <-merged; err != nil {
+		t.Errorf("FindByID() of merged caller error = %v, want user", err)
+	}
+}
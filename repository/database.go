@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"github.com/ppeymann/top-app.git/models"
+	pg "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// database drivers
+const (
+	Postgres string = "postgres"
+	SQLite   string = "sqlite"
+)
+
+// Open opens gorm database of specified driver.
+// for SQLite driver dsn is path of database file, use "file::memory:?cache=shared" for
+// an in-memory database that shared between pool connections.
+func Open(driver, dsn string) (*gorm.DB, error) {
+	conf := &gorm.Config{SkipDefaultTransaction: true}
+
+	switch driver {
+	case Postgres, "":
+		return gorm.Open(pg.Open(dsn), conf)
+	case SQLite:
+		return gorm.Open(sqlite.Open(dsn), conf)
+	}
+
+	return nil, fmt.Errorf("unsupported database driver: %s", driver)
+}
+
+// NewSQLiteUserRepo opens SQLite database at path and returns user repository backed by it.
+// it is intended for tests and local development, so schema is created by AutoMigrate.
+func NewSQLiteUserRepo(path string) (models.UserRepository, error) {
+	db, err := Open(SQLite, path)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := NewUserRepo(db, path)
+	if err := repo.Migrate(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
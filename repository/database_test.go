@@ -0,0 +1,27 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ppeymann/top-app.git/models"
+	"github.com/ppeymann/top-app.git/repository"
+	"github.com/ppeymann/top-app.git/repository/repotest"
+)
+
+func TestSQLiteUserRepo(t *testing.T) {
+	repotest.UserRepository(t, func(t *testing.T) models.UserRepository {
+		repo, err := repository.NewSQLiteUserRepo(filepath.Join(t.TempDir(), "otpapp.db"))
+		if err != nil {
+			t.Fatalf("NewSQLiteUserRepo: %v", err)
+		}
+
+		t.Cleanup(func() {
+			if db, err := repo.Model().DB(); err == nil {
+				_ = db.Close()
+			}
+		})
+
+		return repo
+	})
+}
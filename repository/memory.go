@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ppeymann/top-app.git/models"
+	"github.com/ppeymann/top-app.git/utils"
+	"gorm.io/gorm"
+)
+
+// memoryUserRepo is an in-memory models.UserRepository for tests and local development.
+// entities are copied in and out, so callers never share state with the repository.
+type memoryUserRepo struct {
+	mu       sync.RWMutex
+	seq      uint
+	byID     map[uint]*models.UserEntity
+	byMobile map[string]uint
+}
+
+// mobileKey is key of byMobile index, mobile numbers are unique per tenant.
+func mobileKey(tenant, mobile string) string {
+	return tenant + "\x00" + mobile
+}
+
+// Create implements models.UserRepository.
+func (r *memoryUserRepo) Create(_ context.Context, tenant, mobile string) (*models.UserEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.byMobile[mobileKey(tenant, mobile)]; ok {
+		return nil, models.ErrAccountExist
+	}
+
+	now := time.Now()
+	r.seq++
+
+	user := &models.UserEntity{
+		Model: gorm.Model{
+			ID:        r.seq,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		TenantID:           tenant,
+		Mobile:             mobile,
+		Verification:       utils.RandNumberDigits(6),
+		VerificationExpire: now.Add(models.OtpLifetime).UTC().Unix(),
+	}
+
+	r.byID[user.ID] = user
+	r.byMobile[mobileKey(tenant, mobile)] = user.ID
+
+	return clone(user), nil
+}
+
+// Find implements models.UserRepository.
+func (r *memoryUserRepo) Find(_ context.Context, tenant, mobile string) (*models.UserEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byMobile[mobileKey(tenant, mobile)]
+	if !ok {
+		return nil, models.ErrAccountNotExist
+	}
+
+	return clone(r.byID[id]), nil
+}
+
+// FindAllUser implements models.UserRepository.
+func (r *memoryUserRepo) FindAllUser(_ context.Context, tenant string, page int32, limit int32) ([]models.UserEntity, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 {
+		limit = 2
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]uint, 0, len(r.byID))
+	for id, user := range r.byID {
+		if user.TenantID == tenant {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	offset := int((page - 1) * limit)
+	users := []models.UserEntity{}
+	for i := offset; i < len(ids) && i < offset+int(limit); i++ {
+		users = append(users, *clone(r.byID[ids[i]]))
+	}
+
+	return users, nil
+}
+
+// FindByID implements models.UserRepository.
+func (r *memoryUserRepo) FindByID(_ context.Context, id uint) (*models.UserEntity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.byID[id]
+	if !ok {
+		return nil, models.ErrAccountNotExist
+	}
+
+	return clone(user), nil
+}
+
+// SetOtp implements models.UserRepository.
+func (r *memoryUserRepo) SetOtp(_ context.Context, id uint, otp string, expire int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.byID[id]
+	if !ok {
+		return models.ErrAccountNotExist
+	}
+
+	user.Verification = otp
+	user.VerificationExpire = expire
+	user.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// Update implements models.UserRepository.
+func (r *memoryUserRepo) Update(_ context.Context, user *models.UserEntity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.byID[user.ID]
+	if !ok {
+		return models.ErrAccountNotExist
+	}
+
+	key := mobileKey(user.TenantID, user.Mobile)
+	if id, ok := r.byMobile[key]; ok && id != user.ID {
+		return models.ErrAccountExist
+	}
+
+	delete(r.byMobile, mobileKey(current.TenantID, current.Mobile))
+
+	user.UpdatedAt = time.Now()
+	r.byID[user.ID] = clone(user)
+	r.byMobile[key] = user.ID
+
+	return nil
+}
+
+// Migrate implements models.UserRepository.
+func (r *memoryUserRepo) Migrate() error {
+	return nil
+}
+
+// Model implements models.UserRepository.
+// in-memory repository is not backed by database and always returns nil.
+func (r *memoryUserRepo) Model() *gorm.DB {
+	return nil
+}
+
+// Name implements models.UserRepository.
+func (r *memoryUserRepo) Name() string {
+	return "user_entities"
+}
+
+func clone(user *models.UserEntity) *models.UserEntity {
+	c := *user
+	c.Roles = append(models.Roles(nil), user.Roles...)
+
+	return &c
+}
+
+// NewMemoryUserRepo returns an empty in-memory user repository.
+func NewMemoryUserRepo() models.UserRepository {
+	return &memoryUserRepo{
+		byID:     make(map[uint]*models.UserEntity),
+		byMobile: make(map[string]uint),
+	}
+}
@@ -0,0 +1,15 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/ppeymann/top-app.git/models"
+	"github.com/ppeymann/top-app.git/repository"
+	"github.com/ppeymann/top-app.git/repository/repotest"
+)
+
+func TestMemoryUserRepo(t *testing.T) {
+	repotest.UserRepository(t, func(t *testing.T) models.UserRepository {
+		return repository.NewMemoryUserRepo()
+	})
+}
@@ -0,0 +1,194 @@
+// Package repotest provides conformance suites that every repository implementation must pass.
+//
+// implementations run a suite from their own tests, e.g.
+//
+//	func TestMemoryUserRepo(t *testing.T) {
+//		repotest.UserRepository(t, func(t *testing.T) models.UserRepository {
+//			return repository.NewMemoryUserRepo()
+//		})
+//	}
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ppeymann/top-app.git/models"
+)
+
+// tenant is the tenant that suite accounts belong to.
+const tenant = "acme"
+
+// UserRepository runs models.UserRepository conformance suite.
+// newRepo must return an empty repository on every call.
+func UserRepository(t *testing.T, newRepo func(t *testing.T) models.UserRepository) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+
+		created, err := repo.Create(ctx, tenant, "09120000001")
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		if created.ID == 0 || created.Verification == "" || created.IsVerificationExpired() {
+			t.Fatalf("Create: expected id and valid verification, got %+v", created)
+		}
+
+		found, err := repo.Find(ctx, tenant, "09120000001")
+		if err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+
+		if found.ID != created.ID || found.Verification != created.Verification {
+			t.Fatalf("Find: got %+v, want %+v", found, created)
+		}
+
+		byID, err := repo.FindByID(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+
+		if byID.Mobile != created.Mobile {
+			t.Fatalf("FindByID: got mobile %q, want %q", byID.Mobile, created.Mobile)
+		}
+	})
+
+	t.Run("UniqueMobile", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.Create(ctx, tenant, "09120000002"); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		_, err := repo.Create(ctx, tenant, "09120000002")
+		if !errors.Is(err, models.ErrAccountExist) {
+			t.Fatalf("Create duplicate: got %v, want %v", err, models.ErrAccountExist)
+		}
+	})
+
+	t.Run("UniqueMobilePerTenant", func(t *testing.T) {
+		repo := newRepo(t)
+
+		first, err := repo.Create(ctx, tenant, "09120000005")
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		other, err := repo.Create(ctx, "globex", "09120000005")
+		if err != nil {
+			t.Fatalf("Create in other tenant: %v", err)
+		}
+
+		if first.ID == other.ID || other.TenantID != "globex" {
+			t.Fatalf("Create in other tenant: got %+v", other)
+		}
+
+		found, err := repo.Find(ctx, "globex", "09120000005")
+		if err != nil || found.ID != other.ID {
+			t.Fatalf("Find in other tenant: got %+v, %v", found, err)
+		}
+
+		users, err := repo.FindAllUser(ctx, tenant, 1, 10)
+		if err != nil || len(users) != 1 || users[0].ID != first.ID {
+			t.Fatalf("FindAllUser: expected only accounts of tenant, got %+v, %v", users, err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.Find(ctx, tenant, "09129999999"); !errors.Is(err, models.ErrAccountNotExist) {
+			t.Fatalf("Find: got %v, want %v", err, models.ErrAccountNotExist)
+		}
+
+		if _, err := repo.FindByID(ctx, 4242); !errors.Is(err, models.ErrAccountNotExist) {
+			t.Fatalf("FindByID: got %v, want %v", err, models.ErrAccountNotExist)
+		}
+
+		if err := repo.SetOtp(ctx, 4242, "123456", time.Now().Unix()); !errors.Is(err, models.ErrAccountNotExist) {
+			t.Fatalf("SetOtp: got %v, want %v", err, models.ErrAccountNotExist)
+		}
+	})
+
+	t.Run("SetOtp", func(t *testing.T) {
+		repo := newRepo(t)
+
+		user, err := repo.Create(ctx, tenant, "09120000003")
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		exp := time.Now().Add(time.Minute).UTC().Unix()
+		if err := repo.SetOtp(ctx, user.ID, "654321", exp); err != nil {
+			t.Fatalf("SetOtp: %v", err)
+		}
+
+		found, err := repo.FindByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+
+		if found.Verification != "654321" || found.VerificationExpire != exp {
+			t.Fatalf("SetOtp: got %q/%d, want %q/%d", found.Verification, found.VerificationExpire, "654321", exp)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+
+		user, err := repo.Create(ctx, tenant, "09120000004")
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		user.Roles = models.Roles{"USER", "ADMIN"}
+		user.Suspended = true
+		if err := repo.Update(ctx, user); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		found, err := repo.Find(ctx, tenant, "09120000004")
+		if err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+
+		if !found.Suspended || len(found.Roles) != 2 || found.Roles[1] != "ADMIN" {
+			t.Fatalf("Update: changes not persisted, got %+v", found)
+		}
+	})
+
+	t.Run("FindAllUser", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i := 0; i < 5; i++ {
+			if _, err := repo.Create(ctx, tenant, fmt.Sprintf("0912100000%d", i)); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		first, err := repo.FindAllUser(ctx, tenant, 1, 2)
+		if err != nil {
+			t.Fatalf("FindAllUser: %v", err)
+		}
+
+		last, err := repo.FindAllUser(ctx, tenant, 3, 2)
+		if err != nil {
+			t.Fatalf("FindAllUser: %v", err)
+		}
+
+		if len(first) != 2 || len(last) != 1 {
+			t.Fatalf("FindAllUser: got pages of %d and %d users, want 2 and 1", len(first), len(last))
+		}
+
+		if first[0].ID >= first[1].ID || first[1].ID >= last[0].ID {
+			t.Fatalf("FindAllUser: users are not ordered by id")
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ppeymann/top-app.git/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type riskRepo struct {
+	pg *gorm.DB
+}
+
+// FindDevice implements models.RiskRepository.
+func (r *riskRepo) FindDevice(ctx context.Context, userID uint, fingerprint string) (*models.DeviceEntity, error) {
+	device := &models.DeviceEntity{}
+	err := r.pg.WithContext(ctx).Where("user_id = ? AND fingerprint = ?", userID, fingerprint).First(device).Error
+	if err != nil {
+		return nil, r.translate(err)
+	}
+
+	return device, nil
+}
+
+// LastDevice implements models.RiskRepository.
+func (r *riskRepo) LastDevice(ctx context.Context, userID uint) (*models.DeviceEntity, error) {
+	device := &models.DeviceEntity{}
+	err := r.pg.WithContext(ctx).Where("user_id = ?", userID).Order("last_seen_at DESC").First(device).Error
+	if err != nil {
+		return nil, r.translate(err)
+	}
+
+	return device, nil
+}
+
+// SaveDevice implements models.RiskRepository.
+func (r *riskRepo) SaveDevice(ctx context.Context, device *models.DeviceEntity) error {
+	return r.pg.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "fingerprint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "name", "last_ip", "last_asn", "last_seen_at", "deleted_at"}),
+	}).Create(device).Error
+}
+
+// TrustDevice implements models.RiskRepository.
+func (r *riskRepo) TrustDevice(ctx context.Context, device *models.DeviceEntity) error {
+	return r.pg.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "fingerprint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "name", "last_ip", "last_asn", "last_seen_at", "deleted_at",
+			"token_hash", "trusted_until"}),
+	}).Create(device).Error
+}
+
+// TrustedDevices implements models.RiskRepository.
+func (r *riskRepo) TrustedDevices(ctx context.Context, userID uint) ([]models.DeviceEntity, error) {
+	var devices []models.DeviceEntity
+	err := r.pg.WithContext(ctx).Where("user_id = ? AND trusted_until > ?", userID, time.Now().UTC()).
+		Order("last_seen_at DESC").Find(&devices).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// RevokeDevice implements models.RiskRepository.
+func (r *riskRepo) RevokeDevice(ctx context.Context, userID, id uint) error {
+	res := r.pg.WithContext(ctx).Model(&models.DeviceEntity{}).
+		Where("id = ? AND user_id = ? AND trusted_until IS NOT NULL", id, userID).
+		Updates(map[string]interface{}{"token_hash": "", "trusted_until": nil})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return models.ErrDeviceNotExist
+	}
+
+	return nil
+}
+
+// RecordDecision implements models.RiskRepository.
+func (r *riskRepo) RecordDecision(ctx context.Context, decision *models.RiskDecisionEntity) error {
+	return r.pg.WithContext(ctx).Create(decision).Error
+}
+
+// FindDecisions implements models.RiskRepository.
+func (r *riskRepo) FindDecisions(ctx context.Context, tenant string, userID uint, page, limit int32) ([]models.RiskDecisionEntity, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 {
+		limit = 20
+	}
+
+	query := r.pg.WithContext(ctx).Where("tenant_id = ?", tenant)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var decisions []models.RiskDecisionEntity
+	err := query.Order("id DESC").Limit(int(limit)).Offset(int((page - 1) * limit)).Find(&decisions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return decisions, nil
+}
+
+// Migrate implements models.RiskRepository.
+func (r *riskRepo) Migrate() error {
+	return r.pg.AutoMigrate(models.DeviceEntity{}, models.RiskDecisionEntity{})
+}
+
+// Model implements models.RiskRepository.
+func (r *riskRepo) Model() *gorm.DB {
+	return r.pg.Model(&models.RiskDecisionEntity{})
+}
+
+// Name implements models.RiskRepository.
+func (r *riskRepo) Name() string {
+	return "risk_decision_entities"
+}
+
+func (r *riskRepo) translate(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ErrDeviceNotExist
+	}
+
+	return err
+}
+
+func NewRiskRepo(pg *gorm.DB) models.RiskRepository {
+	return &riskRepo{
+		pg: pg,
+	}
+}
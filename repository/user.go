@@ -1,10 +1,10 @@
 package repository
 
 import (
-	"strings"
+	"context"
+	"errors"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/ppeymann/top-app.git/models"
 	"github.com/ppeymann/top-app.git/utils"
 	"gorm.io/gorm"
@@ -17,41 +17,34 @@ type userRepo struct {
 }
 
 // Create implements models.UserRepository.
-func (r *userRepo) Create(mobile string) (*models.UserEntity, error) {
+func (r *userRepo) Create(ctx context.Context, tenant, mobile string) (*models.UserEntity, error) {
 	code := utils.RandNumberDigits(6)
 
 	user := &models.UserEntity{
 		Model:              gorm.Model{},
+		TenantID:           tenant,
 		Mobile:             mobile,
 		Verification:       code,
-		VerificationExpire: time.Now().Add(180 * time.Second).UTC().Unix(),
+		VerificationExpire: time.Now().Add(models.OtpLifetime).UTC().Unix(),
 	}
 
-	err := r.pg.Transaction(func(tx *gorm.DB) error {
-		if res := r.Model().Create(user).Error; res != nil {
-			str := res.(*pgconn.PgError).Message
-			if strings.Contains(str, "duplicate key value") {
-				return models.ErrAccountExist
-			}
-
-			return res
-		}
-		return nil
+	err := r.pg.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Model(&models.UserEntity{}).Create(user).Error
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, r.translate(err)
 	}
 
 	return user, nil
 }
 
 // Find implements models.UserRepository.
-func (r *userRepo) Find(mobile string) (*models.UserEntity, error) {
+func (r *userRepo) Find(ctx context.Context, tenant, mobile string) (*models.UserEntity, error) {
 	user := &models.UserEntity{}
-	err := r.Model().Where("mobile = ?", mobile).First(user).Error
+	err := r.Model().WithContext(ctx).Where("tenant_id = ? AND mobile = ?", tenant, mobile).First(user).Error
 	if err != nil {
-		return nil, err
+		return nil, r.translate(err)
 	}
 
 	return user, nil
@@ -59,7 +52,7 @@ func (r *userRepo) Find(mobile string) (*models.UserEntity, error) {
 }
 
 // FindAllUser implements models.UserRepository.
-func (r *userRepo) FindAllUser(page int32, limit int32) ([]models.UserEntity, error) {
+func (r *userRepo) FindAllUser(ctx context.Context, tenant string, page int32, limit int32) ([]models.UserEntity, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -67,33 +60,30 @@ func (r *userRepo) FindAllUser(page int32, limit int32) ([]models.UserEntity, er
 	if limit < 1 {
 		limit = 2
 	}
-	var totalRows int64
-	r.Model().Count(&totalRows)
-
 	offset := (page - 1) * limit
 	var users []models.UserEntity
-	err := r.pg.Order("id ASC").Limit(int(limit)).Offset(int(offset)).Find(&users).Error
+	err := r.pg.WithContext(ctx).Where("tenant_id = ?", tenant).Order("id ASC").Limit(int(limit)).Offset(int(offset)).Find(&users).Error
 	if err != nil {
-		return nil, err
+		return nil, r.translate(err)
 	}
 
 	return users, nil
 }
 
 // FindByID implements models.UserRepository.
-func (r *userRepo) FindByID(id uint) (*models.UserEntity, error) {
+func (r *userRepo) FindByID(ctx context.Context, id uint) (*models.UserEntity, error) {
 	user := &models.UserEntity{}
-	err := r.Model().Where("id = ?", id).First(user).Error
+	err := r.Model().WithContext(ctx).Where("id = ?", id).First(user).Error
 	if err != nil {
-		return nil, err
+		return nil, r.translate(err)
 	}
 
 	return user, nil
 }
 
 // SetOtp implements models.UserRepository.
-func (r *userRepo) SetOtp(id uint, otp string, expire int64) error {
-	user, err := r.FindByID(id)
+func (r *userRepo) SetOtp(ctx context.Context, id uint, otp string, expire int64) error {
+	user, err := r.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -101,12 +91,12 @@ func (r *userRepo) SetOtp(id uint, otp string, expire int64) error {
 	user.Verification = otp
 	user.VerificationExpire = expire
 
-	return r.Update(user)
+	return r.Update(ctx, user)
 }
 
 // Update implements models.UserRepository.
-func (r *userRepo) Update(user *models.UserEntity) error {
-	return r.pg.Save(user).Error
+func (r *userRepo) Update(ctx context.Context, user *models.UserEntity) error {
+	return r.translate(r.pg.WithContext(ctx).Save(user).Error)
 }
 
 // Migrate implements models.UserRepository.
@@ -124,6 +114,27 @@ func (r *userRepo) Name() string {
 	return r.table
 }
 
+// translate maps database driver errors to models errors, so repository
+// behaves the same on every gorm dialect.
+func (r *userRepo) translate(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if t, ok := r.pg.Dialector.(gorm.ErrorTranslator); ok {
+		err = t.Translate(err)
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return models.ErrAccountExist
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return models.ErrAccountNotExist
+	}
+
+	return err
+}
+
 func NewUserRepo(pg *gorm.DB, database string) models.UserRepository {
 	return &userRepo{
 		pg:       pg,
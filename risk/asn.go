@@ -0,0 +1,98 @@
+package risk
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// ASNResolver returns autonomous system number of ip addresses.
+	ASNResolver interface {
+		// Lookup returns ASN of ip, it reports false if ip is not in any announced range.
+		Lookup(ip netip.Addr) (uint32, bool)
+	}
+
+	asnRange struct {
+		start netip.Addr
+		end   netip.Addr
+		asn   uint32
+	}
+
+	asnTable []asnRange
+)
+
+// LoadASNDatabase loads ip to ASN table of path, it is tab separated ranges in ip2asn format
+// (https://iptoasn.com), e.g. "1.0.0.0	1.0.0.255	13335	US	CLOUDFLARENET". ranges with ASN zero are not announced.
+func LoadASNDatabase(path string) (ASNResolver, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var table asnTable
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		start, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("risk: %s:%d: %w", path, line, err)
+		}
+
+		end, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("risk: %s:%d: %w", path, line, err)
+		}
+
+		asn, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("risk: %s:%d: invalid ASN %q", path, line, fields[2])
+		}
+
+		if asn != 0 {
+			table = append(table, asnRange{start: start.Unmap(), end: end.Unmap(), asn: uint32(asn)})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(table, func(i, j int) bool {
+		return table[i].start.Less(table[j].start)
+	})
+
+	return table, nil
+}
+
+// Lookup implements ASNResolver.
+func (t asnTable) Lookup(ip netip.Addr) (uint32, bool) {
+	ip = ip.Unmap()
+
+	// first range that starts after ip, ip can only be in the range before it
+	i := sort.Search(len(t), func(i int) bool {
+		return ip.Less(t[i].start)
+	})
+
+	if i == 0 {
+		return 0, false
+	}
+
+	r := t[i-1]
+	if ip.Compare(r.end) > 0 || ip.Is4() != r.start.Is4() {
+		return 0, false
+	}
+
+	return r.asn, true
+}
@@ -0,0 +1,122 @@
+package risk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mssola/user_agent"
+	"github.com/ppeymann/top-app.git/api"
+)
+
+// DeviceIDHeader is the request header that apps send their stable device identifier in.
+const DeviceIDHeader string = api.DeviceIDHeader
+
+// maxDeviceIDLength is the maximum length of device identifiers, longer identifiers are ignored.
+const maxDeviceIDLength int = 128
+
+type (
+	deviceKey struct{}
+
+	// Device is the client that request is sent from.
+	Device struct {
+		// ID is identifier that app provided in X-Device-ID header.
+		ID string `json:"id,omitempty"`
+
+		UserAgent      string `json:"user_agent,omitempty"`
+		Browser        string `json:"browser,omitempty"`
+		BrowserVersion string `json:"browser_version,omitempty"`
+		OS             string `json:"os,omitempty"`
+		Platform       string `json:"platform,omitempty"`
+		Model          string `json:"model,omitempty"`
+		Mobile         bool   `json:"mobile"`
+
+		// IP is client ip of request and ASN is autonomous system number of IP, it is zero if unknown.
+		IP  string `json:"ip"`
+		ASN uint32 `json:"asn,omitempty"`
+	}
+)
+
+// NewDevice returns device of request headers and client ip, User-Agent is parsed and user agent
+// client hints (Sec-CH-UA-*) take precedence over it when they are sent.
+func NewDevice(h http.Header, ip string) *Device {
+	ua := user_agent.New(h.Get("User-Agent"))
+	browser, version := ua.Browser()
+
+	d := &Device{
+		UserAgent:      ua.UA(),
+		Browser:        browser,
+		BrowserVersion: version,
+		OS:             ua.OSInfo().Name,
+		Platform:       ua.Platform(),
+		Model:          ua.Model(),
+		Mobile:         ua.Mobile(),
+		IP:             ip,
+	}
+
+	if id := strings.TrimSpace(h.Get(DeviceIDHeader)); len(id) <= maxDeviceIDLength {
+		d.ID = id
+	}
+
+	if platform := hint(h, "Sec-CH-UA-Platform"); platform != "" {
+		d.OS = platform
+	}
+
+	if model := hint(h, "Sec-CH-UA-Model"); model != "" {
+		d.Model = model
+	}
+
+	if mobile := hint(h, "Sec-CH-UA-Mobile"); mobile != "" {
+		d.Mobile = mobile == "?1"
+	}
+
+	return d
+}
+
+// Fingerprint returns stable hash of device, it is hash of app provided identifier if device has one,
+// otherwise it is hash of browser, operating system and model that do not change with their updates.
+func (d *Device) Fingerprint() string {
+	src := "id:" + d.ID
+	if d.ID == "" {
+		src = strings.Join([]string{"ua", d.Browser, d.OS, d.Platform, d.Model, fmt.Sprint(d.Mobile)}, "\x00")
+	}
+
+	sum := sha256.Sum256([]byte(src))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Name describes device for people, e.g. "Chrome 120 on Android".
+func (d *Device) Name() string {
+	name := d.Browser
+	if major, _, _ := strings.Cut(d.BrowserVersion, "."); major != "" {
+		name += " " + major
+	}
+
+	if d.Model != "" {
+		name += " on " + d.Model
+	} else if d.OS != "" {
+		name += " on " + d.OS
+	}
+
+	return strings.TrimSpace(name)
+}
+
+// NewContext returns a copy of parent that carries device of request.
+func NewContext(parent context.Context, d *Device) context.Context {
+	return context.WithValue(parent, deviceKey{}, d)
+}
+
+// FromContext returns device of request that carried by ctx.
+func FromContext(ctx context.Context) (*Device, bool) {
+	d, ok := ctx.Value(deviceKey{}).(*Device)
+	return d, ok
+}
+
+// hint returns value of client hint header without quotes.
+func hint(h http.Header, name string) string {
+	return strings.Trim(strings.TrimSpace(h.Get(name)), `"`)
+}
@@ -0,0 +1,58 @@
+package risk
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDeviceFingerprint(t *testing.T) {
+	chrome := func(version string) http.Header {
+		h := http.Header{}
+		h.Set("User-Agent", "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/"+version+" Mobile Safari/537.36")
+		return h
+	}
+
+	withID := func(h http.Header, id string) http.Header {
+		h.Set(DeviceIDHeader, id)
+		return h
+	}
+
+	tests := []struct {
+		name string
+		a, b *Device
+		same bool
+	}{
+		{
+			name: "browser update",
+			a:    NewDevice(chrome("119.0.0.0"), "10.0.0.1"),
+			b:    NewDevice(chrome("120.0.0.0"), "10.0.0.2"),
+			same: true,
+		},
+		{
+			name: "device id wins over user agent",
+			a:    NewDevice(withID(chrome("119.0.0.0"), "app-1"), "10.0.0.1"),
+			b:    NewDevice(withID(http.Header{}, "app-1"), "10.0.0.1"),
+			same: true,
+		},
+		{
+			name: "different device ids",
+			a:    NewDevice(withID(chrome("120.0.0.0"), "app-1"), "10.0.0.1"),
+			b:    NewDevice(withID(chrome("120.0.0.0"), "app-2"), "10.0.0.1"),
+			same: false,
+		},
+		{
+			name: "device id and user agent",
+			a:    NewDevice(withID(chrome("120.0.0.0"), "app-1"), "10.0.0.1"),
+			b:    NewDevice(chrome("120.0.0.0"), "10.0.0.1"),
+			same: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Fingerprint() == tt.b.Fingerprint(); got != tt.same {
+				t.Errorf("Fingerprint() equal = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+// Package risk scores sign in attempts by their device, network and velocity, configurable rules
+// decide to allow attempt, require an extra factor for it or block it.
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync/atomic"
+	"time"
+
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
+	"github.com/ppeymann/top-app.git/models"
+)
+
+// Event is the operation that is evaluated.
+type Event string
+
+// evaluated events.
+const (
+	Login     Event = "login"
+	OtpVerify Event = "otp_verify"
+)
+
+// Action is the decision of an evaluation.
+type Action string
+
+// actions in order of severity.
+const (
+	Allow     Action = "allow"
+	Challenge Action = "challenge"
+	Block     Action = "block"
+)
+
+// signals of attempts that rules match, boolean signals are 1 if they are true.
+const (
+	// SignalNewDevice is true if account signed in before but never from device of attempt.
+	SignalNewDevice string = "new_device"
+
+	// SignalIPChanged is true if network of attempt, /24 of IPv4 and /48 of IPv6, differs from last sign in of account.
+	SignalIPChanged string = "ip_changed"
+
+	// SignalASNChanged is true if autonomous system of attempt differs from last sign in of account.
+	SignalASNChanged string = "asn_changed"
+
+	// SignalUserVelocity, SignalIPVelocity and SignalDeviceVelocity are number of attempts of event
+	// by account, client ip and device in velocity window.
+	SignalUserVelocity   string = "user_velocity"
+	SignalIPVelocity     string = "ip_velocity"
+	SignalDeviceVelocity string = "device_velocity"
+)
+
+// DefaultVelocityWindow is window of velocity signals.
+const DefaultVelocityWindow time.Duration = time.Hour
+
+var (
+	// ErrBlocked is returned for attempts that risk policy blocks.
+	ErrBlocked = otpapp.NewError(api.CodeRiskBlocked, http.StatusForbidden, "sign in is blocked by risk policy")
+
+	// ErrFactorRequired is returned for challenged attempts that did not pass an extra factor.
+	ErrFactorRequired = otpapp.NewError(api.CodeExtraFactorRequired, http.StatusUnauthorized, "an extra verification factor is required")
+)
+
+type (
+	factorKey struct{}
+
+	// Rule matches a signal of attempts, matched rules add their score to score of attempt and
+	// their action, if specified, is the least severe action of attempt.
+	Rule struct {
+		Name   string
+		Signal string
+
+		// Threshold is the minimum value of signal that matches rule, default is 1.
+		Threshold int64
+
+		Score  int
+		Action Action
+	}
+
+	// Policy is the rules of evaluation, attempts whose score reaches ChallengeScore or BlockScore are
+	// challenged or blocked, zero scores are disabled.
+	Policy struct {
+		Rules          []Rule
+		ChallengeScore int
+		BlockScore     int
+		VelocityWindow time.Duration
+	}
+
+	// Decision is the result of an evaluation.
+	Decision struct {
+		Action  Action
+		Score   int
+		Reasons []string
+		Signals map[string]int64
+	}
+
+	// Engine evaluates attempts against policy and records decisions.
+	Engine struct {
+		repo     models.RiskRepository
+		velocity Velocity
+		settings atomic.Pointer[settings]
+	}
+
+	// settings are policy and ASN resolver of engine, they are replaced together when configuration is reloaded.
+	settings struct {
+		asn    ASNResolver
+		policy Policy
+	}
+)
+
+// NewEngine returns Engine of policy, asn may be nil that ASN of attempts is unknown and asn_changed never matches.
+func NewEngine(repo models.RiskRepository, velocity Velocity, asn ASNResolver, policy Policy) *Engine {
+	e := &Engine{
+		repo:     repo,
+		velocity: velocity,
+	}
+
+	e.Update(asn, policy)
+
+	return e
+}
+
+// Update replaces policy and ASN resolver of engine, attempts that are being evaluated keep previous ones.
+func (e *Engine) Update(asn ASNResolver, policy Policy) {
+	if policy.VelocityWindow <= 0 {
+		policy.VelocityWindow = DefaultVelocityWindow
+	}
+
+	e.settings.Store(&settings{asn: asn, policy: policy})
+}
+
+// Evaluate scores attempt of user for event, device of attempt is carried by ctx. decision is recorded for review.
+func (e *Engine) Evaluate(ctx context.Context, event Event, tenantID string, user *models.UserEntity) (*Decision, error) {
+	st := e.settings.Load()
+	device := st.device(ctx)
+	fingerprint := device.Fingerprint()
+
+	signals, err := e.signals(ctx, st.policy, event, tenantID, user, device, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	d := st.policy.decide(signals)
+
+	err = e.repo.RecordDecision(ctx, &models.RiskDecisionEntity{
+		TenantID:    tenantID,
+		UserID:      user.ID,
+		Event:       string(event),
+		Action:      string(d.Action),
+		Score:       d.Score,
+		Reasons:     d.Reasons,
+		Fingerprint: fingerprint,
+		Device:      device.Name(),
+		IP:          device.IP,
+		ASN:         device.ASN,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Remember saves device of ctx as a known device of user, it must be called after user signed in.
+func (e *Engine) Remember(ctx context.Context, tenantID string, user *models.UserEntity) error {
+	device := e.settings.Load().device(ctx)
+
+	return e.repo.SaveDevice(ctx, &models.DeviceEntity{
+		TenantID:    tenantID,
+		UserID:      user.ID,
+		Fingerprint: device.Fingerprint(),
+		Name:        device.Name(),
+		LastIP:      device.IP,
+		LastASN:     device.ASN,
+		LastSeenAt:  time.Now().UTC(),
+	})
+}
+
+// device returns device of ctx with its ASN.
+func (s *settings) device(ctx context.Context) *Device {
+	d, ok := FromContext(ctx)
+	if !ok {
+		return &Device{}
+	}
+
+	if ip, err := netip.ParseAddr(d.IP); err == nil && s.asn != nil && d.ASN == 0 {
+		if asn, ok := s.asn.Lookup(ip); ok {
+			d.ASN = asn
+		}
+	}
+
+	return d
+}
+
+func (e *Engine) signals(ctx context.Context, policy Policy, event Event, tenantID string, user *models.UserEntity,
+	device *Device, fingerprint string) (map[string]int64, error) {
+	signals := map[string]int64{}
+
+	last, err := e.repo.LastDevice(ctx, user.ID)
+	switch {
+	case err == nil:
+		if _, err := e.repo.FindDevice(ctx, user.ID, fingerprint); errors.Is(err, models.ErrDeviceNotExist) {
+			signals[SignalNewDevice] = 1
+		} else if err != nil {
+			return nil, err
+		}
+
+		if network(last.LastIP) != network(device.IP) {
+			signals[SignalIPChanged] = 1
+		}
+
+		if last.LastASN != 0 && device.ASN != 0 && last.LastASN != device.ASN {
+			signals[SignalASNChanged] = 1
+		}
+
+	case !errors.Is(err, models.ErrDeviceNotExist):
+		return nil, err
+	}
+
+	counters := map[string]string{
+		SignalUserVelocity:   fmt.Sprintf("user:%s:%d", tenantID, user.ID),
+		SignalIPVelocity:     "ip:" + device.IP,
+		SignalDeviceVelocity: "device:" + fingerprint,
+	}
+
+	for signal, key := range counters {
+		count, err := e.velocity.Add(ctx, string(event)+":"+key, policy.VelocityWindow)
+		if err != nil {
+			return nil, err
+		}
+
+		signals[signal] = count
+	}
+
+	return signals, nil
+}
+
+// decide applies rules of policy to signals.
+func (p Policy) decide(signals map[string]int64) *Decision {
+	d := &Decision{
+		Action:  Allow,
+		Reasons: []string{},
+		Signals: signals,
+	}
+
+	for _, rule := range p.Rules {
+		threshold := rule.Threshold
+		if threshold < 1 {
+			threshold = 1
+		}
+
+		if signals[rule.Signal] < threshold {
+			continue
+		}
+
+		d.Score += rule.Score
+		d.Reasons = append(d.Reasons, rule.Name)
+		d.Action = severest(d.Action, rule.Action)
+	}
+
+	if p.ChallengeScore > 0 && d.Score >= p.ChallengeScore {
+		d.Action = severest(d.Action, Challenge)
+	}
+
+	if p.BlockScore > 0 && d.Score >= p.BlockScore {
+		d.Action = Block
+	}
+
+	return d
+}
+
+// WithFactor returns a copy of ctx that marks request as verified by an extra factor, middlewares of
+// extra factors call it so challenged attempts of request are allowed.
+func WithFactor(ctx context.Context) context.Context {
+	return context.WithValue(ctx, factorKey{}, true)
+}
+
+// HasFactor reports whether request of ctx is verified by an extra factor.
+func HasFactor(ctx context.Context) bool {
+	ok, _ := ctx.Value(factorKey{}).(bool)
+	return ok
+}
+
+// severest returns more severe of actions.
+func severest(a, b Action) Action {
+	severity := map[Action]int{Allow: 0, Challenge: 1, Block: 2}
+	if severity[b] > severity[a] {
+		return b
+	}
+
+	return a
+}
+
+// network returns /24 network of IPv4 and /48 network of IPv6 addresses.
+func network(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+
+	addr = addr.Unmap()
+
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+
+	prefix, _ := addr.Prefix(bits)
+
+	return prefix.String()
+}
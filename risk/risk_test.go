@@ -0,0 +1,273 @@
+package risk
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ppeymann/top-app.git/models"
+)
+
+func TestPolicyDecide(t *testing.T) {
+	rules := []Rule{
+		{Name: "new device", Signal: SignalNewDevice, Score: 30},
+		{Name: "ip changed", Signal: SignalIPChanged, Score: 20},
+		{Name: "user velocity", Signal: SignalUserVelocity, Threshold: 5, Score: 40},
+		{Name: "ip velocity", Signal: SignalIPVelocity, Threshold: 20, Action: Block},
+		{Name: "asn changed", Signal: SignalASNChanged, Action: Challenge},
+	}
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		signals map[string]int64
+		action  Action
+		score   int
+		reasons []string
+	}{
+		{
+			name:    "no signal",
+			policy:  Policy{Rules: rules, ChallengeScore: 50, BlockScore: 80},
+			signals: map[string]int64{SignalUserVelocity: 1, SignalIPVelocity: 1},
+			action:  Allow,
+			reasons: []string{},
+		},
+		{
+			name:    "threshold defaults to one",
+			policy:  Policy{Rules: rules, ChallengeScore: 50, BlockScore: 80},
+			signals: map[string]int64{SignalNewDevice: 1},
+			action:  Allow,
+			score:   30,
+			reasons: []string{"new device"},
+		},
+		{
+			name:    "below threshold",
+			policy:  Policy{Rules: rules, ChallengeScore: 50, BlockScore: 80},
+			signals: map[string]int64{SignalUserVelocity: 4},
+			action:  Allow,
+			reasons: []string{},
+		},
+		{
+			name:    "score reaches challenge score",
+			policy:  Policy{Rules: rules, ChallengeScore: 50, BlockScore: 80},
+			signals: map[string]int64{SignalNewDevice: 1, SignalIPChanged: 1},
+			action:  Challenge,
+			score:   50,
+			reasons: []string{"new device", "ip changed"},
+		},
+		{
+			name:    "score reaches block score",
+			policy:  Policy{Rules: rules, ChallengeScore: 50, BlockScore: 80},
+			signals: map[string]int64{SignalNewDevice: 1, SignalIPChanged: 1, SignalUserVelocity: 5},
+			action:  Block,
+			score:   90,
+			reasons: []string{"new device", "ip changed", "user velocity"},
+		},
+		{
+			name:    "rule action is more severe than score",
+			policy:  Policy{Rules: rules, ChallengeScore: 50, BlockScore: 80},
+			signals: map[string]int64{SignalIPVelocity: 20},
+			action:  Block,
+			reasons: []string{"ip velocity"},
+		},
+		{
+			name:    "score is more severe than rule action",
+			policy:  Policy{Rules: rules, ChallengeScore: 50, BlockScore: 80},
+			signals: map[string]int64{SignalASNChanged: 1, SignalNewDevice: 1, SignalIPChanged: 1, SignalUserVelocity: 5},
+			action:  Block,
+			score:   90,
+			reasons: []string{"new device", "ip changed", "user velocity", "asn changed"},
+		},
+		{
+			name:    "zero scores are disabled",
+			policy:  Policy{Rules: rules},
+			signals: map[string]int64{SignalNewDevice: 1, SignalIPChanged: 1, SignalUserVelocity: 5},
+			action:  Allow,
+			score:   90,
+			reasons: []string{"new device", "ip changed", "user velocity"},
+		},
+		{
+			name:    "zero block score only challenges",
+			policy:  Policy{Rules: rules, ChallengeScore: 50},
+			signals: map[string]int64{SignalNewDevice: 1, SignalIPChanged: 1, SignalUserVelocity: 5},
+			action:  Challenge,
+			score:   90,
+			reasons: []string{"new device", "ip changed", "user velocity"},
+		},
+		{
+			name:    "zero challenge score does not challenge",
+			policy:  Policy{Rules: rules, BlockScore: 80},
+			signals: map[string]int64{SignalNewDevice: 1, SignalIPChanged: 1},
+			action:  Allow,
+			score:   50,
+			reasons: []string{"new device", "ip changed"},
+		},
+		{
+			name:    "zero challenge score still blocks",
+			policy:  Policy{Rules: rules, BlockScore: 80},
+			signals: map[string]int64{SignalNewDevice: 1, SignalIPChanged: 1, SignalUserVelocity: 5},
+			action:  Block,
+			score:   90,
+			reasons: []string{"new device", "ip changed", "user velocity"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.policy.decide(tt.signals)
+
+			if d.Action != tt.action || d.Score != tt.score || !reflect.DeepEqual(d.Reasons, tt.reasons) {
+				t.Errorf("decide() = %s %d %v, want %s %d %v", d.Action, d.Score, d.Reasons, tt.action, tt.score, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{a: "192.168.1.10", b: "192.168.1.250", same: true},
+		{a: "192.168.1.10", b: "192.168.2.10", same: false},
+		{a: "::ffff:192.168.1.10", b: "192.168.1.20", same: true},
+		{a: "2001:db8:aa::1", b: "2001:db8:aa:ffff::1", same: true},
+		{a: "2001:db8:aa::1", b: "2001:db8:ab::1", same: false},
+		{a: "invalid", b: "invalid", same: true},
+		{a: "", b: "10.0.0.1", same: false},
+	}
+
+	for _, tt := range tests {
+		if got := network(tt.a) == network(tt.b); got != tt.same {
+			t.Errorf("network(%q) == network(%q) is %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+}
+
+// fakeRepo is RiskRepository of known devices of an account.
+type fakeRepo struct {
+	models.RiskRepository
+
+	devices   []models.DeviceEntity
+	decisions []models.RiskDecisionEntity
+}
+
+func (r *fakeRepo) FindDevice(_ context.Context, userID uint, fingerprint string) (*models.DeviceEntity, error) {
+	for i := range r.devices {
+		if r.devices[i].UserID == userID && r.devices[i].Fingerprint == fingerprint {
+			return &r.devices[i], nil
+		}
+	}
+
+	return nil, models.ErrDeviceNotExist
+}
+
+func (r *fakeRepo) LastDevice(_ context.Context, userID uint) (*models.DeviceEntity, error) {
+	for i := len(r.devices) - 1; i >= 0; i-- {
+		if r.devices[i].UserID == userID {
+			return &r.devices[i], nil
+		}
+	}
+
+	return nil, models.ErrDeviceNotExist
+}
+
+func (r *fakeRepo) RecordDecision(_ context.Context, decision *models.RiskDecisionEntity) error {
+	r.decisions = append(r.decisions, *decision)
+	return nil
+}
+
+// fakeVelocity counts events of keys without windows.
+type fakeVelocity map[string]int64
+
+func (v fakeVelocity) Add(_ context.Context, key string, _ time.Duration) (int64, error) {
+	v[key]++
+	return v[key], nil
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	known := &Device{ID: "phone", IP: "10.0.0.1"}
+	user := &models.UserEntity{Mobile: "09120000001"}
+	user.ID = 7
+
+	policy := Policy{
+		Rules: []Rule{
+			{Name: "new device", Signal: SignalNewDevice, Score: 40},
+			{Name: "ip changed", Signal: SignalIPChanged, Score: 20},
+			{Name: "user velocity", Signal: SignalUserVelocity, Threshold: 3, Action: Block},
+		},
+		ChallengeScore: 50,
+	}
+
+	tests := []struct {
+		name    string
+		devices []models.DeviceEntity
+		device  *Device
+		action  Action
+		reasons []string
+	}{
+		{
+			name:    "first device of account",
+			device:  known,
+			action:  Allow,
+			reasons: []string{},
+		},
+		{
+			name:    "known device",
+			devices: []models.DeviceEntity{{UserID: 7, Fingerprint: known.Fingerprint(), LastIP: "10.0.0.9"}},
+			device:  known,
+			action:  Allow,
+			reasons: []string{},
+		},
+		{
+			name:    "new device on another network",
+			devices: []models.DeviceEntity{{UserID: 7, Fingerprint: known.Fingerprint(), LastIP: "10.0.0.9"}},
+			device:  &Device{ID: "tablet", IP: "10.0.1.1"},
+			action:  Challenge,
+			reasons: []string{"new device", "ip changed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{devices: tt.devices}
+			engine := NewEngine(repo, fakeVelocity{}, nil, policy)
+
+			d, err := engine.Evaluate(NewContext(context.Background(), tt.device), Login, "default", user)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+
+			if d.Action != tt.action || !reflect.DeepEqual(d.Reasons, tt.reasons) {
+				t.Errorf("Evaluate() = %s %v, want %s %v", d.Action, d.Reasons, tt.action, tt.reasons)
+			}
+
+			if len(repo.decisions) != 1 || repo.decisions[0].Fingerprint != tt.device.Fingerprint() || repo.decisions[0].UserID != 7 {
+				t.Errorf("Evaluate() recorded %+v, want decision of device", repo.decisions)
+			}
+		})
+	}
+}
+
+func TestEngineEvaluateVelocity(t *testing.T) {
+	user := &models.UserEntity{}
+	user.ID = 7
+
+	engine := NewEngine(&fakeRepo{}, fakeVelocity{}, nil, Policy{
+		Rules: []Rule{{Name: "user velocity", Signal: SignalUserVelocity, Threshold: 3, Action: Block}},
+	})
+
+	ctx := NewContext(context.Background(), &Device{ID: "phone", IP: "10.0.0.1"})
+
+	for i, want := range []Action{Allow, Allow, Block} {
+		d, err := engine.Evaluate(ctx, Login, "default", user)
+		if err != nil {
+			t.Fatalf("Evaluate() error = %v", err)
+		}
+
+		if d.Action != want {
+			t.Errorf("attempt %d: Evaluate().Action = %s, want %s", i+1, d.Action, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package risk
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Velocity counts events of keys in fixed windows.
+type Velocity interface {
+	// Add counts an event of key and returns number of events of key in current window.
+	Add(ctx context.Context, key string, window time.Duration) (int64, error)
+}
+
+// velocityScript increments counter of key and starts its window on first event.
+//
+// KEYS[1] is counter key, ARGV[1] is window in milliseconds.
+var velocityScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+
+return count
+`)
+
+type redisVelocity struct {
+	client redis.Scripter
+}
+
+// NewRedisVelocity returns Velocity that keeps counters in redis, so they are shared between api server instances.
+func NewRedisVelocity(client redis.Scripter) Velocity {
+	return &redisVelocity{
+		client: client,
+	}
+}
+
+// Add implements Velocity.
+func (v *redisVelocity) Add(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return velocityScript.Run(ctx, v.client, []string{"risk_velocity:" + key}, window.Milliseconds()).Int64()
+}
@@ -3,11 +3,14 @@ package server
 import (
 	"errors"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/auth"
+	"github.com/ppeymann/top-app.git/i18n"
+	"github.com/ppeymann/top-app.git/tenant"
 	"github.com/ppeymann/top-app.git/utils"
 )
 
@@ -19,37 +22,51 @@ func (s *Server) pasetoAuth() gin.HandlerFunc {
 
 		// abort request if Authenticate header is empty or not provided.
 		if len(ah) == 0 {
-			_ = ctx.AbortWithError(http.StatusUnauthorized, errors.New("authorization header is not provided"))
+			s.Abort(ctx, otpapp.ErrUnAuthorization.Because(errors.New("authorization header is not provided")))
 			return
 		}
 
 		// Bearer token format validation
 		fields := strings.Fields(ah)
 		if len(fields) != 2 {
-			_ = ctx.AbortWithError(http.StatusUnauthorized, errors.New("invalid Authorization header format"))
+			s.Abort(ctx, otpapp.ErrUnAuthorization.Because(errors.New("invalid Authorization header format")))
 			return
 		}
 
 		at := strings.ToLower(fields[0])
 		if at != "bearer" {
-			_ = ctx.AbortWithError(http.StatusUnauthorized,
-				fmt.Errorf("unsupported Authenticate format : %s", fields[0]))
+			s.Abort(ctx, otpapp.ErrUnAuthorization.Because(fmt.Errorf("unsupported Authenticate format : %s", fields[0])))
 			return
 		}
 
 		token := fields[1]
 		claims, err := s.paseto.VerifyToken(token)
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusUnauthorized, err)
+			s.Abort(ctx, otpapp.ErrUnAuthorization.Because(err))
+			return
+		}
+
+		if claims.IsRefresh() {
+			s.Abort(ctx, otpapp.ErrUnAuthorization.Because(errors.New("refresh token can not be used for authorization")))
 			return
 		}
 
 		if claims.ExpiredAt.Before(time.Now().UTC()) {
-			_ = ctx.AbortWithError(http.StatusUnauthorized, errors.New("authorization token is expired"))
+			s.Abort(ctx, otpapp.ErrUnAuthorization.Because(errors.New("authorization token is expired")))
 			return
 		}
 
+		// tokens are only valid for tenant, issuer and audience that issued them
+		t, ok := tenant.FromContext(ctx.Request.Context())
+		if !ok || !t.Issued(claims) {
+			s.Abort(ctx, otpapp.ErrUnAuthorization.Because(errors.New("authorization token is issued for another tenant, issuer or audience")))
+			return
+		}
+
+		// expose claims to gin handlers and carry them as principal of the request context,
+		// saved locale of account is used if client does not ask for a language.
 		ctx.Set(utils.ContextUserKey, claims)
+		ctx.Request = ctx.Request.WithContext(i18n.Prefer(auth.NewContext(ctx.Request.Context(), claims), claims.Locale))
 		ctx.Next()
 	}
 }
@@ -59,3 +76,17 @@ func (s *Server) Authenticate() gin.HandlerFunc {
 
 	return s.pasetoAuth()
 }
+
+// Authorize is http middleware that rejects requests of accounts that have none of roles,
+// it must be used after Authenticate middleware.
+func (s *Server) Authorize(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, ok := auth.FromContext(ctx.Request.Context())
+		if !ok || !claims.HasRole(roles...) {
+			s.Abort(ctx, otpapp.ErrPermissionDenied)
+			return
+		}
+
+		ctx.Next()
+	}
+}
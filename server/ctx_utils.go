@@ -1,12 +1,31 @@
 package server
 
 import (
+	"bytes"
 	"errors"
+	"io"
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
+// MaxBodySize is the maximum size of request bodies that middlewares read before handlers.
+const MaxBodySize int64 = 1 << 20
+
+// peekBody reads body of request up to MaxBodySize and replaces it, so handlers can read it again.
+func peekBody(ctx *gin.Context) ([]byte, error) {
+	if ctx.Request.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, MaxBodySize))
+	_ = ctx.Request.Body.Close()
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, err
+}
+
 func GetPathSize(ctx *gin.Context) int {
 	s, ok := ctx.Params.Get("size")
 	if !ok {
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	otpapp "github.com/ppeymann/top-app.git"
+)
+
+// ETag returns strong entity tag of v, it is hash of json representation of v so it changes whenever
+// any field of representation changes, e.g. UpdatedAt of entities.
+func ETag(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// ReplyConditional writes result like Reply, successful results carry ETag of their Result and requests
+// that their If-None-Match matches it are answered with 304 Not Modified without body.
+func (s *Server) ReplyConditional(ctx *gin.Context, result *otpapp.BaseResult) {
+	if len(result.Errors) > 0 || result.Result == nil {
+		s.Reply(ctx, result)
+		return
+	}
+
+	etag, err := ETag(result.Result)
+	if err != nil {
+		s.Reply(ctx, result)
+		return
+	}
+
+	// clients must revalidate representation of their own resources on every use
+	ctx.Header("Cache-Control", "private, no-cache")
+
+	if s.NotModified(ctx, etag) {
+		return
+	}
+
+	s.Reply(ctx, result)
+}
+
+// NotModified sets ETag header of response and reports whether If-None-Match of request matches etag,
+// matched GET and HEAD requests are answered with 304 Not Modified and other methods with 412 Precondition Failed.
+func (s *Server) NotModified(ctx *gin.Context, etag string) bool {
+	ctx.Header("ETag", etag)
+
+	header := ctx.GetHeader("If-None-Match")
+	if header == "" || !matchETag(header, etag, true) {
+		return false
+	}
+
+	if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+		s.Abort(ctx, otpapp.ErrPreconditionFailed)
+		return true
+	}
+
+	ctx.AbortWithStatus(http.StatusNotModified)
+
+	return true
+}
+
+// Precondition enforces If-Match of update requests against etag of current representation of resource,
+// so clients can not overwrite changes that they have not seen. request is aborted with 428 Precondition Required
+// if header is missing or with 412 Precondition Failed if it does not match, it reports whether request can proceed.
+func (s *Server) Precondition(ctx *gin.Context, etag string) bool {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		s.Abort(ctx, otpapp.ErrPreconditionRequired)
+		return false
+	}
+
+	if !matchETag(header, etag, false) {
+		s.Abort(ctx, otpapp.ErrPreconditionFailed)
+		return false
+	}
+
+	return true
+}
+
+// matchETag reports whether etag is in comma separated list of entity tags or list is "*".
+// weak comparison ignores W/ prefix of tags, strong comparison never matches weak tags.
+func matchETag(list, etag string, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	}
+
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+
+		if tag == etag && !strings.HasPrefix(tag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
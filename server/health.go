@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/health"
+	"github.com/ppeymann/top-app.git/ratelimit"
+)
+
+// probe endpoints, they are not rate limited.
+const (
+	HealthPath string = "/healthz"
+	ReadyPath  string = "/readyz"
+)
+
+// healthz is liveness probe handler, it reports that process is alive and serving http.
+func (s *Server) healthz() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, otpapp.BaseResult{
+			Result: gin.H{"status": "alive"},
+		})
+	}
+}
+
+// readyz is readiness probe handler, it reports result of dependency checks and responds
+// 503 if any dependency is down or server is shutting down.
+func (s *Server) readyz() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		report := s.Health.Ready(ctx.Request.Context())
+
+		result := otpapp.BaseResult{
+			Result: report,
+		}
+
+		status := http.StatusOK
+		if report.Status != health.StatusReady {
+			status = http.StatusServiceUnavailable
+			result.Errors = []string{"server is not ready"}
+		}
+
+		ctx.JSON(status, result)
+	}
+}
+
+// registerChecks registers checks of server dependencies. redis is optional because rate limits, idempotency
+// and risk evaluation fail open or fall back when it is unavailable, unless rate limit is configured to fail closed.
+func (s *Server) registerChecks() {
+	register := s.Health.RegisterOptional
+	if mode, _ := ratelimit.ParseFailureMode(s.Config.Config().RateLimit.Resilience.FailureMode); mode == ratelimit.FailClosed {
+		register = s.Health.Register
+	}
+
+	register("redis", 0, func(ctx context.Context) error {
+		return s.redis.Ping(ctx).Err()
+	})
+
+	s.Health.Register("otp", 0, s.Tenants.Check)
+}
@@ -11,8 +11,8 @@ func (s *Server) cors() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"POST", "GET", "PATCH", "OPTION", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authenticate", "Authorization", "X-Requested-With", "Accept", "Accept-Encoding"},
-		ExposeHeaders:    []string{"Origin"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authenticate", "Authorization", "X-Requested-With", "Accept", "Accept-Encoding", "X-Tenant", "X-Request-ID", "Idempotency-Key", "If-Match", "If-None-Match", "X-Device-ID", "X-PoW-Challenge", "X-PoW-Nonce"},
+		ExposeHeaders:    []string{"Origin", "X-Request-ID", "Idempotent-Replayed", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ppeymann/top-app.git/i18n"
+)
+
+// language is global http middleware that negotiates language of request from Accept-Language header,
+// fallback language of configuration is used if client does not ask for a supported language.
+// saved locale of account is preferred to fallback after request is authenticated.
+func (s *Server) language() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		lang, ok := i18n.Negotiate(ctx.GetHeader("Accept-Language"))
+		if !ok {
+			lang = s.Config.Config().I18n.FallbackLanguage
+			if !i18n.IsSupported(lang) {
+				lang = i18n.English
+			}
+		}
+
+		ctx.Request = ctx.Request.WithContext(i18n.NewContext(ctx.Request.Context(), lang, ok))
+		ctx.Next()
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/auth"
+	"github.com/ppeymann/top-app.git/idempotency"
+	"github.com/ppeymann/top-app.git/logging"
+	"github.com/ppeymann/top-app.git/tenant"
+)
+
+// responseRecorder keeps a copy of response body that is written to client.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotent returns middleware that makes route idempotent for requests with Idempotency-Key header,
+// response of first request is stored and replayed for retries of it, reusing key for a different request
+// or sending it while first request is in flight is rejected. Requests without the header are not affected.
+func (s *Server) Idempotent() gin.HandlerFunc {
+	return s.IdempotentFor(0)
+}
+
+// IdempotentFor returns Idempotent middleware that keeps responses at most for maxTTL, zero does not cap
+// ttl of configuration. routes that respond with tokens or one time passwords cap it to lifetime of
+// one time passwords so their responses are not replayable after it.
+func (s *Server) IdempotentFor(maxTTL time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		conf := s.Config.Config().Idempotency
+		key := ctx.GetHeader(idempotency.Header)
+
+		if !conf.Enabled || key == "" || s.idempotency == nil {
+			ctx.Next()
+			return
+		}
+
+		if !idempotency.ValidKey(key) {
+			s.Abort(ctx, idempotency.ErrInvalidKey)
+			return
+		}
+
+		body, err := peekBody(ctx)
+		if err != nil {
+			s.Abort(ctx, otpapp.ErrInvalidBody.Because(err))
+			return
+		}
+
+		ttl, lockTTL := time.Duration(conf.TTLSeconds)*time.Second, time.Duration(conf.LockSeconds)*time.Second
+		if ttl <= 0 {
+			ttl = idempotency.DefaultTTL
+		}
+
+		if maxTTL > 0 && ttl > maxTTL {
+			ttl = maxTTL
+		}
+
+		if lockTTL <= 0 {
+			lockTTL = idempotency.DefaultLockTTL
+		}
+
+		var tenantID string
+		if t, ok := tenant.FromContext(ctx.Request.Context()); ok {
+			tenantID = t.ID
+		}
+
+		route := ctx.FullPath()
+		storeKey := idempotency.StoreKey(tenantID, route, idempotencySubject(ctx), key)
+		fingerprint := idempotency.Fingerprint(ctx.Request.Method, route, body)
+		logger := logging.FromContext(ctx.Request.Context())
+
+		owner, rec, err := s.idempotency.Lock(ctx.Request.Context(), storeKey, fingerprint, lockTTL)
+		if err != nil {
+			// requests are still served while redis is unavailable, only without replay protection
+			_ = logger.Log("method", "Idempotent", "route", route, "err", err)
+			ctx.Next()
+			return
+		}
+
+		if rec != nil {
+			switch {
+			case rec.Fingerprint != fingerprint:
+				s.Abort(ctx, idempotency.ErrKeyReused)
+
+			case !rec.Completed:
+				ctx.Header("Retry-After", strconv.Itoa(1))
+				s.Abort(ctx, idempotency.ErrInFlight)
+
+			default:
+				ctx.Header(idempotency.ReplayedHeader, "true")
+				ctx.Data(rec.Status, rec.ContentType, rec.Body)
+				ctx.Abort()
+			}
+
+			return
+		}
+
+		saved := false
+		defer func() {
+			// key of failed or panicked requests is released so client can retry them
+			if !saved {
+				if err := s.idempotency.Release(context.WithoutCancel(ctx.Request.Context()), storeKey, owner); err != nil {
+					_ = logger.Log("method", "Idempotent", "route", route, "err", err)
+				}
+			}
+		}()
+
+		w := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = w
+
+		ctx.Next()
+
+		if w.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		err = s.idempotency.Save(context.WithoutCancel(ctx.Request.Context()), storeKey, owner, &idempotency.Record{
+			Fingerprint: fingerprint,
+			Status:      w.Status(),
+			ContentType: w.Header().Get("Content-Type"),
+			Body:        w.body.Bytes(),
+		}, ttl)
+		if err != nil {
+			_ = logger.Log("method", "Idempotent", "route", route, "err", err)
+			return
+		}
+
+		saved = true
+	}
+}
+
+// idempotencySubject returns subject that idempotency keys of request are scoped to, it is subject of
+// authorization token, hash of mobile of request body for unauthenticated requests or client ip.
+func idempotencySubject(ctx *gin.Context) string {
+	if claims, ok := auth.FromContext(ctx.Request.Context()); ok {
+		return fmt.Sprintf("%d", claims.Subject)
+	}
+
+	if mobile := requestMobile(ctx); mobile != "" {
+		sum := sha256.Sum256([]byte(mobile))
+		return "mobile:" + hex.EncodeToString(sum[:])
+	}
+
+	return "ip:" + ctx.ClientIP()
+}
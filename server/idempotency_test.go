@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ppeymann/top-app.git/api"
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/idempotency"
+)
+
+// memoryStore is in-process idempotency.Store.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*idempotency.Record
+	owners  map[string]string
+	seq     int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		records: make(map[string]*idempotency.Record),
+		owners:  make(map[string]string),
+	}
+}
+
+func (m *memoryStore) Lock(_ context.Context, key, fingerprint string, _ time.Duration) (string, *idempotency.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rec, ok := m.records[key]; ok {
+		c := *rec
+		return "", &c, nil
+	}
+
+	m.seq++
+	owner := strconv.Itoa(m.seq)
+
+	m.records[key] = &idempotency.Record{Fingerprint: fingerprint}
+	m.owners[key] = owner
+
+	return owner, nil, nil
+}
+
+func (m *memoryStore) Save(_ context.Context, key, owner string, rec *idempotency.Record, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.owners[key] != owner {
+		return idempotency.ErrNotOwner
+	}
+
+	c := *rec
+	c.Completed = true
+	m.records[key] = &c
+
+	return nil
+}
+
+func (m *memoryStore) Release(_ context.Context, key, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.owners[key] == owner && !m.records[key].Completed {
+		delete(m.records, key)
+		delete(m.owners, key)
+	}
+
+	return nil
+}
+
+// newTestStore returns configuration store of a valid configuration that modify changes.
+func newTestStore(t *testing.T, modify func(conf *config.Configuration)) *config.Store {
+	t.Helper()
+
+	conf := config.Default()
+	conf.Database.DSN = "otpapp.db"
+	conf.Paseto.SymmetricKey = "0123456789abcdef0123456789abcdef"
+	conf.Jwt = config.Jwt{TokenExpire: 60, RefreshExpire: 120, Issuer: "otpapp.com", Audience: "otpapp.com"}
+	modify(conf)
+
+	b, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := config.NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+// idempotentRouter returns router of an idempotent route that responds with status and func that returns
+// number of calls of its handler, requests wait for release if it is not nil.
+func idempotentRouter(t *testing.T, status int, release chan struct{}) (*gin.Engine, func() int) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	s := &Server{
+		Config: newTestStore(t, func(conf *config.Configuration) {
+			conf.Idempotency.Enabled = true
+		}),
+		idempotency: newMemoryStore(),
+	}
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+
+	router := gin.New()
+	router.POST("/signup", s.Idempotent(), func(ctx *gin.Context) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+
+		if release != nil {
+			<-release
+		}
+
+		ctx.JSON(status, gin.H{"call": n})
+	})
+
+	return router, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return calls
+	}
+}
+
+func postIdempotent(router http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
+	req.Header.Set(api.IdempotencyKeyHeader, key)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	router, calls := idempotentRouter(t, http.StatusCreated, nil)
+
+	first := postIdempotent(router, "key-1", `{"mobile":"09120000001"}`)
+	second := postIdempotent(router, "key-1", `{"mobile":"09120000001"}`)
+
+	if calls() != 1 {
+		t.Fatalf("handler is called %d times, want 1", calls())
+	}
+
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("replayed response = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+
+	if second.Header().Get(api.IdempotentReplayedHeader) != "true" || first.Header().Get(api.IdempotentReplayedHeader) != "" {
+		t.Errorf("%s header is not set only on replayed response", api.IdempotentReplayedHeader)
+	}
+}
+
+func TestIdempotentKeyReused(t *testing.T) {
+	router, calls := idempotentRouter(t, http.StatusCreated, nil)
+
+	// keys of unauthenticated requests are scoped to their mobile, so key is reused by the same mobile
+	postIdempotent(router, "key-1", `{"mobile":"09120000001"}`)
+	w := postIdempotent(router, "key-1", `{"mobile":"09120000001","trust_device":true}`)
+
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), api.CodeIdempotencyKeyReused) {
+		t.Errorf("response = %d %s, want 422 %s", w.Code, w.Body, api.CodeIdempotencyKeyReused)
+	}
+
+	if calls() != 1 {
+		t.Errorf("handler is called %d times, want 1", calls())
+	}
+}
+
+func TestIdempotentInFlight(t *testing.T) {
+	release := make(chan struct{})
+	router, calls := idempotentRouter(t, http.StatusCreated, release)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- postIdempotent(router, "key-1", `{"mobile":"09120000001"}`)
+	}()
+
+	// wait until first request holds lock of key and runs handler
+	for i := 0; calls() == 0; i++ {
+		if i == 100 {
+			t.Fatal("first request did not reach handler")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	w := postIdempotent(router, "key-1", `{"mobile":"09120000001"}`)
+	close(release)
+
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" || !strings.Contains(w.Body.String(), api.CodeIdempotencyInFlight) {
+		t.Errorf("response = %d %s, want 409 %s with Retry-After", w.Code, w.Body, api.CodeIdempotencyInFlight)
+	}
+
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Errorf("first response = %d, want 201", first.Code)
+	}
+}
+
+func TestIdempotentServerError(t *testing.T) {
+	router, calls := idempotentRouter(t, http.StatusInternalServerError, nil)
+
+	// key of failed request is released, so retry runs handler again
+	postIdempotent(router, "key-1", `{"mobile":"09120000001"}`)
+	postIdempotent(router, "key-1", `{"mobile":"09120000001"}`)
+
+	if calls() != 2 {
+		t.Errorf("handler is called %d times, want 2", calls())
+	}
+}
+
+func TestIdempotentBodyLimit(t *testing.T) {
+	router, calls := idempotentRouter(t, http.StatusCreated, nil)
+
+	w := postIdempotent(router, "key-1", strings.Repeat("a", int(MaxBodySize)+1))
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), api.CodeInvalidBody) {
+		t.Errorf("response = %d %s, want 400 %s", w.Code, w.Body, api.CodeInvalidBody)
+	}
+
+	if calls() != 0 {
+		t.Errorf("handler is called %d times, want 0", calls())
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-kit/kit/metrics"
 	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
-	"github.com/mssola/user_agent"
+	"github.com/ppeymann/top-app.git/risk"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -14,6 +14,13 @@ import (
 type serviceInstrumenting struct {
 	os      metrics.Counter
 	browser metrics.Counter
+
+	rateLimitDecisions metrics.Counter
+	rateLimitFallbacks metrics.Counter
+	rateLimitBreaker   metrics.Gauge
+
+	powChallenges    metrics.Counter
+	powVerifications metrics.Counter
 }
 
 // newServiceInstrumenting returns a configured instance of serviceInstrumenting.
@@ -31,6 +38,36 @@ func newServiceInstrumenting() serviceInstrumenting {
 			Name:      "browser_count",
 			Help:      "num of request that made by any of Browsers.",
 		}, []string{"browser"}),
+		rateLimitDecisions: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "api",
+			Subsystem: "rate_limit",
+			Name:      "decisions_count",
+			Help:      "num of rate limit decisions by policy, decision and limiter that made decision.",
+		}, []string{"policy", "decision", "source"}),
+		rateLimitFallbacks: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "api",
+			Subsystem: "rate_limit",
+			Name:      "fallback_count",
+			Help:      "num of rate limit fallbacks because redis failed or circuit breaker is open.",
+		}, []string{"reason", "mode"}),
+		rateLimitBreaker: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: "api",
+			Subsystem: "rate_limit",
+			Name:      "breaker_state",
+			Help:      "state of redis circuit breaker, 0 is closed, 1 is open and 2 is half open.",
+		}, []string{}),
+		powChallenges: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "api",
+			Subsystem: "pow",
+			Name:      "challenges_count",
+			Help:      "num of issued proof-of-work challenges by difficulty.",
+		}, []string{"difficulty"}),
+		powVerifications: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: "api",
+			Subsystem: "pow",
+			Name:      "verifications_count",
+			Help:      "num of proof-of-work verifications by result, required is for requests without solution.",
+		}, []string{"result"}),
 	}
 }
 
@@ -43,15 +80,21 @@ func (s *Server) prometheus() gin.HandlerFunc {
 	}
 }
 
-// metrics is http middleware for serviceInstrumenting global metrics
+// metrics is http middleware for serviceInstrumenting global metrics, device of request is parsed
+// from its headers and carried by request context for risk evaluation.
 func (s *Server) metrics() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		ua := user_agent.New(ctx.GetHeader("User-Agent"))
-		defer func(agent *user_agent.UserAgent) {
-			b, v := ua.Browser()
-			s.instrumenting.os.With("os", ua.OS()).Add(1)
-			s.instrumenting.browser.With("browser", fmt.Sprintf("%s, version: %s", b, v)).Add(1)
-		}(ua)
+		device := risk.NewDevice(ctx.Request.Header, ctx.ClientIP())
+		ctx.Request = ctx.Request.WithContext(risk.NewContext(ctx.Request.Context(), device))
+
+		// browsers send client hints of device model in next requests
+		ctx.Header("Accept-CH", "Sec-CH-UA-Platform, Sec-CH-UA-Mobile, Sec-CH-UA-Model")
+
+		defer func() {
+			s.instrumenting.os.With("os", device.OS).Add(1)
+			s.instrumenting.browser.With("browser", fmt.Sprintf("%s, version: %s", device.Browser, device.BrowserVersion)).Add(1)
+		}()
+
 		ctx.Next()
 	}
 }
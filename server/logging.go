@@ -0,0 +1,71 @@
+package server
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	kitlog "github.com/go-kit/log"
+	"github.com/ppeymann/top-app.git/auth"
+	"github.com/ppeymann/top-app.git/logging"
+	"github.com/ppeymann/top-app.git/tenant"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDPattern limits request ids that are accepted from clients, other ids are replaced.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// requestLogger is global http middleware that assigns or propagates X-Request-ID, carries request scoped
+// logger in request context and logs every request after it is handled, sensitive values are redacted.
+func (s *Server) requestLogger() gin.HandlerFunc {
+	base := logging.NewRedactor(s.Logger)
+
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		id := ctx.GetHeader(logging.RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = logging.NewRequestID()
+		}
+
+		ctx.Header(logging.RequestIDHeader, id)
+
+		logger := kitlog.With(base, "request_id", id)
+
+		// span of request is started by tracing middleware, trace id correlates logs with traces
+		if sc := trace.SpanContextFromContext(ctx.Request.Context()); sc.IsValid() {
+			logger = kitlog.With(logger, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+		}
+		ctx.Request = ctx.Request.WithContext(logging.NewContext(ctx.Request.Context(), logger, id))
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		keyvals := []interface{}{
+			"method", ctx.Request.Method,
+			"route", route,
+			"status", ctx.Writer.Status(),
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip", ctx.ClientIP(),
+			"bytes", max(ctx.Writer.Size(), 0),
+		}
+
+		if t, ok := tenant.FromContext(ctx.Request.Context()); ok {
+			keyvals = append(keyvals, "tenant", t.ID)
+		}
+
+		if claims, ok := auth.FromContext(ctx.Request.Context()); ok {
+			keyvals = append(keyvals, "subject", claims.Subject)
+		}
+
+		if len(ctx.Errors) > 0 {
+			keyvals = append(keyvals, "err", ctx.Errors.String())
+		}
+
+		_ = logger.Log(keyvals...)
+	}
+}
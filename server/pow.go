@@ -0,0 +1,195 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/logging"
+	"github.com/ppeymann/top-app.git/pow"
+	"github.com/ppeymann/top-app.git/risk"
+	"github.com/ppeymann/top-app.git/tenant"
+)
+
+// PowChallengePath is path of endpoint that issues proof-of-work challenges.
+const PowChallengePath string = api.PowChallengePath
+
+// powFactorPurpose is value of purpose query parameter of challenges that are solved as extra factor.
+const powFactorPurpose string = "factor"
+
+// powChallenge is handler that issues a proof-of-work challenge to caller, difficulty of challenge
+// grows with number of challenges that caller requested in window of configuration. challenges
+// that are requested with "purpose=factor" are at least as difficult as factor difficulty.
+func (s *Server) powChallenge() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		conf := s.Config.Config().ProofOfWork
+		if !conf.Enabled {
+			s.Abort(ctx, otpapp.ErrNotFound)
+			return
+		}
+
+		policy := powPolicy(conf)
+		difficulty := policy.Difficulty(s.powCount(ctx, policy.Window))
+		if ctx.Query("purpose") == powFactorPurpose {
+			difficulty = max(difficulty, policy.Factor)
+		}
+
+		challenge, err := pow.Issue([]byte(conf.Secret), powResource(ctx), difficulty, policy.TTL)
+		if err != nil {
+			_ = logging.FromContext(ctx.Request.Context()).Log("method", "PowChallenge", "err", err)
+			s.Abort(ctx, otpapp.ErrInternalServer)
+			return
+		}
+
+		s.instrumenting.powChallenges.With("difficulty", strconv.Itoa(difficulty)).Add(1)
+
+		ctx.Header("Cache-Control", "no-store")
+		s.Reply(ctx, &otpapp.BaseResult{
+			Status: http.StatusOK,
+			Result: challenge,
+		})
+	}
+}
+
+// ProofOfWork is http middleware that requires a solved proof-of-work challenge for routes that send
+// one time password, solution is bound to client of challenge and mobile of request body. requests
+// pass if proof of work is disabled. it must be applied before rate limit policies so requests without
+// a solution do not consume limits of mobile. solutions of factor difficulty also pass as extra factor.
+func (s *Server) ProofOfWork() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		conf := s.Config.Config().ProofOfWork
+		if !conf.Enabled {
+			ctx.Next()
+			return
+		}
+
+		if ctx.GetHeader(pow.ChallengeHeader) == "" {
+			s.instrumenting.powVerifications.With("result", "required").Add(1)
+			s.Abort(ctx, pow.ErrRequired)
+			return
+		}
+
+		if !s.proveWork(ctx, conf) {
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// ExtraFactor is http middleware that accepts a solved proof-of-work challenge of factor difficulty as
+// extra factor of sign in attempts that risk engine challenges, requests without a solution pass
+// without extra factor. challenged attempts are blocked when proof of work is disabled.
+func (s *Server) ExtraFactor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		conf := s.Config.Config().ProofOfWork
+		if !conf.Enabled || ctx.GetHeader(pow.ChallengeHeader) == "" {
+			ctx.Next()
+			return
+		}
+
+		if !s.proveWork(ctx, conf) {
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// proveWork verifies proof-of-work solution of request and marks request as verified by an extra factor
+// if solution is of factor difficulty, request is aborted if solution is not valid.
+func (s *Server) proveWork(ctx *gin.Context, conf config.ProofOfWorkConfig) bool {
+	difficulty, err := pow.Verify([]byte(conf.Secret), ctx.GetHeader(pow.ChallengeHeader), powResource(ctx),
+		requestMobile(ctx), ctx.GetHeader(pow.NonceHeader))
+	if err != nil {
+		s.instrumenting.powVerifications.With("result", "invalid").Add(1)
+		s.Abort(ctx, err)
+		return false
+	}
+
+	s.instrumenting.powVerifications.With("result", "valid").Add(1)
+
+	if difficulty >= powPolicy(conf).Factor {
+		ctx.Request = ctx.Request.WithContext(risk.WithFactor(ctx.Request.Context()))
+	}
+
+	return true
+}
+
+// powCount counts a challenge of caller and returns number of challenges of its client ip or device in window,
+// whichever is more. callers are counted as first challenge when redis is unavailable.
+func (s *Server) powCount(ctx *gin.Context, window time.Duration) int64 {
+	if s.velocity == nil {
+		return 1
+	}
+
+	keys := []string{"pow:ip:" + ctx.ClientIP()}
+	if d, ok := risk.FromContext(ctx.Request.Context()); ok && d.ID != "" {
+		keys = append(keys, "pow:device:"+d.Fingerprint())
+	}
+
+	count := int64(1)
+	for _, key := range keys {
+		n, err := s.velocity.Add(ctx.Request.Context(), key, window)
+		if err != nil {
+			_ = logging.FromContext(ctx.Request.Context()).Log("method", "PowChallenge", "err", err)
+			return count
+		}
+
+		count = max(count, n)
+	}
+
+	return count
+}
+
+// powPolicy returns challenge policy of configuration with defaults of unspecified options.
+func powPolicy(conf config.ProofOfWorkConfig) pow.Policy {
+	p := pow.Policy{
+		Base:   conf.BaseDifficulty,
+		Max:    conf.MaxDifficulty,
+		Factor: conf.FactorDifficulty,
+		Step:   conf.StepRequests,
+		Window: time.Duration(conf.WindowSeconds) * time.Second,
+		TTL:    time.Duration(conf.TTLSeconds) * time.Second,
+	}
+
+	if p.Base <= 0 {
+		p.Base = pow.DefaultBaseDifficulty
+	}
+
+	if p.Max <= 0 {
+		p.Max = pow.DefaultMaxDifficulty
+	}
+
+	if p.Factor <= 0 {
+		p.Factor = pow.DefaultFactorDifficulty
+	}
+
+	if p.Step <= 0 {
+		p.Step = pow.DefaultStep
+	}
+
+	if p.Window <= 0 {
+		p.Window = pow.DefaultWindow
+	}
+
+	if p.TTL <= 0 {
+		p.TTL = pow.DefaultTTL
+	}
+
+	return p
+}
+
+// powResource returns client that challenges are bound to, it is tenant and client ip of request.
+func powResource(ctx *gin.Context) string {
+	var tenantID string
+	if t, ok := tenant.FromContext(ctx.Request.Context()); ok {
+		tenantID = t.ID
+	}
+
+	return tenantID + "|" + ctx.ClientIP()
+}
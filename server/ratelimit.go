@@ -1,58 +1,213 @@
 package server
 
 import (
-	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/auth"
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/logging"
+	"github.com/ppeymann/top-app.git/ratelimit"
+	"github.com/ppeymann/top-app.git/tenant"
+	"github.com/redis/go-redis/v9"
 )
 
-func (s *Server) redisRateLimit() gin.HandlerFunc {
+// rate limit policy keys, they specify identity that requests are counted for.
+const (
+	RateLimitKeyIP      string = "ip"
+	RateLimitKeyMobile  string = "mobile"
+	RateLimitKeySubject string = "subject"
+)
+
+// globalRateLimitPolicy is name of global limit that applied to every request by client ip.
+const globalRateLimitPolicy string = "global"
+
+// contextRateLimitKey is the gin context key of most restrictive rate limit decision of request.
+const contextRateLimitKey string = "CONTEXT_RATE_LIMIT"
+
+// newRateLimiter returns redis Limiter that is protected by circuit breaker and falls back
+// according to resilience configuration when redis is unavailable.
+func newRateLimiter(conf config.RateLimitResilience, client *redis.Client, inst serviceInstrumenting) (ratelimit.Limiter, error) {
+	mode, err := ratelimit.ParseFailureMode(conf.FailureMode)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := conf.BreakerThreshold
+	if threshold < 1 {
+		threshold = ratelimit.DefaultBreakerThreshold
+	}
+
+	cooldown := time.Duration(conf.BreakerCooldownSeconds) * time.Second
+	if cooldown <= 0 {
+		cooldown = ratelimit.DefaultBreakerCooldown
+	}
+
+	breaker := ratelimit.NewBreaker(threshold, cooldown, inst.rateLimitBreaker)
+	timeout := time.Duration(conf.TimeoutMillis) * time.Millisecond
+
+	return ratelimit.NewResilientLimiter(ratelimit.NewRedisLimiter(client), breaker, mode, timeout, inst.rateLimitFallbacks), nil
+}
+
+// rateLimit is global http middleware that limits requests of every client ip.
+// it is applied if enabled in config file or in tenant configuration, probe endpoints are not limited.
+func (s *Server) rateLimit() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		conf, tenantID := s.rateLimitConfig(ctx)
+		if !conf.Enabled || ctx.Request.URL.Path == HealthPath || ctx.Request.URL.Path == ReadyPath {
+			ctx.Next()
+			return
+		}
+
 		// Skip rate limiting if the path is in the exclude list
-		for _, v := range s.Config.RateLimit.RateLimitExcludePaths {
+		for _, v := range conf.RateLimitExcludePaths {
 			if strings.HasPrefix(ctx.Request.URL.Path, v) {
 				ctx.Next()
 				return
 			}
 		}
 
-		// Get the client IP address
-		clientIP := ctx.ClientIP()
+		limit := ratelimit.Limit{
+			Algorithm: ratelimit.Algorithm(conf.Algorithm),
+			Requests:  conf.RateLimitRequestPerDuration,
+			Window:    time.Duration(conf.RateLimitDurationSeconds) * time.Second,
+		}
+
+		if !s.allow(ctx, globalRateLimitPolicy, tenantID, RateLimitKeyIP, limit) {
+			return
+		}
+
+		ctx.Next()
+	}
+}
 
-		// Generate a unique key for the client's rate limit
-		key := fmt.Sprintf("api_rate_limit:%s", clientIP)
+// RateLimit is http middleware that applies named policy of rate limit configuration to route group.
+// requests pass if rate limit is disabled or policy is not configured for tenant.
+func (s *Server) RateLimit(policy string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		conf, tenantID := s.rateLimitConfig(ctx)
 
-		// Check if the client has exceeded the rate limit
-		count, err := s.redis.Incr(context.Background(), key).Result()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, otpapp.ErrInternalServer.Error())
-			ctx.Abort()
+		p, ok := conf.Policies[policy]
+		if !conf.Enabled || !ok {
+			ctx.Next()
 			return
 		}
 
-		// Set expiration if it's the first request within the duration
-		if count == 1 {
-			err := s.redis.Expire(context.Background(), key, time.Duration(s.Config.RateLimit.RateLimitDurationSeconds)*time.Second).Err()
-			if err != nil {
-				ctx.JSON(http.StatusInternalServerError, otpapp.ErrInternalServer.Error())
-				ctx.Abort()
-				return
-			}
+		algorithm := p.Algorithm
+		if algorithm == "" {
+			algorithm = conf.Algorithm
+		}
+
+		limit := ratelimit.Limit{
+			Algorithm: ratelimit.Algorithm(algorithm),
+			Requests:  p.Requests,
+			Window:    time.Duration(p.DurationSeconds) * time.Second,
 		}
 
-		// Check if the request exceeds the allowed limit
-		if count > s.Config.RateLimit.RateLimitRequestPerDuration {
-			ctx.JSON(http.StatusTooManyRequests, "rate limit exceeded")
-			ctx.Abort()
+		if !s.allow(ctx, policy, tenantID, p.Key, limit) {
 			return
 		}
 
-		// Continue processing the request
 		ctx.Next()
 	}
 }
+
+// rateLimitConfig returns rate limit configuration and id of request tenant.
+func (s *Server) rateLimitConfig(ctx *gin.Context) (config.RateLimitConfig, string) {
+	if t, ok := tenant.FromContext(ctx.Request.Context()); ok {
+		return t.RateLimit, t.ID
+	}
+
+	return s.Config.Config().RateLimit, ""
+}
+
+// allow checks request against limit and writes RateLimit headers, request is aborted if it exceeds limit.
+func (s *Server) allow(ctx *gin.Context, policy, tenantID, key string, limit ratelimit.Limit) bool {
+	id := fmt.Sprintf("api_rate_limit:%s:%s:%s", policy, tenantID, rateLimitIdentity(ctx, key))
+
+	d, err := s.limiter.Allow(ctx.Request.Context(), id, limit)
+	if err != nil {
+		s.instrumenting.rateLimitDecisions.With("policy", policy, "decision", "error", "source", "none").Add(1)
+
+		if errors.Is(err, ratelimit.ErrUnavailable) {
+			s.Abort(ctx, otpapp.ErrUnavailable.Because(err))
+			return false
+		}
+
+		_ = logging.FromContext(ctx.Request.Context()).Log("method", "RateLimit", "policy", policy, "err", err)
+		s.Abort(ctx, otpapp.ErrInternalServer)
+		return false
+	}
+
+	decision := "allowed"
+	if !d.Allowed {
+		decision = "rejected"
+	}
+
+	s.instrumenting.rateLimitDecisions.With("policy", policy, "decision", decision, "source", string(d.Source)).Add(1)
+
+	// clients see the most restrictive of limits that applied to request
+	if prev, ok := ctx.Get(contextRateLimitKey); !ok || d.Remaining <= prev.(*ratelimit.Decision).Remaining || !d.Allowed {
+		ctx.Set(contextRateLimitKey, d)
+		ctx.Header("RateLimit-Limit", strconv.FormatInt(d.Limit, 10))
+		ctx.Header("RateLimit-Remaining", strconv.FormatInt(d.Remaining, 10))
+		ctx.Header("RateLimit-Reset", seconds(d.Reset))
+		ctx.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%s", d.Limit, seconds(limit.Window)))
+	}
+
+	if !d.Allowed {
+		ctx.Header("Retry-After", seconds(d.RetryAfter))
+		s.Abort(ctx, otpapp.ErrRateLimited.Because(ratelimit.ErrLimitExceeded))
+		return false
+	}
+
+	return true
+}
+
+// rateLimitIdentity returns identity of request for key, it falls back to client ip when
+// request does not carry identity of key.
+func rateLimitIdentity(ctx *gin.Context, key string) string {
+	switch key {
+	case RateLimitKeyMobile:
+		if mobile := requestMobile(ctx); mobile != "" {
+			return "mobile:" + mobile
+		}
+
+	case RateLimitKeySubject:
+		if claims, ok := auth.FromContext(ctx.Request.Context()); ok {
+			return fmt.Sprintf("sub:%d", claims.Subject)
+		}
+	}
+
+	return "ip:" + ctx.ClientIP()
+}
+
+// requestMobile returns mobile number of json request body, body is restored for handlers.
+func requestMobile(ctx *gin.Context) string {
+	body, err := peekBody(ctx)
+	if err != nil || body == nil {
+		return ""
+	}
+
+	in := struct {
+		Mobile string `json:"mobile"`
+	}{}
+
+	if json.Unmarshal(body, &in) != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(in.Mobile)
+}
+
+// seconds formats d as whole seconds rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/i18n"
+	"github.com/ppeymann/top-app.git/logging"
+)
+
+// ProblemContentType is media type of RFC 7807 problem details, clients that accept it receive
+// failed responses as Problem instead of BaseResult.
+const ProblemContentType string = "application/problem+json"
+
+// problemTypePrefix is prefix of problem type URIs, it is followed by error code.
+const problemTypePrefix string = "urn:otpapp:error:"
+
+// Problem is RFC 7807 problem details of a failed request.
+type Problem struct {
+	// Type identifies the problem, it is "urn:otpapp:error:" followed by code of first error.
+	Type string `json:"type"`
+
+	// Title is http status text of response.
+	Title string `json:"title"`
+
+	// Status is http status code of response.
+	Status int `json:"status"`
+
+	// Detail joins messages of every error.
+	Detail string `json:"detail,omitempty"`
+
+	// Instance is path of request.
+	Instance string `json:"instance,omitempty"`
+
+	// Code is code of first error.
+	Code string `json:"code"`
+
+	// RequestID is X-Request-ID of request.
+	RequestID string `json:"request_id,omitempty"`
+
+	// Errors are every error of request.
+	Errors []otpapp.Error `json:"errors"`
+}
+
+// Reply writes result of a service call, status of result is used unless legacy_errors is enabled
+// in http configuration that failed results are written with status 200 as before error catalog.
+func (s *Server) Reply(ctx *gin.Context, result *otpapp.BaseResult) {
+	s.write(ctx, s.status(result), result)
+}
+
+// Abort writes failed response of errs with status of first error and aborts request, status is
+// 200 when legacy_errors is enabled as responses of Reply.
+func (s *Server) Abort(ctx *gin.Context, errs ...error) {
+	for _, err := range errs {
+		_ = ctx.Error(err)
+	}
+
+	result := otpapp.NewErrorResult(errs...)
+
+	ctx.Abort()
+	s.write(ctx, s.status(result), result)
+}
+
+// status returns http status that result is written with.
+func (s *Server) status(result *otpapp.BaseResult) int {
+	if result.Status == 0 || (len(result.Errors) > 0 && s.Config.Config().Http.LegacyErrors) {
+		return http.StatusOK
+	}
+
+	return result.Status
+}
+
+func (s *Server) write(ctx *gin.Context, status int, result *otpapp.BaseResult) {
+	lang := i18n.Language(ctx.Request.Context())
+	i18n.Localize(lang, result)
+	ctx.Header("Content-Language", lang)
+
+	if status < http.StatusBadRequest || len(result.ErrorDetails) == 0 || !acceptsProblem(ctx) {
+		ctx.JSON(status, result)
+		return
+	}
+
+	first := result.ErrorDetails[0]
+
+	ctx.Header("Content-Type", ProblemContentType)
+	ctx.JSON(status, Problem{
+		Type:      problemTypePrefix + first.Code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    strings.Join(result.Errors, "; "),
+		Instance:  ctx.Request.URL.Path,
+		Code:      first.Code,
+		RequestID: logging.RequestID(ctx.Request.Context()),
+		Errors:    result.ErrorDetails,
+	})
+}
+
+// acceptsProblem reports whether client accepts problem details media type.
+func acceptsProblem(ctx *gin.Context) bool {
+	for _, accept := range ctx.Request.Header.Values("Accept") {
+		if strings.Contains(accept, ProblemContentType) {
+			return true
+		}
+	}
+
+	return false
+}
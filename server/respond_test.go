@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
+	"github.com/ppeymann/top-app.git/config"
+)
+
+func TestLegacyErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		legacy bool
+		accept string
+		status int
+	}{
+		{name: "status of error", status: http.StatusNotFound},
+		{name: "problem details", accept: ProblemContentType, status: http.StatusNotFound},
+		{name: "legacy errors", legacy: true, status: http.StatusOK},
+		{name: "legacy errors ignore problem details", legacy: true, accept: ProblemContentType, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Config: newTestStore(t, func(conf *config.Configuration) {
+					conf.Http.LegacyErrors = tt.legacy
+				}),
+			}
+
+			router := gin.New()
+			router.GET("/reply", func(ctx *gin.Context) {
+				s.Reply(ctx, otpapp.NewErrorResult(otpapp.ErrNotFound))
+			})
+			router.GET("/abort", func(ctx *gin.Context) {
+				s.Abort(ctx, otpapp.ErrNotFound)
+			})
+
+			for _, path := range []string{"/reply", "/abort"} {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if tt.accept != "" {
+					req.Header.Set("Accept", tt.accept)
+				}
+
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+
+				if rec.Code != tt.status {
+					t.Errorf("%s: status = %d, want %d", path, rec.Code, tt.status)
+				}
+
+				var body struct {
+					Code         string         `json:"code"`
+					ErrorDetails []otpapp.Error `json:"error_details"`
+				}
+
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatalf("%s: %v", path, err)
+				}
+
+				code := body.Code
+				if len(body.ErrorDetails) > 0 {
+					code = body.ErrorDetails[0].Code
+				}
+
+				if code != api.CodeNotFound {
+					t.Errorf("%s: code = %q, want %q", path, code, api.CodeNotFound)
+				}
+			}
+		})
+	}
+}
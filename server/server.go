@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"os/signal"
 	"syscall"
 	"time"
@@ -15,7 +14,11 @@ import (
 	"github.com/ppeymann/top-app.git/auth"
 	"github.com/ppeymann/top-app.git/config"
 	"github.com/ppeymann/top-app.git/docs"
-	"github.com/ppeymann/top-app.git/env"
+	"github.com/ppeymann/top-app.git/health"
+	"github.com/ppeymann/top-app.git/idempotency"
+	"github.com/ppeymann/top-app.git/ratelimit"
+	"github.com/ppeymann/top-app.git/risk"
+	"github.com/ppeymann/top-app.git/tenant"
 	"github.com/redis/go-redis/v9"
 
 	swaggerFiles "github.com/swaggo/files"
@@ -24,65 +27,115 @@ import (
 
 type Server struct {
 	Router        *gin.Engine
-	Config        *config.Configuration
+	Config        *config.Store
 	Logger        kitlog.Logger
+	Tenants       *tenant.Registry
 	paseto        auth.TokenMaker
 	instrumenting serviceInstrumenting
 	redis         *redis.Client
+	limiter       ratelimit.Limiter
+	idempotency   idempotency.Store
+	velocity      risk.Velocity
+
+	// Health checks dependencies of server for readiness probe, services register their dependencies to it.
+	Health *health.Checker
+
+	// Certificates are TLS certificates of listener, it is nil if listener runs without TLS.
+	Certificates *Certificates
 }
 
 // EnvMode specified the running env 'release' represents production mode and ” represents development.
 // it depended on gin GIN_MODE env for unifying and simplicity of setting.
 var EnvMode = ""
 
-func NewServer(logger kitlog.Logger, conf *config.Configuration, redis *redis.Client) *Server {
+func NewServer(logger kitlog.Logger, store *config.Store, redis *redis.Client, paseto auth.TokenMaker,
+	tenants *tenant.Registry) *Server {
+	conf := store.Config()
+
 	svr := &Server{
 		Logger:        logger,
-		Config:        conf,
+		Config:        store,
+		Tenants:       tenants,
 		instrumenting: newServiceInstrumenting(),
+		Health:        health.NewChecker(health.DefaultTTL),
 		redis:         redis,
+		paseto:        paseto,
+	}
+
+	limiter, err := newRateLimiter(conf.RateLimit.Resilience, redis, svr.instrumenting)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
+	svr.limiter = limiter
+
+	if redis != nil {
+		svr.idempotency = idempotency.NewRedisStore(redis)
+		svr.velocity = risk.NewRedisVelocity(redis)
+	}
+
+	if conf.Listener.Cert != "" {
+		certificates, err := NewCertificates(conf.Listener)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		svr.Certificates = certificates
+	}
+
+	if conf.Http.Mode != "" {
+		gin.SetMode(conf.Http.Mode)
+	}
+
+	EnvMode = conf.Http.Mode
+
 	router := gin.New()
-	router.Use(gin.Recovery())
 
-	EnvMode = os.Getenv("GIN_MODE")
+	// spans of requests, trace context of incoming requests is continued
+	router.Use(svr.tracing(conf.Tracing))
+
+	// request logger wraps recovery so recovered panics are logged with their status
+	router.Use(svr.requestLogger())
+	router.Use(gin.Recovery())
 
 	// setting swagger info if not in production mode
-	if env.GetEnv("SWAGGER_ENABLED", "false") == "true" {
+	if conf.Http.SwaggerEnabled {
 		docs.SwaggerInfo.Title = fmt.Sprintf("OTP App Backend [ AuthMode: %s ]", "Paseto")
 		docs.SwaggerInfo.Description = "The Swagger Documentation For OtpApps Backend API server."
 		docs.SwaggerInfo.Version = "1.0"
-		docs.SwaggerInfo.Host = env.GetEnv("HOST_URL", "localhost:8080")
+		docs.SwaggerInfo.Host = conf.Http.HostURL
 		docs.SwaggerInfo.BasePath = "/api/v1"
 		docs.SwaggerInfo.Schemes = []string{"http", "https"}
 	}
 
 	// binding global
 	router.Use(svr.metrics())
+	router.Use(svr.language())
+	router.Use(svr.resolveTenant())
 
-	// enable api rate limit if expected and enabled in config file
-	if conf.RateLimit.Enabled {
-		router.Use(svr.redisRateLimit())
-	}
+	// api rate limit, it is applied if enabled in config file or in tenant configuration
+	router.Use(svr.rateLimit())
 
-	if env.GetEnv("CORS_ENABLE", "false") == "true" {
+	if conf.Http.CorsEnabled {
 		router.Use(svr.cors())
 	}
 
-	err := router.SetTrustedProxies([]string{"127.0.0.1"})
+	err = router.SetTrustedProxies([]string{"127.0.0.1"})
 	if err != nil {
 		log.Fatalln(err)
 	}
 
 	svr.Router = router
 
-	svr.Router.GET("/metrics", svr.prometheus())
+	svr.Router.GET("/metrics", svr.Internal(), svr.prometheus())
 
-	svr.paseto, err = auth.NewPasetoMaker(env.GetEnv("JWT", ""))
-	if err != nil {
-		log.Fatal(err)
-	}
+	// kubernetes probes
+	svr.Router.GET(HealthPath, svr.healthz())
+	svr.Router.GET(ReadyPath, svr.readyz())
+	svr.registerChecks()
+
+	// proof-of-work challenges of routes that send one time password
+	svr.Router.GET(PowChallengePath, svr.powChallenge())
 
 	return svr
 }
@@ -91,23 +144,54 @@ func (s *Server) Listen() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
 	defer stop()
 
-	if env.GetEnv("SWAGGER_ENABLED", "false") == "true" {
+	conf := s.Config.Config()
+
+	if conf.Http.SwaggerEnabled {
 		s.Router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
 
 	srv := &http.Server{
 		ReadHeaderTimeout: 10 * time.Second,
 		ReadTimeout:       10 * time.Second,
-		Addr:              fmt.Sprintf("%s:%d", s.Config.Listener.Host, s.Config.Listener.Port),
+		Addr:              fmt.Sprintf("%s:%d", conf.Listener.Host, conf.Listener.Port),
 		Handler:           s.Router,
 	}
 
-	// start https server
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("http listener stopped : %s", err)
+	var redirect *http.Server
+
+	if s.Certificates != nil {
+		srv.TLSConfig = s.Certificates.TLSConfig(conf.Listener)
+
+		// start https server, certificate is served by TLSConfig
+		go func() {
+			if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("https listener stopped : %s", err)
+			}
+		}()
+
+		if conf.Listener.RedirectPort > 0 {
+			redirect = &http.Server{
+				ReadHeaderTimeout: 10 * time.Second,
+				ReadTimeout:       10 * time.Second,
+				Addr:              fmt.Sprintf("%s:%d", conf.Listener.Host, conf.Listener.RedirectPort),
+				Handler:           redirectHandler(conf.Listener.Port),
+			}
+
+			// start http to https redirect server
+			go func() {
+				if err := redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Fatalf("redirect listener stopped : %s", err)
+				}
+			}()
 		}
-	}()
+	} else {
+		// start http server
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("http listener stopped : %s", err)
+			}
+		}()
+	}
 
 	// Listen for the interrupt signal.
 	<-ctx.Done()
@@ -116,10 +200,19 @@ func (s *Server) Listen() {
 	stop()
 	log.Println("shutting down gracefully otp app server, press Ctrl+C again to force")
 
+	// readiness probe fails from now on, listeners are kept open for shutdown delay
+	// so load balancers stop routing requests before they are closed.
+	s.Health.Shutdown()
+	time.Sleep(time.Duration(conf.Http.ShutdownDelaySeconds) * time.Second)
+
 	// The context is used to inform the server it has 30 seconds to finish
 	// the request it is currently handling
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
+	if redirect != nil {
+		_ = redirect.Shutdown(ctx)
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("server forced to shutdown: ", err)
 	}
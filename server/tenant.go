@@ -0,0 +1,35 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ppeymann/top-app.git/tenant"
+)
+
+// ContextTenantKey is the gin context key of resolved request tenant.
+const ContextTenantKey = "CONTEXT_TENANT"
+
+// resolveTenant is global http middleware that resolves tenant of request by X-Tenant header or host.
+// it does not reject requests, routes that require a tenant must use Tenant middleware.
+func (s *Server) resolveTenant() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		t, err := s.Tenants.Resolve(ctx.GetHeader(tenant.Header), ctx.Request.Host)
+		if err == nil {
+			ctx.Set(ContextTenantKey, t)
+			ctx.Request = ctx.Request.WithContext(tenant.NewContext(ctx.Request.Context(), t))
+		}
+
+		ctx.Next()
+	}
+}
+
+// Tenant is http middleware that rejects requests that no tenant resolved for them.
+func (s *Server) Tenant() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := tenant.FromContext(ctx.Request.Context()); !ok {
+			s.Abort(ctx, tenant.ErrUnknownTenant)
+			return
+		}
+
+		ctx.Next()
+	}
+}
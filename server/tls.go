@@ -0,0 +1,216 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/config"
+)
+
+// ErrClientCertificateRequired is returned when an internal endpoint is called without verified client certificate.
+var ErrClientCertificateRequired = errors.New("client certificate is required")
+
+type (
+	// Certificates holds TLS certificate and client certificate authorities of listener, they are
+	// reloaded from disk and replaced atomically, established connections keep their certificate.
+	Certificates struct {
+		cert, key, ca string
+		mu            sync.Mutex
+		active        atomic.Pointer[certificateSet]
+	}
+
+	certificateSet struct {
+		cert    *tls.Certificate
+		pool    *x509.CertPool
+		version string
+	}
+)
+
+// NewCertificates returns Certificates of listener cert, key and client_ca files.
+func NewCertificates(listener config.Listener) (*Certificates, error) {
+	c := &Certificates{
+		cert: listener.Cert,
+		key:  listener.Key,
+		ca:   listener.ClientCA,
+	}
+
+	set, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.active.Store(set)
+
+	return c, nil
+}
+
+// Version returns version of active certificates, it is derived from content of files.
+func (c *Certificates) Version() string {
+	return c.active.Load().version
+}
+
+// Paths returns certificate, key and client certificate authorities files.
+func (c *Certificates) Paths() []string {
+	paths := []string{c.cert, c.key}
+	if c.ca != "" {
+		paths = append(paths, c.ca)
+	}
+
+	return paths
+}
+
+// Reload loads certificate files and swaps active certificates if they are changed,
+// active certificates are kept if any file is invalid.
+func (c *Certificates) Reload() (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	set, err := c.load()
+	if err != nil {
+		return false, err
+	}
+
+	if set.version == c.Version() {
+		return false, nil
+	}
+
+	c.active.Store(set)
+
+	return true, nil
+}
+
+func (c *Certificates) load() (*certificateSet, error) {
+	hash := sha256.New()
+	set := &certificateSet{}
+
+	certPEM, err := os.ReadFile(c.cert)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := os.ReadFile(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("tls certificate %s: %w", c.cert, err)
+	}
+
+	set.cert = &cert
+	hash.Write(certPEM)
+	hash.Write(keyPEM)
+
+	if c.ca != "" {
+		caPEM, err := os.ReadFile(c.ca)
+		if err != nil {
+			return nil, err
+		}
+
+		set.pool = x509.NewCertPool()
+		if !set.pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("tls client ca %s: no certificate found", c.ca)
+		}
+
+		hash.Write(caPEM)
+	}
+
+	set.version = hex.EncodeToString(hash.Sum(nil)[:6])
+
+	return set, nil
+}
+
+// TLSConfig returns tls.Config of listener, certificate and client certificate authorities
+// are read from active certificates on every handshake.
+func (c *Certificates) TLSConfig(listener config.Listener) *tls.Config {
+	conf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return c.active.Load().cert, nil
+		},
+	}
+
+	if listener.MinTLSVersion == "1.3" {
+		conf.MinVersion = tls.VersionTLS13
+	}
+
+	if len(listener.CipherSuites) > 0 {
+		ids := map[string]uint16{}
+		for _, s := range tls.CipherSuites() {
+			ids[s.Name] = s.ID
+		}
+
+		for _, name := range listener.CipherSuites {
+			conf.CipherSuites = append(conf.CipherSuites, ids[name])
+		}
+	}
+
+	switch listener.ClientAuth {
+	case "request":
+		conf.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return conf
+	}
+
+	// client certificate authorities are reloadable, so they are set per connection
+	conf.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		client := conf.Clone()
+		client.GetConfigForClient = nil
+		client.ClientCAs = c.active.Load().pool
+
+		return client, nil
+	}
+
+	return conf
+}
+
+// Internal is http middleware for endpoints of internal callers, it rejects requests that has no
+// verified client certificate when client certificate verification is enabled on listener.
+func (s *Server) Internal() gin.HandlerFunc {
+	listener := s.Config.Config().Listener
+	if s.Certificates == nil || listener.ClientAuth == "" || listener.ClientAuth == "none" {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
+	}
+
+	return func(ctx *gin.Context) {
+		if ctx.Request.TLS == nil || len(ctx.Request.TLS.VerifiedChains) == 0 {
+			s.Abort(ctx, otpapp.ErrPermissionDenied.Because(ErrClientCertificateRequired))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// redirectHandler redirects requests to https listener on port.
+func redirectHandler(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// tracing is global http middleware that starts span of every request by route template,
+// metrics and probe endpoints are not traced.
+func (s *Server) tracing(conf config.TracingConfig) gin.HandlerFunc {
+	name := conf.ServiceName
+	if name == "" {
+		name = tracing.DefaultServiceName
+	}
+
+	return otelgin.Middleware(name, otelgin.WithFilter(func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/metrics", HealthPath, ReadyPath:
+			return false
+		}
+
+		return true
+	}))
+}
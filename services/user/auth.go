@@ -1,10 +1,10 @@
 package user
 
 import (
-	"net/http"
+	"context"
 
-	"github.com/gin-gonic/gin"
 	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/auth"
 	"github.com/ppeymann/top-app.git/models"
 )
 
@@ -12,45 +12,73 @@ type authService struct {
 	next models.UserService
 }
 
-// GetAllUser implements models.UserService.
-func (a *authService) GetAllUser(ctx *gin.Context, page int32, limit int32) *otpapp.BaseResult {
-	_, err := otpapp.CheckAuth(ctx)
+// GetAllUser implements models.UserService, only tenant admins can list accounts.
+func (a *authService) GetAllUser(ctx context.Context, in *models.PageInput) *otpapp.BaseResult {
+	claims, err := otpapp.CheckAuth(ctx)
 	if err != nil {
-		return &otpapp.BaseResult{
-			Errors: []string{err.Error()},
-			Status: http.StatusOK,
-		}
+		return otpapp.NewErrorResult(err)
+	}
+
+	if !claims.HasRole(auth.RoleAdmin) {
+		return otpapp.NewErrorResult(otpapp.ErrPermissionDenied)
 	}
 
-	return a.next.GetAllUser(ctx, page, limit)
+	return a.next.GetAllUser(ctx, in)
 }
 
 // GetUserByPhone implements models.UserService.
-func (a *authService) GetUserByPhone(ctx *gin.Context) *otpapp.BaseResult {
+func (a *authService) GetUserByPhone(ctx context.Context) *otpapp.BaseResult {
 	_, err := otpapp.CheckAuth(ctx)
 	if err != nil {
-		return &otpapp.BaseResult{
-			Errors: []string{err.Error()},
-			Status: http.StatusOK,
-		}
+		return otpapp.NewErrorResult(err)
 	}
 
 	return a.next.GetUserByPhone(ctx)
 }
 
 // OtpVerify implements models.UserService.
-func (a *authService) OtpVerify(in *models.OtpInput, ctx *gin.Context) *otpapp.BaseResult {
-	return a.next.OtpVerify(in, ctx)
+func (a *authService) OtpVerify(ctx context.Context, in *models.OtpInput) *otpapp.BaseResult {
+	return a.next.OtpVerify(ctx, in)
+}
+
+// Refresh implements models.UserService.
+func (a *authService) Refresh(ctx context.Context, in *models.RefreshInput) *otpapp.BaseResult {
+	return a.next.Refresh(ctx, in)
 }
 
 // Register implements models.UserService.
-func (a *authService) Register(ctx *gin.Context, mobile string) *otpapp.BaseResult {
-	return a.next.Register(ctx, mobile)
+func (a *authService) Register(ctx context.Context, in *models.MobileInput) *otpapp.BaseResult {
+	return a.next.Register(ctx, in)
 }
 
 // Login implements models.UserService.
-func (a *authService) Login(ctx *gin.Context, mobile string) *otpapp.BaseResult {
-	return a.next.Login(ctx, mobile)
+func (a *authService) Login(ctx context.Context, in *models.MobileInput) *otpapp.BaseResult {
+	return a.next.Login(ctx, in)
+}
+
+// DeviceSignIn implements models.UserService.
+func (a *authService) DeviceSignIn(ctx context.Context, in *models.DeviceSignInInput) *otpapp.BaseResult {
+	return a.next.DeviceSignIn(ctx, in)
+}
+
+// TrustedDevices implements models.UserService.
+func (a *authService) TrustedDevices(ctx context.Context) *otpapp.BaseResult {
+	_, err := otpapp.CheckAuth(ctx)
+	if err != nil {
+		return otpapp.NewErrorResult(err)
+	}
+
+	return a.next.TrustedDevices(ctx)
+}
+
+// RevokeDevice implements models.UserService.
+func (a *authService) RevokeDevice(ctx context.Context, in *models.DeviceInput) *otpapp.BaseResult {
+	_, err := otpapp.CheckAuth(ctx)
+	if err != nil {
+		return otpapp.NewErrorResult(err)
+	}
+
+	return a.next.RevokeDevice(ctx, in)
 }
 
 func NewAuthService(srv models.UserService) models.UserService {
@@ -0,0 +1,169 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/logging"
+	"github.com/ppeymann/top-app.git/models"
+	"github.com/ppeymann/top-app.git/risk"
+	"github.com/ppeymann/top-app.git/tenant"
+)
+
+// defaultDeviceTrust is time that devices stay trusted if it is not configured.
+const defaultDeviceTrust time.Duration = 30 * 24 * time.Hour
+
+// DeviceSignIn implements models.UserService.
+func (s *service) DeviceSignIn(ctx context.Context, in *models.DeviceSignInInput) *otpapp.BaseResult {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return otpapp.NewErrorResult(tenant.ErrUnknownTenant)
+	}
+
+	if !s.store.Config().TrustedDevices.Enabled {
+		return otpapp.NewErrorResult(models.ErrDeviceNotTrusted)
+	}
+
+	user, err := s.repo.Find(ctx, t.ID, in.Mobile)
+	if err != nil {
+		return otpapp.NewErrorResult(err)
+	}
+
+	if user.Suspended {
+		return otpapp.NewErrorResult(models.ErrAccountSuspended)
+	}
+
+	// device token is bound to device that it is issued for
+	device, err := s.devices.FindDevice(ctx, user.ID, currentDevice(ctx).Fingerprint())
+	if err != nil {
+		return otpapp.NewErrorResult(models.ErrDeviceNotTrusted)
+	}
+
+	hash := hashDeviceToken(in.DeviceToken)
+	if device.TrustedUntil == nil || device.TrustedUntil.Before(time.Now()) ||
+		subtle.ConstantTimeCompare([]byte(hash), []byte(device.TokenHash)) != 1 {
+		return otpapp.NewErrorResult(models.ErrDeviceNotTrusted)
+	}
+
+	// risky attempts of trusted devices still need an extra factor
+	if res := s.assess(ctx, risk.Login, t, user); res != nil {
+		return res
+	}
+
+	s.remember(ctx, t, user)
+
+	bundle, err := s.issueTokens(t, user)
+	if err != nil {
+		return otpapp.NewErrorResult(otpapp.ErrInternalServer)
+	}
+
+	return &otpapp.BaseResult{
+		Status: http.StatusOK,
+		Result: bundle,
+	}
+}
+
+// TrustedDevices implements models.UserService.
+func (s *service) TrustedDevices(ctx context.Context) *otpapp.BaseResult {
+	claims, err := otpapp.CheckAuth(ctx)
+	if err != nil {
+		return otpapp.NewErrorResult(err)
+	}
+
+	devices, err := s.devices.TrustedDevices(ctx, claims.Subject)
+	if err != nil {
+		return otpapp.NewErrorResult(err)
+	}
+
+	return &otpapp.BaseResult{
+		Status:      http.StatusOK,
+		Result:      devices,
+		ResultCount: int64(len(devices)),
+	}
+}
+
+// RevokeDevice implements models.UserService.
+func (s *service) RevokeDevice(ctx context.Context, in *models.DeviceInput) *otpapp.BaseResult {
+	claims, err := otpapp.CheckAuth(ctx)
+	if err != nil {
+		return otpapp.NewErrorResult(err)
+	}
+
+	if err := s.devices.RevokeDevice(ctx, claims.Subject, in.ID); err != nil {
+		return otpapp.NewErrorResult(err)
+	}
+
+	return &otpapp.BaseResult{
+		Status: http.StatusOK,
+	}
+}
+
+// trustDevice issues device token of current device and adds it to bundle.
+func (s *service) trustDevice(ctx context.Context, t *tenant.Tenant, user *models.UserEntity, bundle *models.TokenBundlerOutput) error {
+	ttl := time.Duration(s.store.Config().TrustedDevices.TTLHours) * time.Hour
+	if ttl <= 0 {
+		ttl = defaultDeviceTrust
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	until := time.Now().Add(ttl).UTC()
+	device := currentDevice(ctx)
+
+	err := s.devices.TrustDevice(ctx, &models.DeviceEntity{
+		TenantID:     t.ID,
+		UserID:       user.ID,
+		Fingerprint:  device.Fingerprint(),
+		Name:         device.Name(),
+		LastIP:       device.IP,
+		LastASN:      device.ASN,
+		LastSeenAt:   time.Now().UTC(),
+		TokenHash:    hashDeviceToken(token),
+		TrustedUntil: &until,
+	})
+	if err != nil {
+		return err
+	}
+
+	bundle.DeviceToken = token
+	bundle.DeviceExpire = &until
+
+	return nil
+}
+
+// remember saves current device as a known device of user for risk evaluation.
+func (s *service) remember(ctx context.Context, t *tenant.Tenant, user *models.UserEntity) {
+	if s.risk == nil || !s.store.Config().Risk.Enabled {
+		return
+	}
+
+	if err := s.risk.Remember(ctx, t.ID, user); err != nil {
+		_ = logging.FromContext(ctx).Log("method", "remember", "tenant", t.ID, "user", user.ID, "err", err)
+	}
+}
+
+// currentDevice returns device of request.
+func currentDevice(ctx context.Context) *risk.Device {
+	if d, ok := risk.FromContext(ctx); ok {
+		return d
+	}
+
+	return &risk.Device{}
+}
+
+// hashDeviceToken returns hash of device token that is stored instead of token.
+func hashDeviceToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,16 +1,17 @@
 package user
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
 	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
+	"github.com/ppeymann/top-app.git/auth"
 	"github.com/ppeymann/top-app.git/models"
 	"github.com/ppeymann/top-app.git/server"
 )
 
 type handler struct {
-	next models.UserService
+	next   models.UserService
+	server *server.Server
 }
 
 // SignUp is handler for create New user
@@ -23,21 +24,28 @@ type handler struct {
 // @Produce 					json
 //
 // @Param						input body models.MobileInput true "MobileInput"
-// @Success 					200 {object} otpapp.BaseResult{result=models.TokenBundlerOutput}	"always return status 200 but body contains error"
+// @Param						Idempotency-Key header string false "key that makes retries of request idempotent"
+// @Param						X-PoW-Challenge header string false "token of solved proof-of-work challenge"
+// @Param						X-PoW-Nonce header string false "nonce that solves proof-of-work challenge"
+// @Success 					200 {object} otpapp.BaseResult{result=models.OtpOutput}
+// @Failure 					400	{object}	otpapp.BaseResult	"invalid body or validation failed"
+// @Failure 					403	{object}	otpapp.BaseResult	"POW_INVALID"
+// @Failure 					409	{object}	otpapp.BaseResult	"ACCOUNT_EXISTS or OTP_NOT_EXPIRED"
+// @Failure 					428	{object}	otpapp.BaseResult	"POW_REQUIRED"
+// @Failure 					429	{object}	otpapp.BaseResult	"RATE_LIMITED"
+// @Failure 					502	{object}	otpapp.BaseResult	"OTP_SEND_FAILED"
 // @Router						/api/v1/user/signup	[post]
 func (h *handler) SignUp(ctx *gin.Context) {
 	in := &models.MobileInput{}
 
 	if err := ctx.ShouldBindJSON(in); err != nil {
-		ctx.JSON(http.StatusBadRequest, otpapp.BaseResult{
-			Errors: []string{otpapp.ProvideRequiredJsonBody},
-		})
+		h.server.Abort(ctx, otpapp.ErrInvalidBody)
 
 		return
 	}
 
-	result := h.next.Register(ctx, in.Mobile)
-	ctx.JSON(result.Status, result)
+	result := h.next.Register(ctx.Request.Context(), in)
+	h.server.Reply(ctx, result)
 }
 
 // Login is handler for log in
@@ -50,21 +58,29 @@ func (h *handler) SignUp(ctx *gin.Context) {
 // @Produce				json
 //
 // @Params				input body models.MobileInput	true	"MobileInput"
-// @Success				200 {object} otpapp.BaseResult{result=models.TokenBundlerOutput} 	"always return status 200 but body contains error"
+// @Param				Idempotency-Key header string false "key that makes retries of request idempotent"
+// @Param				X-Device-ID header string false "stable identifier of app installation"
+// @Param				X-PoW-Challenge header string false "token of solved proof-of-work challenge"
+// @Param				X-PoW-Nonce header string false "nonce that solves proof-of-work challenge"
+// @Success				200 {object} otpapp.BaseResult{result=models.OtpOutput}
+// @Failure				400	{object}	otpapp.BaseResult	"invalid body or validation failed"
+// @Failure				401	{object}	otpapp.BaseResult	"EXTRA_FACTOR_REQUIRED"
+// @Failure				403	{object}	otpapp.BaseResult	"ACCOUNT_SUSPENDED, RISK_BLOCKED or POW_INVALID"
+// @Failure				404	{object}	otpapp.BaseResult	"ACCOUNT_NOT_FOUND"
+// @Failure				428	{object}	otpapp.BaseResult	"POW_REQUIRED"
+// @Failure				429	{object}	otpapp.BaseResult	"RATE_LIMITED"
 // @Router				/api/v1/user/login	[post]
 func (h *handler) SignIn(ctx *gin.Context) {
 	in := &models.MobileInput{}
 
 	if err := ctx.ShouldBindJSON(in); err != nil {
-		ctx.JSON(http.StatusBadRequest, otpapp.BaseResult{
-			Errors: []string{otpapp.ProvideRequiredJsonBody},
-		})
+		h.server.Abort(ctx, otpapp.ErrInvalidBody)
 
 		return
 	}
 
-	result := h.next.Login(ctx, in.Mobile)
-	ctx.JSON(result.Status, result)
+	result := h.next.Login(ctx.Request.Context(), in)
+	h.server.Reply(ctx, result)
 }
 
 // GetUser is handler for get information
@@ -76,11 +92,16 @@ func (h *handler) SignIn(ctx *gin.Context) {
 // @Accept				json
 // @Produce				json
 //
-// @Success				200	{object}	otpapp.BaseResult{result=models.UserEntity}	"always return status 200 but body contains error"
+// @Param				If-None-Match	header	string	false	"ETag of cached user info"
+// @Success				200	{object}	otpapp.BaseResult{result=models.UserEntity}
+// @Header				200	{string}	ETag	"entity tag of user info"
+// @Success				304	"user info is not modified since If-None-Match"
+// @Failure				401	{object}	otpapp.BaseResult	"UNAUTHORIZED"
+// @Failure				404	{object}	otpapp.BaseResult	"ACCOUNT_NOT_FOUND"
 // @Router				/api/v1/user	[get]
 func (h *handler) GetUser(ctx *gin.Context) {
-	result := h.next.GetUserByPhone(ctx)
-	ctx.JSON(result.Status, result)
+	result := h.next.GetUserByPhone(ctx.Request.Context())
+	h.server.ReplyConditional(ctx, result)
 }
 
 // OtpVerify is handler for verification one time password
@@ -92,66 +113,191 @@ func (h *handler) GetUser(ctx *gin.Context) {
 // @Product				json
 //
 // @Params				input body models.OtpInput	true	"OtpInput"
+// @Param				Idempotency-Key header string false "key that makes retries of request idempotent"
+// @Param				X-Device-ID header string false "stable identifier of app installation"
+// @Param				X-PoW-Challenge header string false "token of solved proof-of-work challenge"
+// @Param				X-PoW-Nonce header string false "nonce that solves proof-of-work challenge"
 // @Success				200	{object}	otpapp.BaseResult{result=models.UserEntity}
+// @Failure				400	{object}	otpapp.BaseResult	"invalid body or validation failed"
+// @Failure				401	{object}	otpapp.BaseResult	"OTP_INVALID, OTP_EXPIRED or EXTRA_FACTOR_REQUIRED"
+// @Failure				403	{object}	otpapp.BaseResult	"ACCOUNT_SUSPENDED, RISK_BLOCKED or POW_INVALID"
+// @Failure				404	{object}	otpapp.BaseResult	"ACCOUNT_NOT_FOUND"
 // @Router				/api/v1/user/otp	[post]
 func (h *handler) OtpVerify(ctx *gin.Context) {
 	in := &models.OtpInput{}
 
 	if err := ctx.ShouldBindJSON(in); err != nil {
-		ctx.JSON(http.StatusBadRequest, otpapp.BaseResult{
-			Errors: []string{otpapp.ProvideRequiredJsonBody},
-		})
+		h.server.Abort(ctx, otpapp.ErrInvalidBody)
 
 		return
 	}
 
-	result := h.next.OtpVerify(in, ctx)
-	ctx.JSON(result.Status, result)
+	result := h.next.OtpVerify(ctx.Request.Context(), in)
+	h.server.Reply(ctx, result)
+}
+
+// Refresh is handler for exchanging refresh token
+//
+// @BasePath			/api/v1/user
+// @Summary				refresh token
+// @Description			exchange refresh token for a new token bundle
+// @Tags				user
+// @Accept				json
+// @Produce				json
+//
+// @Param				input body models.RefreshInput	true	"RefreshInput"
+// @Param				Idempotency-Key header string false "key that makes retries of request idempotent"
+// @Success				200	{object}	otpapp.BaseResult{result=models.TokenBundlerOutput}
+// @Failure				400	{object}	otpapp.BaseResult	"invalid body or validation failed"
+// @Failure				401	{object}	otpapp.BaseResult	"REFRESH_INVALID"
+// @Router				/api/v1/user/refresh	[post]
+func (h *handler) Refresh(ctx *gin.Context) {
+	in := &models.RefreshInput{}
+
+	if err := ctx.ShouldBindJSON(in); err != nil {
+		h.server.Abort(ctx, otpapp.ErrInvalidBody)
+
+		return
+	}
+
+	result := h.next.Refresh(ctx.Request.Context(), in)
+	h.server.Reply(ctx, result)
 }
 
 // GetAllUsers is handler for get all user
 //
 // @BasePath			/api/v1/user
 // @Summary				get all user
-// @Description			get all user
+// @Description			get all user, requires ADMIN role
 // @Tags				user
 // @Accept				json
 // @Product				json
 //
 // @Success				200	{object}	otpapp.BaseResult{result=[]models.UserEntity}
+// @Failure				400	{object}	otpapp.BaseResult	"INVALID_PARAM"
+// @Failure				401	{object}	otpapp.BaseResult	"UNAUTHORIZED"
+// @Failure				403	{object}	otpapp.BaseResult	"PERMISSION_DENIED"
 // @Router				/api/v1/user/{offset}/{page}	[get]
 // @Security			bearer
 func (h *handler) GetAllUsers(ctx *gin.Context) {
 	offset := server.GetPathOffset(ctx)
 	page, err := server.GetInt64Path("page", ctx)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, otpapp.BaseResult{
-			Errors: []string{otpapp.ProvideRequiredParam},
-		})
+		h.server.Abort(ctx, otpapp.ErrInvalidParam)
+
+		return
+	}
+
+	result := h.next.GetAllUser(ctx.Request.Context(), &models.PageInput{
+		Page:  int32(page),
+		Limit: int32(offset),
+	})
+	h.server.Reply(ctx, result)
+}
+
+// DeviceSignIn is handler for signing in a trusted device
+//
+// @BasePath			/api/v1/user
+// @Summary				trusted device sign in
+// @Description			sign in without one time password by device token that issued in otp verification
+// @Tags				user
+// @Accept				json
+// @Produce				json
+//
+// @Param				input body models.DeviceSignInInput	true	"DeviceSignInInput"
+// @Param				Idempotency-Key header string false "key that makes retries of request idempotent"
+// @Param				X-Device-ID header string false "stable identifier of app installation"
+// @Param				X-PoW-Challenge header string false "token of solved proof-of-work challenge"
+// @Param				X-PoW-Nonce header string false "nonce that solves proof-of-work challenge"
+// @Success				200	{object}	otpapp.BaseResult{result=models.TokenBundlerOutput}
+// @Failure				400	{object}	otpapp.BaseResult	"invalid body or validation failed"
+// @Failure				401	{object}	otpapp.BaseResult	"DEVICE_NOT_TRUSTED or EXTRA_FACTOR_REQUIRED"
+// @Failure				403	{object}	otpapp.BaseResult	"ACCOUNT_SUSPENDED, RISK_BLOCKED or POW_INVALID"
+// @Failure				404	{object}	otpapp.BaseResult	"ACCOUNT_NOT_FOUND"
+// @Failure				429	{object}	otpapp.BaseResult	"RATE_LIMITED"
+// @Router				/api/v1/user/devices/signin	[post]
+func (h *handler) DeviceSignIn(ctx *gin.Context) {
+	in := &models.DeviceSignInInput{}
+
+	if err := ctx.ShouldBindJSON(in); err != nil {
+		h.server.Abort(ctx, otpapp.ErrInvalidBody)
+
+		return
+	}
+
+	result := h.next.DeviceSignIn(ctx.Request.Context(), in)
+	h.server.Reply(ctx, result)
+}
+
+// TrustedDevices is handler for listing trusted devices
+//
+// @BasePath			/api/v1/user
+// @Summary				trusted devices
+// @Description			list trusted devices of user
+// @Tags				user
+// @Accept				json
+// @Produce				json
+//
+// @Success				200	{object}	otpapp.BaseResult{result=[]models.DeviceEntity}
+// @Failure				401	{object}	otpapp.BaseResult	"UNAUTHORIZED"
+// @Router				/api/v1/user/devices	[get]
+// @Security			bearer
+func (h *handler) TrustedDevices(ctx *gin.Context) {
+	result := h.next.TrustedDevices(ctx.Request.Context())
+	h.server.Reply(ctx, result)
+}
+
+// RevokeDevice is handler for revoking trust of a device
+//
+// @BasePath			/api/v1/user
+// @Summary				revoke trusted device
+// @Description			revoke trust of device, its device token can not sign in anymore
+// @Tags				user
+// @Accept				json
+// @Produce				json
+//
+// @Param				id	path	int	true	"device id"
+// @Success				200	{object}	otpapp.BaseResult
+// @Failure				400	{object}	otpapp.BaseResult	"INVALID_PARAM"
+// @Failure				401	{object}	otpapp.BaseResult	"UNAUTHORIZED"
+// @Failure				404	{object}	otpapp.BaseResult	"DEVICE_NOT_FOUND"
+// @Router				/api/v1/user/devices/{id}	[delete]
+// @Security			bearer
+func (h *handler) RevokeDevice(ctx *gin.Context) {
+	id, err := server.GetPathUint64(ctx)
+	if err != nil {
+		h.server.Abort(ctx, otpapp.ErrInvalidParam)
 
 		return
 	}
 
-	result := h.next.GetAllUser(ctx, int32(page), int32(offset))
-	ctx.JSON(result.Status, result)
+	result := h.next.RevokeDevice(ctx.Request.Context(), &models.DeviceInput{
+		ID: uint(id),
+	})
+	h.server.Reply(ctx, result)
 }
 
 func NewHandler(srv models.UserService, s *server.Server) models.UserHandler {
 	handler := &handler{
-		next: srv,
+		next:   srv,
+		server: s,
 	}
 
-	group := s.Router.Group("/api/v1/user")
+	group := s.Router.Group(api.UserPath, s.Tenant())
 	{
-		group.POST("/signup", handler.SignUp)
-		group.POST("/signin", handler.SignIn)
-		group.POST("/otp", handler.OtpVerify)
+		group.POST("/signup", s.ProofOfWork(), s.RateLimit("signup"), s.IdempotentFor(models.OtpLifetime), handler.SignUp)
+		group.POST("/signin", s.ProofOfWork(), s.RateLimit("signin"), s.IdempotentFor(models.OtpLifetime), handler.SignIn)
+		group.POST("/otp", s.ExtraFactor(), s.RateLimit("otp"), s.IdempotentFor(models.OtpLifetime), handler.OtpVerify)
+		group.POST("/refresh", s.RateLimit("refresh"), s.IdempotentFor(models.OtpLifetime), handler.Refresh)
+		group.POST("/devices/signin", s.ExtraFactor(), s.RateLimit("signin"), s.IdempotentFor(models.OtpLifetime), handler.DeviceSignIn)
 	}
 
 	group.Use(s.Authenticate())
 	{
-		group.GET("/:offset/:page", handler.GetAllUsers)
+		group.GET("/:offset/:page", s.Authorize(auth.RoleAdmin), s.RateLimit("listing"), handler.GetAllUsers)
 		group.GET("/", handler.GetUser)
+		group.GET("/devices", handler.TrustedDevices)
+		group.DELETE("/devices/:id", handler.RevokeDevice)
 	}
 
 	return handler
@@ -1,9 +1,9 @@
 package user
 
 import (
+	"context"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/go-kit/kit/metrics"
 	otpapp "github.com/ppeymann/top-app.git"
 	"github.com/ppeymann/top-app.git/models"
@@ -16,17 +16,17 @@ type instrumentingService struct {
 }
 
 // GetAllUser implements models.UserService.
-func (i *instrumentingService) GetAllUser(ctx *gin.Context, page int32, limit int32) *otpapp.BaseResult {
+func (i *instrumentingService) GetAllUser(ctx context.Context, in *models.PageInput) *otpapp.BaseResult {
 	defer func(begin time.Time) {
 		i.requestCount.With("method", "GetAllUser").Add(1)
 		i.requestLatency.With("method", "GetAllUser").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return i.next.GetAllUser(ctx, page, limit)
+	return i.next.GetAllUser(ctx, in)
 }
 
 // GetUserByPhone implements models.UserService.
-func (i *instrumentingService) GetUserByPhone(ctx *gin.Context) *otpapp.BaseResult {
+func (i *instrumentingService) GetUserByPhone(ctx context.Context) *otpapp.BaseResult {
 	defer func(begin time.Time) {
 		i.requestCount.With("method", "GetUserByPhone").Add(1)
 		i.requestLatency.With("method", "GetUserByPhone").Observe(time.Since(begin).Seconds())
@@ -36,33 +36,73 @@ func (i *instrumentingService) GetUserByPhone(ctx *gin.Context) *otpapp.BaseResu
 }
 
 // Login implements models.UserService.
-func (i *instrumentingService) Login(ctx *gin.Context, mobile string) *otpapp.BaseResult {
+func (i *instrumentingService) Login(ctx context.Context, in *models.MobileInput) *otpapp.BaseResult {
 	defer func(begin time.Time) {
 		i.requestCount.With("method", "Login").Add(1)
 		i.requestLatency.With("method", "Login").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return i.next.Login(ctx, mobile)
+	return i.next.Login(ctx, in)
 }
 
 // OtpVerify implements models.UserService.
-func (i *instrumentingService) OtpVerify(in *models.OtpInput, ctx *gin.Context) *otpapp.BaseResult {
+func (i *instrumentingService) OtpVerify(ctx context.Context, in *models.OtpInput) *otpapp.BaseResult {
 	defer func(begin time.Time) {
 		i.requestCount.With("method", "OtpVerify").Add(1)
 		i.requestLatency.With("method", "OtpVerify").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return i.next.OtpVerify(in, ctx)
+	return i.next.OtpVerify(ctx, in)
+}
+
+// Refresh implements models.UserService.
+func (i *instrumentingService) Refresh(ctx context.Context, in *models.RefreshInput) *otpapp.BaseResult {
+	defer func(begin time.Time) {
+		i.requestCount.With("method", "Refresh").Add(1)
+		i.requestLatency.With("method", "Refresh").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return i.next.Refresh(ctx, in)
 }
 
 // Register implements models.UserService.
-func (i *instrumentingService) Register(ctx *gin.Context, mobile string) *otpapp.BaseResult {
+func (i *instrumentingService) Register(ctx context.Context, in *models.MobileInput) *otpapp.BaseResult {
 	defer func(begin time.Time) {
 		i.requestCount.With("method", "Register").Add(1)
 		i.requestLatency.With("method", "Register").Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	return i.next.Register(ctx, mobile)
+	return i.next.Register(ctx, in)
+}
+
+// DeviceSignIn implements models.UserService.
+func (i *instrumentingService) DeviceSignIn(ctx context.Context, in *models.DeviceSignInInput) *otpapp.BaseResult {
+	defer func(begin time.Time) {
+		i.requestCount.With("method", "DeviceSignIn").Add(1)
+		i.requestLatency.With("method", "DeviceSignIn").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return i.next.DeviceSignIn(ctx, in)
+}
+
+// TrustedDevices implements models.UserService.
+func (i *instrumentingService) TrustedDevices(ctx context.Context) *otpapp.BaseResult {
+	defer func(begin time.Time) {
+		i.requestCount.With("method", "TrustedDevices").Add(1)
+		i.requestLatency.With("method", "TrustedDevices").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return i.next.TrustedDevices(ctx)
+}
+
+// RevokeDevice implements models.UserService.
+func (i *instrumentingService) RevokeDevice(ctx context.Context, in *models.DeviceInput) *otpapp.BaseResult {
+	defer func(begin time.Time) {
+		i.requestCount.With("method", "RevokeDevice").Add(1)
+		i.requestLatency.With("method", "RevokeDevice").Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	return i.next.RevokeDevice(ctx, in)
 }
 
 func NewInstrumentingService(requestCount metrics.Counter, requestLatency metrics.Histogram, srv models.UserService) models.UserService {
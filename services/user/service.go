@@ -1,32 +1,44 @@
 package user
 
+//go:generate go run ../../cmd/decorator -kind logging -source ../../models/user.go -interface UserService -import github.com/ppeymann/top-app.git/models
+//go:generate go run ../../cmd/decorator -kind tracing -source ../../models/user.go -interface UserService -import github.com/ppeymann/top-app.git/models
+//go:generate go run ../../cmd/decorator -kind validation -source ../../models/user.go -interface UserService -import github.com/ppeymann/top-app.git/models
+
 import (
-	"fmt"
+	"context"
 	"net/http"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	otpapp "github.com/ppeymann/top-app.git"
 	"github.com/ppeymann/top-app.git/auth"
 	"github.com/ppeymann/top-app.git/config"
-	"github.com/ppeymann/top-app.git/env"
+	"github.com/ppeymann/top-app.git/i18n"
+	"github.com/ppeymann/top-app.git/logging"
 	"github.com/ppeymann/top-app.git/models"
+	"github.com/ppeymann/top-app.git/otp"
+	"github.com/ppeymann/top-app.git/risk"
+	"github.com/ppeymann/top-app.git/tenant"
 	"github.com/ppeymann/top-app.git/utils"
 )
 
 type service struct {
-	repo models.UserRepository
-	conf *config.Configuration
+	repo    models.UserRepository
+	devices models.RiskRepository
+	store   *config.Store
+	paseto  auth.TokenMaker
+	risk    *risk.Engine
 }
 
 // GetAllUser implements models.UserService.
-func (s *service) GetAllUser(ctx *gin.Context, page int32, limit int32) *otpapp.BaseResult {
-	users, err := s.repo.FindAllUser(page, limit)
+func (s *service) GetAllUser(ctx context.Context, in *models.PageInput) *otpapp.BaseResult {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return otpapp.NewErrorResult(tenant.ErrUnknownTenant)
+	}
+
+	users, err := s.repo.FindAllUser(ctx, t.ID, in.Page, in.Limit)
 	if err != nil {
-		return &otpapp.BaseResult{
-			Errors: []string{err.Error()},
-			Status: http.StatusOK,
-		}
+		return otpapp.NewErrorResult(err)
 	}
 
 	return &otpapp.BaseResult{
@@ -37,14 +49,19 @@ func (s *service) GetAllUser(ctx *gin.Context, page int32, limit int32) *otpapp.
 }
 
 // GetUserByPhone implements models.UserService.
-func (s *service) GetUserByPhone(ctx *gin.Context) *otpapp.BaseResult {
-	claims, _ := otpapp.CheckAuth(ctx)
-	user, err := s.repo.FindByID(claims.Subject)
+func (s *service) GetUserByPhone(ctx context.Context) *otpapp.BaseResult {
+	claims, err := otpapp.CheckAuth(ctx)
 	if err != nil {
-		return &otpapp.BaseResult{
-			Errors: []string{err.Error()},
-			Status: http.StatusOK,
-		}
+		return otpapp.NewErrorResult(err)
+	}
+
+	user, err := s.repo.FindByID(ctx, claims.Subject)
+	if err != nil {
+		return otpapp.NewErrorResult(err)
+	}
+
+	if t, ok := tenant.FromContext(ctx); !ok || user.TenantID != t.ID {
+		return otpapp.NewErrorResult(models.ErrAccountNotExist)
 	}
 
 	return &otpapp.BaseResult{
@@ -54,132 +71,249 @@ func (s *service) GetUserByPhone(ctx *gin.Context) *otpapp.BaseResult {
 }
 
 // Login implements models.UserService.
-func (s *service) Login(ctx *gin.Context, mobile string) *otpapp.BaseResult {
+func (s *service) Login(ctx context.Context, in *models.MobileInput) *otpapp.BaseResult {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return otpapp.NewErrorResult(tenant.ErrUnknownTenant)
+	}
+
 	user := &models.UserEntity{}
 	var err error
 
-	user, err = s.repo.Find(mobile)
+	user, err = s.repo.Find(ctx, t.ID, in.Mobile)
 	if err != nil {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{err.Error()},
-		}
+		return otpapp.NewErrorResult(err)
+	}
+
+	if user.Suspended {
+		return otpapp.NewErrorResult(models.ErrAccountSuspended)
+	}
+
+	if res := s.assess(ctx, risk.Login, t, user); res != nil {
+		return res
 	}
 
 	if user.Verification != "" && !user.IsVerificationExpired() {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{"previous otp not expired, please wait a few minutes"},
-		}
+		return otpapp.NewErrorResult(models.ErrOtpNotExpired)
 	}
 
 	code := utils.RandNumberDigits(6)
-	exp := time.Now().Add(180 * time.Second).UTC().Unix()
+	exp := time.Now().Add(models.OtpLifetime).UTC()
 
-	err = s.repo.SetOtp(user.ID, code, exp)
+	err = s.repo.SetOtp(ctx, user.ID, code, exp.Unix())
 	if err != nil {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{err.Error()},
-		}
+		return otpapp.NewErrorResult(err)
 	}
 
-	return &otpapp.BaseResult{
-		Status: http.StatusOK,
-		Result: fmt.Sprintf("%s for : %s", code, mobile),
-	}
+	// one time password is sent in saved locale of account if client does not ask for a language
+	return s.sendOtp(i18n.Prefer(ctx, user.Locale), t, user.Mobile, code, exp)
 }
 
 // OtpVerify implements models.UserService.
-func (s *service) OtpVerify(in *models.OtpInput, ctx *gin.Context) *otpapp.BaseResult {
+func (s *service) OtpVerify(ctx context.Context, in *models.OtpInput) *otpapp.BaseResult {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return otpapp.NewErrorResult(tenant.ErrUnknownTenant)
+	}
+
 	user := &models.UserEntity{}
 	var err error
 
-	user, err = s.repo.Find(in.Mobile)
+	user, err = s.repo.Find(ctx, t.ID, in.Mobile)
 	if err != nil {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{err.Error()},
-		}
+		return otpapp.NewErrorResult(err)
+	}
+
+	if user.Suspended {
+		return otpapp.NewErrorResult(models.ErrAccountSuspended)
+	}
+
+	// attempts are evaluated before one time password is checked, so guessing attempts are counted too
+	if res := s.assess(ctx, risk.OtpVerify, t, user); res != nil {
+		return res
 	}
 
 	if in.Verification != user.Verification {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{"OTP is not correct"},
-		}
+		return otpapp.NewErrorResult(models.ErrOtpInvalid)
 	}
 
 	if user.IsVerificationExpired() {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{"OTP Expired"},
-		}
+		return otpapp.NewErrorResult(models.ErrOtpExpired)
 	}
 
 	user.Verification = ""
 	user.VerificationExpire = time.Now().UTC().Unix()
 
-	err = s.repo.Update(user)
+	// language that client asked for is saved as locale of account
+	if lang, ok := i18n.Requested(ctx); ok {
+		user.Locale = lang
+	}
+
+	err = s.repo.Update(ctx, user)
 	if err != nil {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{err.Error()},
-		}
+		return otpapp.NewErrorResult(err)
 	}
 
-	paseto, err := auth.NewPasetoMaker(env.GetEnv("JWT", ""))
+	s.remember(ctx, t, user)
+
+	bundle, err := s.issueTokens(t, user)
 	if err != nil {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{otpapp.ErrInternalServer.Error()},
+		return otpapp.NewErrorResult(otpapp.ErrInternalServer)
+	}
+
+	// client asked to skip one time password on this device in next sign ins
+	if in.TrustDevice && s.store.Config().TrustedDevices.Enabled {
+		if err := s.trustDevice(ctx, t, user, bundle); err != nil {
+			_ = logging.FromContext(ctx).Log("method", "OtpVerify", "tenant", t.ID, "user", user.ID, "err", err)
 		}
 	}
 
-	tokenClaims := &auth.Claims{
-		Subject:   user.ID,
-		Issuer:    s.conf.Jwt.Issuer,
-		Audience:  s.conf.Jwt.Audience,
-		IssuedAt:  time.Unix(s.conf.Jwt.RefreshExpire, 0),
-		ExpiredAt: time.Now().Add(time.Duration(s.conf.Jwt.TokenExpire) * time.Minute).UTC(),
+	return &otpapp.BaseResult{
+		Status: http.StatusOK,
+		Result: bundle,
+	}
+}
+
+// Refresh implements models.UserService.
+func (s *service) Refresh(ctx context.Context, in *models.RefreshInput) *otpapp.BaseResult {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return otpapp.NewErrorResult(tenant.ErrUnknownTenant)
 	}
 
-	tokenStr, err := paseto.CreateToken(tokenClaims)
+	claims, err := s.paseto.VerifyToken(in.Refresh)
+	if err != nil || !claims.IsRefresh() || claims.ExpiredAt.Before(time.Now().UTC()) || !t.Issued(claims) {
+		return otpapp.NewErrorResult(models.ErrInvalidRefresh)
+	}
+
+	user, err := s.repo.FindByID(ctx, claims.Subject)
 	if err != nil {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{otpapp.ErrInternalServer.Error()},
-		}
+		return otpapp.NewErrorResult(err)
+	}
+
+	if user.Suspended {
+		return otpapp.NewErrorResult(models.ErrAccountSuspended)
+	}
+
+	bundle, err := s.issueTokens(t, user)
+	if err != nil {
+		return otpapp.NewErrorResult(otpapp.ErrInternalServer)
 	}
 
 	return &otpapp.BaseResult{
 		Status: http.StatusOK,
-		Result: models.TokenBundlerOutput{
-			Token:  tokenStr,
-			Expire: tokenClaims.ExpiredAt,
-		},
+		Result: bundle,
 	}
 }
 
+// assess evaluates risk of attempt and returns failed result if attempt is blocked or challenged without
+// an extra factor. attempts are allowed if risk engine is disabled or fails, so sign in does not depend on it.
+func (s *service) assess(ctx context.Context, event risk.Event, t *tenant.Tenant, user *models.UserEntity) *otpapp.BaseResult {
+	conf := s.store.Config()
+	if s.risk == nil || !conf.Risk.Enabled {
+		return nil
+	}
+
+	d, err := s.risk.Evaluate(ctx, event, t.ID, user)
+	if err != nil {
+		_ = logging.FromContext(ctx).Log("method", "assess", "event", event, "tenant", t.ID, "user", user.ID, "err", err)
+		return nil
+	}
+
+	switch {
+	case d.Action == risk.Block:
+		return otpapp.NewErrorResult(risk.ErrBlocked)
+
+	// proof of work is the only extra factor, challenged attempts can not pass without it
+	case d.Action == risk.Challenge && !conf.ProofOfWork.Enabled:
+		return otpapp.NewErrorResult(risk.ErrBlocked)
+
+	case d.Action == risk.Challenge && !risk.HasFactor(ctx):
+		return otpapp.NewErrorResult(risk.ErrFactorRequired)
+	}
+
+	return nil
+}
+
+// issueTokens creates access and refresh tokens of tenant for specified user.
+func (s *service) issueTokens(t *tenant.Tenant, user *models.UserEntity) (*models.TokenBundlerOutput, error) {
+	tokenClaims := auth.NewClaims(user.ID, auth.AccessToken, t.Jwt.Issuer, t.Jwt.Audience,
+		time.Duration(t.Jwt.TokenExpire)*time.Minute)
+	tokenClaims.Roles = user.Roles
+	tokenClaims.Tenant = t.ID
+	tokenClaims.Locale = user.Locale
+
+	tokenStr, err := s.paseto.CreateToken(tokenClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshClaims := auth.NewClaims(user.ID, auth.RefreshToken, t.Jwt.Issuer, t.Jwt.Audience,
+		time.Duration(t.Jwt.RefreshExpire)*time.Minute)
+	refreshClaims.Roles = user.Roles
+	refreshClaims.Tenant = t.ID
+	refreshClaims.Locale = user.Locale
+
+	refreshStr, err := s.paseto.CreateToken(refreshClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenBundlerOutput{
+		Token:   tokenStr,
+		Refresh: refreshStr,
+		Expire:  tokenClaims.ExpiredAt,
+	}, nil
+}
+
 // Register implements models.UserService.
-func (s *service) Register(ctx *gin.Context, mobile string) *otpapp.BaseResult {
-	user, err := s.repo.Create(mobile)
+func (s *service) Register(ctx context.Context, in *models.MobileInput) *otpapp.BaseResult {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return otpapp.NewErrorResult(tenant.ErrUnknownTenant)
+	}
+
+	user, err := s.repo.Create(ctx, t.ID, in.Mobile)
 	if err != nil {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{err.Error()},
-		}
+		return otpapp.NewErrorResult(err)
+	}
+
+	return s.sendOtp(ctx, t, user.Mobile, user.Verification, time.Unix(user.VerificationExpire, 0).UTC())
+}
+
+// sendOtp delivers one time password by sender of tenant.
+func (s *service) sendOtp(ctx context.Context, t *tenant.Tenant, mobile, code string, exp time.Time) *otpapp.BaseResult {
+	if err := t.Sender.Send(ctx, mobile, code); err != nil {
+		_ = logging.FromContext(ctx).Log("method", "sendOtp", "tenant", t.ID, "mobile", mobile, "err", err)
+
+		return otpapp.NewErrorResult(otp.ErrSendFailed)
+	}
+
+	out := models.OtpOutput{
+		Mobile: mobile,
+		Expire: exp,
+	}
+
+	if t.Otp.ExposeCode {
+		out.Code = code
 	}
 
 	return &otpapp.BaseResult{
 		Status: http.StatusOK,
-		Result: fmt.Sprintf("%s for : %s", user.Verification, mobile),
+		Result: out,
 	}
 }
 
-func NewService(repo models.UserRepository, conf *config.Configuration) models.UserService {
+// NewService returns user service, devices stores trusted devices of accounts and engine evaluates risk of
+// sign in attempts, engine may be nil to disable risk evaluation. options of store are read on every call,
+// so reloaded configuration applies without restart.
+func NewService(repo models.UserRepository, devices models.RiskRepository, store *config.Store, paseto auth.TokenMaker,
+	engine *risk.Engine) models.UserService {
 	return &service{
-		repo: repo,
-		conf: conf,
+		repo:    repo,
+		devices: devices,
+		store:   store,
+		paseto:  paseto,
+		risk:    engine,
 	}
 }
@@ -0,0 +1,244 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	kitlog "github.com/go-kit/log"
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/api"
+	"github.com/ppeymann/top-app.git/auth"
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/otp"
+)
+
+// DefaultID is identifier of the implicit tenant of single tenant deployments.
+const DefaultID string = "default"
+
+// Header is the request header that clients select tenant by.
+const Header string = api.TenantHeader
+
+// ErrUnknownTenant is returned when no tenant resolved for request.
+var ErrUnknownTenant error = otpapp.NewError(api.CodeUnknownTenant, http.StatusBadRequest, "unknown tenant")
+
+type (
+	// Tenant is a brand that served by deployment with its own users and options.
+	Tenant struct {
+		ID        string
+		Hosts     []string
+		Jwt       config.Jwt
+		Otp       config.OtpConfig
+		RateLimit config.RateLimitConfig
+
+		// Sender delivers one time passwords of tenant users.
+		Sender otp.Sender
+	}
+
+	// Registry holds configured tenants and resolves tenant of requests.
+	// tenants are replaced atomically when configuration is reloaded.
+	Registry struct {
+		logger kitlog.Logger
+		state  atomic.Pointer[registryState]
+	}
+
+	registryState struct {
+		byID   map[string]*Tenant
+		byHost map[string]*Tenant
+		def    *Tenant
+	}
+
+	tenantKey struct{}
+)
+
+// NewRegistry returns Registry of tenants specified in configuration.
+func NewRegistry(conf *config.Configuration, logger kitlog.Logger) (*Registry, error) {
+	r := &Registry{logger: logger}
+
+	state, err := r.build(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	r.state.Store(state)
+
+	return r, nil
+}
+
+// Prepare builds tenants of reloaded configuration, returned func replaces active tenants.
+// it implements config.ReloadHook.
+func (r *Registry) Prepare(conf *config.Configuration) (func(), error) {
+	state, err := r.build(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		r.state.Store(state)
+	}, nil
+}
+
+func (r *Registry) build(conf *config.Configuration) (*registryState, error) {
+	rs := &registryState{
+		byID:   make(map[string]*Tenant),
+		byHost: make(map[string]*Tenant),
+	}
+
+	tenants := conf.Tenants
+	if len(tenants) == 0 {
+		tenants = []config.TenantConfig{{ID: DefaultID}}
+	}
+
+	for _, tc := range tenants {
+		if tc.ID == "" {
+			return nil, errors.New("tenant: id is required")
+		}
+
+		if _, ok := rs.byID[tc.ID]; ok {
+			return nil, fmt.Errorf("tenant: duplicate tenant id %s", tc.ID)
+		}
+
+		t := &Tenant{
+			ID:        tc.ID,
+			Hosts:     tc.Hosts,
+			Jwt:       conf.Jwt,
+			Otp:       conf.Otp,
+			RateLimit: conf.RateLimit,
+		}
+
+		if tc.Jwt != nil {
+			t.Jwt = *tc.Jwt
+		}
+
+		if tc.Otp != nil {
+			t.Otp = *tc.Otp
+		}
+
+		if tc.RateLimit != nil {
+			t.RateLimit = *tc.RateLimit
+		}
+
+		sender, err := otp.NewSender(t.Otp, kitlog.With(r.logger, "tenant", t.ID))
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+
+		t.Sender = sender
+
+		for _, host := range tc.Hosts {
+			host = strings.ToLower(host)
+			if _, ok := rs.byHost[host]; ok {
+				return nil, fmt.Errorf("tenant: host %s is assigned to more than one tenant", host)
+			}
+
+			rs.byHost[host] = t
+		}
+
+		rs.byID[t.ID] = t
+	}
+
+	switch {
+	case len(conf.Tenants) == 0:
+		rs.def = rs.byID[DefaultID]
+	case conf.DefaultTenant != "":
+		def, ok := rs.byID[conf.DefaultTenant]
+		if !ok {
+			return nil, fmt.Errorf("tenant: default tenant %s is not configured", conf.DefaultTenant)
+		}
+
+		rs.def = def
+	}
+
+	return rs, nil
+}
+
+// Get returns tenant with specified id.
+func (r *Registry) Get(id string) (*Tenant, bool) {
+	t, ok := r.state.Load().byID[id]
+	return t, ok
+}
+
+// Check checks one time password senders of every tenant, it implements health.Check.
+func (r *Registry) Check(ctx context.Context) error {
+	rs := r.state.Load()
+
+	ids := make([]string, 0, len(rs.byID))
+	for id := range rs.byID {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := rs.byID[id].Sender.Check(ctx); err != nil {
+			return fmt.Errorf("tenant %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// Resolve returns tenant of request by X-Tenant header value, then by request host and
+// finally falls back to default tenant.
+func (r *Registry) Resolve(header, host string) (*Tenant, error) {
+	rs := r.state.Load()
+
+	if header != "" {
+		t, ok := rs.byID[header]
+		if !ok {
+			return nil, ErrUnknownTenant
+		}
+
+		return t, nil
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if t, ok := rs.byHost[strings.ToLower(host)]; ok {
+		return t, nil
+	}
+
+	if rs.def != nil {
+		return rs.def, nil
+	}
+
+	return nil, ErrUnknownTenant
+}
+
+// NewContext returns a copy of parent that carries tenant of request.
+func NewContext(parent context.Context, t *Tenant) context.Context {
+	return context.WithValue(parent, tenantKey{}, t)
+}
+
+// FromContext returns tenant of request carried by ctx.
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(tenantKey{}).(*Tenant)
+	if !ok || t == nil {
+		return nil, false
+	}
+
+	return t, true
+}
+
+// Issued reports whether token of claims is issued by t, it must belong to t and its issuer and audience
+// must be issuer and audience of t. tokens are rejected when issuer or audience of tenant changes.
+func (t *Tenant) Issued(claims *auth.Claims) bool {
+	return OfClaims(claims) == t.ID && claims.Issuer == t.Jwt.Issuer && claims.Audience == t.Jwt.Audience
+}
+
+// OfClaims returns tenant id of token claims, tokens that issued before multi-tenancy
+// belong to default tenant.
+func OfClaims(claims *auth.Claims) string {
+	if claims.Tenant == "" {
+		return DefaultID
+	}
+
+	return claims.Tenant
+}
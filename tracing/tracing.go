@@ -0,0 +1,145 @@
+// Package tracing configures OpenTelemetry tracing of api server and traces calls of service chains.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/config"
+	"github.com/ppeymann/top-app.git/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is name of tracer of api server spans.
+const InstrumentationName string = "github.com/ppeymann/top-app.git"
+
+// DefaultServiceName is service.name of spans when it is not configured.
+const DefaultServiceName string = "otpapp"
+
+// Init sets global tracer provider of configuration and W3C trace context propagator, spans are
+// not recorded if tracing is disabled but trace context is still propagated.
+// returned func flushes and stops exporter, it must be called on shutdown.
+func Init(conf config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !conf.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closer, err := newExporter(conf)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+
+	name := conf.ServiceName
+	if name == "" {
+		name = DefaultServiceName
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(name)))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+
+	ratio := 1.0
+	if conf.SampleRatio != nil {
+		ratio = *conf.SampleRatio
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			_ = closer.Close()
+		}
+
+		return err
+	}, nil
+}
+
+// newExporter returns span exporter of configuration and the file that must be closed after it is stopped.
+func newExporter(conf config.TracingConfig) (sdktrace.SpanExporter, io.Closer, error) {
+	switch conf.Exporter {
+	case "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exp, nil, err
+
+	case "file":
+		f, err := os.OpenFile(conf.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			_ = f.Close()
+			return nil, nil, err
+		}
+
+		return exp, f, nil
+
+	default:
+		opts := []otlptracehttp.Option{}
+		if conf.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(conf.Endpoint))
+		}
+
+		if conf.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		if len(conf.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(conf.Headers))
+		}
+
+		exp, err := otlptracehttp.New(context.Background(), opts...)
+		return exp, nil, err
+	}
+}
+
+// Tracer returns tracer of api server spans.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Call calls next in a span that is named by layer of service chain and method, errors of
+// returned result are recorded in span. it is the body of tracing decorators of services, see cmd/decorator.
+func Call(ctx context.Context, layer, method string, next func(context.Context) *otpapp.BaseResult) *otpapp.BaseResult {
+	ctx, span := Tracer().Start(ctx, layer+"/"+method, trace.WithAttributes(
+		attribute.String("service.layer", layer),
+		attribute.String("service.method", method),
+	))
+	defer span.End()
+
+	result := next(ctx)
+
+	if result != nil && len(result.Errors) > 0 {
+		// errors may contain mobile numbers
+		msg := logging.RedactString(strings.Join(result.Errors, "; "))
+
+		span.SetStatus(codes.Error, msg)
+		span.SetAttributes(attribute.String("result.errors", msg))
+	}
+
+	return result
+}
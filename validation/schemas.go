@@ -0,0 +1,175 @@
+package validations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+type (
+	// Schemas holds json schemas of a component, they are replaced atomically when schema files are reloaded.
+	// schemas may refer to each other and to shared definitions by their $id, e.g. "definitions#/definitions/mobile".
+	Schemas struct {
+		paths    []string
+		mu       sync.Mutex
+		required []string
+		active   atomic.Pointer[schemaSet]
+	}
+
+	schemaSet struct {
+		schemas  map[string][]byte
+		compiled map[string]*gojsonschema.Schema
+		version  string
+	}
+)
+
+// NewSchemas returns Schemas of json schema files at paths, e.g. schemas of a component and shared definitions.
+func NewSchemas(paths ...string) (*Schemas, error) {
+	set, err := loadSchemaSet(paths, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schemas{paths: paths}
+	s.active.Store(set)
+
+	return s, nil
+}
+
+// Require reports error if schema of any input type is not loaded, schemas are named by their
+// input type, e.g. MobileInput.json is schema of *models.MobileInput.
+// required schemas are checked on every reload too.
+func (s *Schemas) Require(inputs ...interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, in := range inputs {
+		t := reflect.TypeOf(in)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t == nil || t.Name() == "" {
+			return fmt.Errorf("schema of %T can not be required", in)
+		}
+
+		s.required = append(s.required, t.Name())
+	}
+
+	return checkRequired(s.active.Load(), s.required, s.paths)
+}
+
+// Map returns active schemas by name, it must not be modified.
+func (s *Schemas) Map() map[string][]byte {
+	return s.active.Load().schemas
+}
+
+// Version returns version of active schemas, it is derived from content of schema files.
+func (s *Schemas) Version() string {
+	return s.active.Load().version
+}
+
+// Paths returns directories of schema files.
+func (s *Schemas) Paths() []string {
+	return s.paths
+}
+
+// Reload loads and compiles schema files and swaps active schemas if they are changed,
+// active schemas are kept if any schema is invalid or a required schema is removed.
+func (s *Schemas) Reload() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, err := loadSchemaSet(s.paths, s.required)
+	if err != nil {
+		return false, err
+	}
+
+	if set.version == s.Version() {
+		return false, nil
+	}
+
+	s.active.Store(set)
+
+	return true, nil
+}
+
+// loadSchemaSet loads schema files at paths and compiles every schema with references to other schemas.
+func loadSchemaSet(paths, required []string) (*schemaSet, error) {
+	schemas := make(map[string][]byte)
+	for _, path := range paths {
+		files := make(map[string][]byte)
+		if err := LoadSchema(path, files); err != nil {
+			return nil, err
+		}
+
+		for name, data := range files {
+			if _, ok := schemas[name]; ok {
+				return nil, fmt.Errorf("schema %s is duplicated in %s", name, strings.Join(paths, ", "))
+			}
+
+			schemas[name] = data
+		}
+	}
+
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	set := &schemaSet{
+		schemas:  schemas,
+		compiled: make(map[string]*gojsonschema.Schema, len(schemas)),
+	}
+
+	hash := sha256.New()
+	for _, name := range names {
+		compiled, err := compile(name, schemas)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+
+		set.compiled[name] = compiled
+		hash.Write([]byte(name))
+		hash.Write(schemas[name])
+	}
+
+	set.version = hex.EncodeToString(hash.Sum(nil)[:6])
+
+	return set, checkRequired(set, required, paths)
+}
+
+// compile compiles schema of name, other schemas are added to loader so $ref to them are resolved.
+func compile(name string, schemas map[string][]byte) (*gojsonschema.Schema, error) {
+	loader := gojsonschema.NewSchemaLoader()
+	for other, data := range schemas {
+		if other == name {
+			continue
+		}
+
+		if err := loader.AddSchemas(gojsonschema.NewBytesLoader(data)); err != nil {
+			return nil, fmt.Errorf("%s: %w", other, err)
+		}
+	}
+
+	return loader.Compile(gojsonschema.NewBytesLoader(schemas[name]))
+}
+
+func checkRequired(set *schemaSet, required, paths []string) error {
+	for _, name := range required {
+		if _, ok := set.compiled[name]; !ok {
+			return fmt.Errorf("schema %s is not found in %s", name, strings.Join(paths, ", "))
+		}
+	}
+
+	return nil
+}
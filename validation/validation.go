@@ -1,21 +1,27 @@
 package validations
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
 
 	otpapp "github.com/ppeymann/top-app.git"
+	"github.com/ppeymann/top-app.git/i18n"
 	"github.com/xeipuuv/gojsonschema"
 )
 
-// error for when validation is not OK
-const formatErr string = "%s: is not in correct format or not provided."
+// messages of validation errors, they are translated by i18n and {name} placeholders are replaced.
+const (
+	formatErr   string = "{field}: is not in correct format or not provided."
+	requiredErr string = "{field} is required"
+	enumErr     string = "{field} must be one of the following: {allowed}"
+	invalidErr  string = "{field} {description}"
+)
 
 // LoadSchema loads json schema files on specified path for given component name
 func LoadSchema(path string, input map[string][]byte) (err error) {
@@ -28,7 +34,10 @@ func LoadSchema(path string, input map[string][]byte) (err error) {
 	}
 
 	for _, file := range files {
-		key := strings.TrimLeft(file, path)
+		key, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
 
 		fp := filepath.Clean(file)
 		data, err := os.ReadFile(fp)
@@ -36,7 +45,7 @@ func LoadSchema(path string, input map[string][]byte) (err error) {
 			return err
 		}
 
-		key = strings.Replace(key, ".json", "", 1)
+		key = strings.TrimSuffix(filepath.ToSlash(key), ".json")
 
 		input[key] = data
 	}
@@ -74,73 +83,76 @@ func load(dir string, ignore []string) ([]string, error) {
 	return files, err
 }
 
-// validateSchema validate given struct with expected schema
-func validateSchema(input interface{}, schema []byte) ([]string, error) {
-	bytes, err := json.Marshal(input)
-	if err != nil {
-		return nil, err
+// Validate validates input with schema of its type, failed result has a VALIDATION_FAILED error
+// for every violation of schema that reports its field and its message is in language of ctx.
+func (s *Schemas) Validate(ctx context.Context, input interface{}) *otpapp.BaseResult {
+	val := reflect.ValueOf(input)
+	if val.Kind() != reflect.Ptr {
+		return otpapp.NewErrorResult(otpapp.ErrUnimplementedRequest)
 	}
 
-	sl := gojsonschema.NewStringLoader(string(schema))
-	rsl := gojsonschema.NewStringLoader(string(bytes))
+	if val.IsNil() {
+		return otpapp.NewErrorResult(otpapp.ErrInvalidBody)
+	}
 
-	res, err := gojsonschema.Validate(sl, rsl)
-	if err != nil {
-		return nil, err
+	schema, ok := s.active.Load().compiled[val.Elem().Type().Name()]
+	if !ok {
+		return otpapp.NewErrorResult(otpapp.ErrUnimplementedRequest)
 	}
 
-	if !res.Valid() {
-		var errs []string
-		for _, e := range res.Errors() {
-			t := e.Type()
-			switch t {
-			case gojsonschema.KEY_PATTERN:
-				errs = append(errs, fmt.Sprintf(formatErr, e.Field()))
-			case gojsonschema.KEY_REQUIRED:
-				errs = append(errs, fmt.Sprint("%v", e.Description()))
-			case gojsonschema.KEY_ENUM:
-				errs = append(errs, fmt.Sprintf("%v", strings.Replace(e.Description(), "\"", "'", -1)))
-			case "condition_else":
-			case "condition_then":
-				break
-			default:
-				errs = append(errs, fmt.Sprintf("%v %s", e.Field(), e.Description()))
+	b, err := json.Marshal(input)
+	if err != nil {
+		return otpapp.NewErrorResult(otpapp.ErrValidation.Because(err))
+	}
 
-			}
-		}
+	res, err := schema.Validate(gojsonschema.NewBytesLoader(b))
+	if err != nil {
+		return otpapp.NewErrorResult(otpapp.ErrValidation.Because(err))
+	}
 
-		return errs, errors.New("bad request")
+	if res.Valid() {
+		return nil
 	}
 
-	return nil, nil
-}
+	lang := i18n.Language(ctx)
 
-func Validate(input interface{}, schemas map[string][]byte) *otpapp.BaseResult {
-	val := reflect.ValueOf(input)
-	if val.Kind() != reflect.Ptr {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{otpapp.ErrUnimplementedRequest.Error()},
+	var errs []error
+	for _, e := range res.Errors() {
+		field := e.Field()
+		if field == gojsonschema.STRING_CONTEXT_ROOT {
+			field = ""
 		}
-	}
 
-	name := reflect.Indirect(val).Type().Name()
-	schema, ok := schemas[name]
-	if !ok {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: []string{otpapp.ErrUnimplementedRequest.Error()},
-		}
-	}
+		var msg string
+		switch e.Type() {
+		case gojsonschema.KEY_PATTERN:
+			msg = i18n.Render(lang, formatErr, map[string]string{"field": field})
+		case gojsonschema.KEY_REQUIRED:
+			// required errors are reported for parent of missing property
+			property := fmt.Sprint(e.Details()["property"])
+			if field != "" {
+				property = field + "." + property
+			}
 
-	errs, err := validateSchema(input, schema)
-	if err != nil {
-		return &otpapp.BaseResult{
-			Status: http.StatusOK,
-			Errors: errs,
+			field = property
+			msg = i18n.Render(lang, requiredErr, map[string]string{"field": field})
+		case gojsonschema.KEY_ENUM:
+			msg = i18n.Render(lang, enumErr, map[string]string{
+				"field":   field,
+				"allowed": strings.Replace(fmt.Sprint(e.Details()["allowed"]), "\"", "'", -1),
+			})
+		case "condition_else", "condition_then":
+			continue
+		default:
+			msg = i18n.Render(lang, invalidErr, map[string]string{"field": field, "description": e.Description()})
 		}
+
+		errs = append(errs, otpapp.ErrValidation.OnField(field, errors.New(msg)))
 	}
 
-	return nil
+	if len(errs) == 0 {
+		return otpapp.NewErrorResult(otpapp.ErrValidation)
+	}
 
+	return otpapp.NewErrorResult(errs...)
 }